@@ -28,8 +28,8 @@ const (
 	httpInstanceName       = "pentameter._http._tcp.local."
 	promHTTPInstanceName   = "pentameter._prometheus-http._tcp.local."
 
-	// TXT record metadata.
-	txtPathMetrics = "path=/metrics"
+	// TXT record metadata: the metrics path (--metrics-path) follows this prefix.
+	txtPathPrefix = "path="
 
 	// Standard mDNS multicast port (RFC 6762).
 	mdnsMcastPort = 5353
@@ -405,7 +405,7 @@ func (a *MDNSAdvertiser) buildTXTResponse(instance string) *dnsmessage.Message {
 			},
 			Body: &dnsmessage.TXTResource{
 				TXT: []string{
-					txtPathMetrics,
+					txtPathPrefix + metricsPath,
 					"version=" + version,
 				},
 			},
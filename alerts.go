@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// alertMetrics are the --alerts metrics.
+type alertMetrics struct {
+	activeAlerts prometheus.Gauge
+	alertInfo    *prometheus.GaugeVec
+}
+
+// defineAlertMetrics builds the --alerts metrics.
+func defineAlertMetrics() alertMetrics {
+	return alertMetrics{
+		activeAlerts: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "intellicenter_active_alerts",
+				Help: "Number of alerts (sensor faults, communication errors, ...) the panel reports as active; " +
+					"0 on firmware that exposes none (--alerts only)",
+			},
+		),
+
+		alertInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "intellicenter_alert_info",
+				Help: "Always 1 for each active panel alert, labeled with its code (SUBTYP) and message (SNAME) (--alerts only)",
+			},
+			[]string{"alert", "code", "message"},
+		),
+	}
+}
+
+// applyAlerts sets intellicenter_active_alerts and one intellicenter_alert_info
+// series per active alert. An alert counts as active unless its STATUS is OFF,
+// since firmware that lists an alert without a status is still reporting it.
+// Alerts that cleared or disappeared lose their series.
+func (pm *PoolMonitor) applyAlerts(objs []ObjectData) {
+	previous := pm.activeAlertKeys
+	pm.activeAlertKeys = make(map[string]bool, len(objs))
+	for _, obj := range objs {
+		if strings.EqualFold(obj.Params[keySTATUS], statusDescOff) {
+			continue
+		}
+		code, message := obj.Params[keySUBTYP], obj.Params[keySNAME]
+		pm.metrics.alertInfo.WithLabelValues(obj.ObjName, code, message).Set(1)
+		pm.activeAlertKeys[obj.ObjName+"|"+code+"|"+message] = true
+	}
+	pm.metrics.activeAlerts.Set(float64(len(pm.activeAlertKeys)))
+	for key := range previous {
+		if !pm.activeAlertKeys[key] {
+			pm.metrics.alertInfo.DeleteLabelValues(strings.SplitN(key, "|", 3)...)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestApplyAlerts(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	alert := func(objName, code, message, status string) ObjectData {
+		return ObjectData{ObjName: objName, Params: map[string]string{"SUBTYP": code, "SNAME": message, "STATUS": status}}
+	}
+
+	pm.applyAlerts([]ObjectData{
+		alert("ALT91", "PMPCOM", "Pump Communication Error", "ON"),
+		alert("ALT92", "SENSOR", "Water Sensor Fault", ""), // no STATUS: still reported
+		alert("ALT93", "FLOW", "Low Flow", "OFF"),          // cleared
+	})
+	if got := gaugeVal(t, pm.metrics.activeAlerts); got != 2 {
+		t.Errorf("intellicenter_active_alerts = %v, want 2", got)
+	}
+	if got := gaugeVal(t, pm.metrics.alertInfo.WithLabelValues("ALT91", "PMPCOM", "Pump Communication Error")); got != 1 {
+		t.Errorf("intellicenter_alert_info = %v, want 1", got)
+	}
+	if pm.metrics.alertInfo.DeleteLabelValues("ALT93", "FLOW", "Low Flow") {
+		t.Error("cleared alert exported an info series")
+	}
+
+	// Firmware without alerts (or all cleared) reports 0 and drops the series.
+	pm.applyAlerts(nil)
+	if got := gaugeVal(t, pm.metrics.activeAlerts); got != 0 {
+		t.Errorf("no alerts: intellicenter_active_alerts = %v, want 0", got)
+	}
+	if pm.metrics.alertInfo.DeleteLabelValues("ALT91", "PMPCOM", "Pump Communication Error") {
+		t.Error("stale intellicenter_alert_info series not removed")
+	}
+}
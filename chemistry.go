@@ -0,0 +1,175 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CHEM params read by applyChemistry: IntelliChem pH/ORP and tank levels, and
+// IntelliChlor salt and output.
+const (
+	keyPHVAL  = "PHVAL"
+	keyORPVAL = "ORPVAL"
+	keyPHTNK  = "PHTNK"
+	keyORPTNK = "ORPTNK"
+	keySALT   = "SALT"
+	keyPRIM   = "PRIM"
+
+	// subtypChlorinator is the CHEM SUBTYP of an IntelliChlor salt cell.
+	// IntelliChem echoes the cell's salt reading, so only the cell exports it.
+	subtypChlorinator = "ICHLOR"
+
+	// chemTankFull is the PHTNK/ORPTNK reading of a full IntelliChem feed tank;
+	// the controller reports tanks in steps from 0 (empty) to 6.
+	chemTankFull = 6
+)
+
+// chemistryMetrics are the chemistry and chlorinator metrics.
+type chemistryMetrics struct {
+	poolPH                   *prometheus.GaugeVec
+	poolORP                  *prometheus.GaugeVec
+	chemTankLevel            *prometheus.GaugeVec
+	chemTankLevelPercent     *prometheus.GaugeVec
+	chemFlowOK               *prometheus.GaugeVec
+	saltLevelPPM             *prometheus.GaugeVec
+	chlorinatorOutputPercent *prometheus.GaugeVec
+}
+
+// defineChemistryMetrics builds the chemistry and chlorinator metrics.
+func defineChemistryMetrics() chemistryMetrics {
+	return chemistryMetrics{
+		poolPH: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "water_ph",
+				Help: "Water pH measured by an IntelliChem controller. Absent until the probe reports a reading.",
+			},
+			[]string{"chem", fieldName},
+		),
+
+		poolORP: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "water_orp_millivolts",
+				Help: "Water oxidation-reduction potential (sanitizer activity) in millivolts, measured by an IntelliChem " +
+					"controller. Absent until the probe reports a reading.",
+			},
+			[]string{"chem", fieldName},
+		),
+
+		chemTankLevel: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "chem_tank_level",
+				Help: "IntelliChem feed tank level as the panel reports it (0 = empty), by tank: ph (acid) or orp (chlorine)",
+			},
+			[]string{"chem", fieldName, "tank"},
+		),
+
+		chemTankLevelPercent: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "chemistry_tank_level_percent",
+				Help: "IntelliChem feed tank fill in percent (0 = empty), by tank: ph (acid) or orp (chlorine)",
+			},
+			[]string{"chem", fieldName, "tank"},
+		),
+
+		chemFlowOK: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "chemistry_flow_ok",
+				Help: "1 while the IntelliChem flow switch reports flow, 0 without flow (no dosing happens then). " +
+					"--chem-flow-key only.",
+			},
+			[]string{"chem", fieldName},
+		),
+
+		saltLevelPPM: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "salt_level_ppm",
+				Help: "Salt concentration in ppm measured by an IntelliChlor cell. Absent until the cell reports a reading.",
+			},
+			[]string{"chlorinator", fieldName},
+		),
+
+		chlorinatorOutputPercent: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "chlorinator_output_percent",
+				Help: "IntelliChlor primary (pool) output setting in percent",
+			},
+			[]string{"chlorinator", fieldName},
+		),
+	}
+}
+
+// applyChemistry sets the pH, ORP, tank-level and flow gauges from IntelliChem
+// objects, and salt and output from IntelliChlor cells. A probe without a
+// reading reports 0 (pH, ORP and salt are never 0 in real water), so those
+// series are removed rather than exported as 0; a tank level or output of 0 is
+// real (an empty tank, a cell turned down) and is kept. Panels without either
+// have no CHEM objects and export none of these.
+func (pm *PoolMonitor) applyChemistry(objs []ObjectData) {
+	for _, obj := range objs {
+		name := obj.Params[keySNAME]
+		if name == "" {
+			continue
+		}
+		setPositive(pm.metrics.poolPH, obj.Params[keyPHVAL], obj.ObjName, name)
+		setPositive(pm.metrics.poolORP, obj.Params[keyORPVAL], obj.ObjName, name)
+		for tank, key := range map[string]string{"ph": keyPHTNK, "orp": keyORPTNK} {
+			level, err := strconv.ParseFloat(obj.Params[key], 64)
+			if err != nil || level < 0 {
+				pm.metrics.chemTankLevel.DeleteLabelValues(obj.ObjName, name, tank)
+				pm.metrics.chemTankLevelPercent.DeleteLabelValues(obj.ObjName, name, tank)
+				continue
+			}
+			pm.metrics.chemTankLevel.WithLabelValues(obj.ObjName, name, tank).Set(level)
+			pm.metrics.chemTankLevelPercent.WithLabelValues(obj.ObjName, name, tank).Set(min(level/chemTankFull, 1) * 100)
+		}
+		if obj.Params[keySUBTYP] == subtypChlorinator {
+			pm.applyChlorinator(obj, name)
+			continue
+		}
+		pm.applyChemFlow(obj, name)
+		pm.logChangedf("chem:"+obj.ObjName, "Updated chemistry: %s (%s) pH=%s ORP=%s mV",
+			name, obj.ObjName, obj.Params[keyPHVAL], obj.Params[keyORPVAL])
+	}
+}
+
+// applyChlorinator sets an IntelliChlor cell's salt and output gauges.
+func (pm *PoolMonitor) applyChlorinator(obj ObjectData, name string) {
+	setPositive(pm.metrics.saltLevelPPM, obj.Params[keySALT], obj.ObjName, name)
+	if output, err := strconv.ParseFloat(obj.Params[keyPRIM], 64); err == nil && output >= 0 {
+		pm.metrics.chlorinatorOutputPercent.WithLabelValues(obj.ObjName, name).Set(output)
+	} else {
+		pm.metrics.chlorinatorOutputPercent.DeleteLabelValues(obj.ObjName, name)
+	}
+	pm.logChangedf("chlor:"+obj.ObjName, "Updated chlorinator: %s (%s) salt=%s ppm output=%s%%",
+		name, obj.ObjName, obj.Params[keySALT], obj.Params[keyPRIM])
+}
+
+// applyChemFlow sets chemistry_flow_ok from the CHEM param named by
+// --chem-flow-key. No documented key carries the flow switch and firmwares
+// differ, so it is opt-in. ON/1 reads 1 and OFF/0 reads 0; any other value (the
+// key-name echo of firmware without it) removes the series.
+func (pm *PoolMonitor) applyChemFlow(obj ObjectData, name string) {
+	if pm.chemFlowKey == "" {
+		return
+	}
+	switch obj.Params[pm.chemFlowKey] {
+	case statusOn, "1":
+		pm.metrics.chemFlowOK.WithLabelValues(obj.ObjName, name).Set(1)
+	case statusDescOff, "0":
+		pm.metrics.chemFlowOK.WithLabelValues(obj.ObjName, name).Set(0)
+	default:
+		pm.metrics.chemFlowOK.DeleteLabelValues(obj.ObjName, name)
+	}
+}
+
+// setPositive sets g's series for labels to value when it parses to a positive
+// number, and removes the series otherwise.
+func setPositive(g *prometheus.GaugeVec, value string, labels ...string) {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || v <= 0 {
+		g.DeleteLabelValues(labels...)
+		return
+	}
+	g.WithLabelValues(labels...).Set(v)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestApplyChemistry(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	chem := func(ph, orp, phTank, orpTank string) []ObjectData {
+		return []ObjectData{{ObjName: "CHR91", Params: map[string]string{
+			"SNAME": "IntelliChem", "PHVAL": ph, "ORPVAL": orp, "PHTNK": phTank, "ORPTNK": orpTank,
+		}}}
+	}
+
+	pm.applyChemistry(chem("7.4", "680", "4", "0"))
+	if got := gaugeVal(t, pm.metrics.poolPH.WithLabelValues("CHR91", "IntelliChem")); got != 7.4 {
+		t.Errorf("water_ph = %v, want 7.4", got)
+	}
+	if got := gaugeVal(t, pm.metrics.poolORP.WithLabelValues("CHR91", "IntelliChem")); got != 680 {
+		t.Errorf("water_orp_millivolts = %v, want 680", got)
+	}
+	if got := gaugeVal(t, pm.metrics.chemTankLevel.WithLabelValues("CHR91", "IntelliChem", "ph")); got != 4 {
+		t.Errorf("ph tank = %v, want 4", got)
+	}
+	// An empty tank is a real reading.
+	if got := gaugeVal(t, pm.metrics.chemTankLevel.WithLabelValues("CHR91", "IntelliChem", "orp")); got != 0 {
+		t.Errorf("orp tank = %v, want 0", got)
+	}
+
+	// No probe reading (0) or an unparseable echo drops the series.
+	pm.applyChemistry(chem("0", "ORPVAL", "4", ""))
+	if pm.metrics.poolPH.DeleteLabelValues("CHR91", "IntelliChem") {
+		t.Error("water_ph exported for a zero reading")
+	}
+	if pm.metrics.poolORP.DeleteLabelValues("CHR91", "IntelliChem") {
+		t.Error("water_orp_millivolts exported for an unparseable reading")
+	}
+	if pm.metrics.chemTankLevel.DeleteLabelValues("CHR91", "IntelliChem", "orp") {
+		t.Error("orp tank exported without a level")
+	}
+}
+
+func TestApplyChlorinator(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	cell := func(salt, prim string) []ObjectData {
+		return []ObjectData{{ObjName: "CHR92", Params: map[string]string{
+			"SNAME": "Salt Cell", "SUBTYP": "ICHLOR", "SALT": salt, "PRIM": prim,
+		}}}
+	}
+
+	pm.applyChemistry(cell("3200", "0"))
+	if got := gaugeVal(t, pm.metrics.saltLevelPPM.WithLabelValues("CHR92", "Salt Cell")); got != 3200 {
+		t.Errorf("salt_level_ppm = %v, want 3200", got)
+	}
+	// A cell turned down to 0% is a real setting.
+	if got := gaugeVal(t, pm.metrics.chlorinatorOutputPercent.WithLabelValues("CHR92", "Salt Cell")); got != 0 {
+		t.Errorf("chlorinator_output_percent = %v, want 0", got)
+	}
+	if pm.metrics.poolPH.DeleteLabelValues("CHR92", "Salt Cell") {
+		t.Error("salt cell exported water_ph")
+	}
+
+	// No salt reading yet drops the series; output stays.
+	pm.applyChemistry(cell("0", "40"))
+	if pm.metrics.saltLevelPPM.DeleteLabelValues("CHR92", "Salt Cell") {
+		t.Error("salt_level_ppm exported for a zero reading")
+	}
+	if got := gaugeVal(t, pm.metrics.chlorinatorOutputPercent.WithLabelValues("CHR92", "Salt Cell")); got != 40 {
+		t.Errorf("chlorinator_output_percent = %v, want 40", got)
+	}
+
+	// IntelliChem echoes the salt reading; only the cell exports it.
+	pm.applyChemistry([]ObjectData{{ObjName: "CHR93", Params: map[string]string{
+		"SNAME": "IntelliChem", "SUBTYP": "ICHEM", "SALT": "3200", "PHVAL": "7.5",
+	}}})
+	if pm.metrics.saltLevelPPM.DeleteLabelValues("CHR93", "IntelliChem") {
+		t.Error("IntelliChem exported salt_level_ppm")
+	}
+}
+
+func TestChemTankPercentAndFlow(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	pm.chemFlowKey = "FLOSW"
+	chem := func(phTank, orpTank, flow string) []ObjectData {
+		return []ObjectData{{ObjName: "CHR94", Params: map[string]string{
+			"SNAME": "IntelliChem", "SUBTYP": "ICHEM", "PHTNK": phTank, "ORPTNK": orpTank, "FLOSW": flow,
+		}}}
+	}
+
+	pm.applyChemistry(chem("3", "6", "ON"))
+	if got := gaugeVal(t, pm.metrics.chemTankLevelPercent.WithLabelValues("CHR94", "IntelliChem", "ph")); got != 50 {
+		t.Errorf("ph tank percent = %v, want 50", got)
+	}
+	if got := gaugeVal(t, pm.metrics.chemTankLevelPercent.WithLabelValues("CHR94", "IntelliChem", "orp")); got != 100 {
+		t.Errorf("orp tank percent = %v, want 100", got)
+	}
+	if got := gaugeVal(t, pm.metrics.chemFlowOK.WithLabelValues("CHR94", "IntelliChem")); got != 1 {
+		t.Errorf("chemistry_flow_ok = %v, want 1", got)
+	}
+
+	pm.applyChemistry(chem("0", "", "0"))
+	if got := gaugeVal(t, pm.metrics.chemTankLevelPercent.WithLabelValues("CHR94", "IntelliChem", "ph")); got != 0 {
+		t.Errorf("empty ph tank percent = %v, want 0", got)
+	}
+	if pm.metrics.chemTankLevelPercent.DeleteLabelValues("CHR94", "IntelliChem", "orp") {
+		t.Error("orp tank percent exported without a level")
+	}
+	if got := gaugeVal(t, pm.metrics.chemFlowOK.WithLabelValues("CHR94", "IntelliChem")); got != 0 {
+		t.Errorf("no flow: chemistry_flow_ok = %v, want 0", got)
+	}
+
+	// The key-name echo of firmware without the key drops the series.
+	pm.applyChemistry(chem("3", "3", "FLOSW"))
+	if pm.metrics.chemFlowOK.DeleteLabelValues("CHR94", "IntelliChem") {
+		t.Error("chemistry_flow_ok exported for an echoed key")
+	}
+}
+
+// TestChemWithoutController checks a panel with no IntelliChem (no CHEM
+// objects, or only a salt cell) exports no tank or flow series.
+func TestChemWithoutController(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	pm.chemFlowKey = "FLOSW"
+
+	pm.applyChemistry(nil)
+	pm.applyChemistry([]ObjectData{{ObjName: "CHR95", Params: map[string]string{
+		"SNAME": "Salt Cell", "SUBTYP": "ICHLOR", "SALT": "3200", "PRIM": "40",
+	}}})
+	for name, vec := range map[string]*prometheus.GaugeVec{
+		"chemistry_tank_level_percent": pm.metrics.chemTankLevelPercent,
+		"chemistry_flow_ok":            pm.metrics.chemFlowOK,
+	} {
+		ch := make(chan prometheus.Metric, 4)
+		vec.Collect(ch)
+		close(ch)
+		if n := len(ch); n != 0 {
+			t.Errorf("%s: %d series without an IntelliChem, want 0", name, n)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// runtimeMetrics are the circuit and feature runtime counters.
+type runtimeMetrics struct {
+	circuitRuntimeSeconds *prometheus.CounterVec
+	featureRuntimeSeconds *prometheus.CounterVec
+}
+
+// defineRuntimeMetrics builds the circuit and feature runtime counters.
+func defineRuntimeMetrics() runtimeMetrics {
+	return runtimeMetrics{
+		circuitRuntimeSeconds: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "circuit_runtime_seconds_total",
+				Help: "Cumulative seconds a circuit has been running (circuit_status on or freeze protection), " +
+					"for maintenance reminders such as a cleaner bag every N hours",
+			},
+			[]string{logFieldCircuit, fieldName},
+		),
+
+		featureRuntimeSeconds: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "feature_runtime_seconds_total",
+				Help: "Cumulative seconds a feature has been running (feature_status on or freeze protection)",
+			},
+			[]string{"feature", fieldName},
+		),
+	}
+}
+
+// accumulateRuntime credits counter with the interval since objName's previous
+// sample in samples when it was running at that sample, then records this one.
+// Crediting the interval that began running, rather than the one that ends, keeps
+// the counter monotonic and never over-counts across a stop; the same scheme
+// drives body filtration and heater heating time. It runs on every poll and
+// push, so a change is timed to the sample that saw it.
+func (pm *PoolMonitor) accumulateRuntime(samples map[string]runtimeSample, objName string,
+	counter prometheus.Counter, running bool,
+) {
+	now := pm.now()
+	if prev, ok := samples[objName]; ok && prev.running {
+		if elapsed := now.Sub(prev.at).Seconds(); elapsed > 0 {
+			counter.Add(elapsed)
+		}
+	}
+	samples[objName] = runtimeSample{at: now, running: running}
+}
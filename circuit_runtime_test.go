@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCircuitRuntimeSeconds checks circuit_runtime_seconds_total and
+// feature_runtime_seconds_total credit only intervals that began running.
+func TestCircuitRuntimeSeconds(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	clock := time.Unix(1_700_000_000, 0)
+	pm.now = func() time.Time { return clock }
+
+	circuit := counterVal(t, pm.metrics.circuitRuntimeSeconds.WithLabelValues("C9301", "Cleaner"))
+	feature := counterVal(t, pm.metrics.featureRuntimeSeconds.WithLabelValues("FTR93", "Waterfall"))
+
+	steps := []struct {
+		advance time.Duration
+		status  string
+		want    float64 // cumulative seconds credited since start
+	}{
+		{0, "ON", 0},                  // first sample: nothing to credit yet
+		{60 * time.Second, "ON", 60},  // running for the full interval
+		{30 * time.Second, "OFF", 90}, // was running at last sample; now off
+		{45 * time.Second, "OFF", 90}, // off: not credited
+		{10 * time.Second, "ON", 90},  // turned on: credited from here
+		{20 * time.Second, "ON", 110},
+	}
+	for i, st := range steps {
+		clock = clock.Add(st.advance)
+		pm.processCircuitObject(ObjectData{ObjName: "C9301", Params: map[string]string{
+			"SNAME": "Cleaner", "STATUS": st.status, "SUBTYP": "GENERIC",
+		}})
+		pm.processCircuitObject(ObjectData{ObjName: "FTR93", Params: map[string]string{
+			"SNAME": "Waterfall", "STATUS": st.status, "SUBTYP": "GENERIC",
+		}})
+		if got := counterVal(t, pm.metrics.circuitRuntimeSeconds.WithLabelValues("C9301", "Cleaner")) - circuit; got != st.want {
+			t.Errorf("step %d: circuit runtime = %v, want %v", i, got, st.want)
+		}
+		if got := counterVal(t, pm.metrics.featureRuntimeSeconds.WithLabelValues("FTR93", "Waterfall")) - feature; got != st.want {
+			t.Errorf("step %d: feature runtime = %v, want %v", i, got, st.want)
+		}
+	}
+}
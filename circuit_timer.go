@@ -0,0 +1,76 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// circuitTimerMetrics holds circuit_timer_remaining_seconds.
+type circuitTimerMetrics struct {
+	circuitTimerRemaining *prometheus.GaugeVec
+}
+
+// defineCircuitTimerMetrics builds circuitTimerMetrics.
+func defineCircuitTimerMetrics() circuitTimerMetrics {
+	return circuitTimerMetrics{
+		circuitTimerRemaining: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "circuit_timer_remaining_seconds",
+				Help: "Run time left on a circuit that is on (egg timer, freeze protection), from the circuit param named by " +
+					"--circuit-timer-key. Absent while the circuit is off or reports no time left.",
+			},
+			[]string{logFieldCircuit, fieldName},
+		),
+	}
+}
+
+// applyCircuitTimers exports how long each running circuit will keep running.
+// No documented key carries the remaining time and firmwares differ, so, like
+// the board temperature, it is off unless --circuit-timer-key names the param.
+// A circuit that is off, or whose value is missing, zero or unparseable (a key
+// echo), has no series.
+func (pm *PoolMonitor) applyCircuitTimers(objs []ObjectData) {
+	if pm.circuitTimerKey == "" {
+		return
+	}
+	for _, obj := range objs {
+		name := obj.Params[keySNAME]
+		if name == "" {
+			continue
+		}
+		remaining, ok := parseRemainingSeconds(obj.Params[pm.circuitTimerKey])
+		if !ok || remaining <= 0 || obj.Params[keySTATUS] != statusOn {
+			pm.metrics.circuitTimerRemaining.DeleteLabelValues(obj.ObjName, name)
+			continue
+		}
+		pm.metrics.circuitTimerRemaining.WithLabelValues(obj.ObjName, name).Set(remaining)
+	}
+}
+
+// parseRemainingSeconds reads a remaining time given either as plain seconds
+// or in the panel's "HH,MM,SS" clock format. NaN and infinities are rejected.
+func parseRemainingSeconds(value string) (float64, bool) {
+	parts := strings.Split(strings.TrimSpace(value), ",")
+	if len(parts) == 1 {
+		seconds, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+			return 0, false
+		}
+		return seconds, true
+	}
+	if len(parts) != 3 {
+		return 0, false
+	}
+	total := 0.0
+	for _, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		total = total*60 + float64(n)
+	}
+	return total, true
+}
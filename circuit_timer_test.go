@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestParseRemainingSeconds(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"900", 900, true},
+		{"01,30,15", 5415, true},
+		{"00,00,00", 0, true},
+		{"TIMER", 0, false},
+		{"01,30", 0, false},
+		{"01,-5,00", 0, false},
+		{"", 0, false},
+		{"NaN", 0, false},
+		{"+Inf", 0, false},
+		{"-inf", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRemainingSeconds(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRemainingSeconds(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestApplyCircuitTimers(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	circuit := func(status, remaining string) []ObjectData {
+		return []ObjectData{{ObjName: "C9601", Params: map[string]string{
+			"SNAME": "Spa Jets", "STATUS": status, "RUNTIM": remaining,
+		}}}
+	}
+
+	// Off unless a key is configured.
+	pm.applyCircuitTimers(circuit("ON", "600"))
+	if pm.metrics.circuitTimerRemaining.DeleteLabelValues("C9601", "Spa Jets") {
+		t.Fatal("exported without --circuit-timer-key")
+	}
+
+	pm.circuitTimerKey = "RUNTIM"
+	pm.applyCircuitTimers(circuit("ON", "00,10,00"))
+	if got := gaugeVal(t, pm.metrics.circuitTimerRemaining.WithLabelValues("C9601", "Spa Jets")); got != 600 {
+		t.Errorf("remaining = %v, want 600", got)
+	}
+
+	// Turning off (or an echoed key) removes the series.
+	pm.applyCircuitTimers(circuit("OFF", "600"))
+	if pm.metrics.circuitTimerRemaining.DeleteLabelValues("C9601", "Spa Jets") {
+		t.Error("series kept for a circuit that is off")
+	}
+	pm.applyCircuitTimers(circuit("ON", "RUNTIM"))
+	if pm.metrics.circuitTimerRemaining.DeleteLabelValues("C9601", "Spa Jets") {
+		t.Error("series exported for an unparseable value")
+	}
+}
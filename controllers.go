@@ -0,0 +1,115 @@
+package main
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// controllerLabel tells apart the series of several controllers served by one
+// process (a comma-separated --ic-ip). A lone controller's series carry no such
+// label, so single-panel names and labels are unchanged.
+const controllerLabel = "controller"
+
+// controllerMetrics is every metric that describes one controller. Each
+// PoolMonitor owns a set, so several controllers in one process never share a
+// series. Process-wide metrics (build info, discovery, lock waits) stay
+// package globals.
+type controllerMetrics struct {
+	coreMetrics
+	temperatureMetrics
+	chemistryMetrics
+	alertMetrics
+	runtimeMetrics
+	freezeMetrics
+	circuitTimerMetrics
+	enumMetrics
+	equipmentStatusMetrics
+	pollsSinceChangeMetrics
+	pumpAnomalyMetrics
+	scheduleMetrics
+}
+
+// defineControllerMetrics builds a fresh metric set for one controller, with
+// Celsius temperature metrics when celsius is set (--units=c).
+func defineControllerMetrics(celsius bool) *controllerMetrics {
+	return &controllerMetrics{
+		coreMetrics:             defineCoreMetrics(),
+		temperatureMetrics:      defineTemperatureMetrics(celsius),
+		chemistryMetrics:        defineChemistryMetrics(),
+		alertMetrics:            defineAlertMetrics(),
+		runtimeMetrics:          defineRuntimeMetrics(),
+		freezeMetrics:           defineFreezeMetrics(),
+		circuitTimerMetrics:     defineCircuitTimerMetrics(),
+		enumMetrics:             defineEnumMetrics(),
+		equipmentStatusMetrics:  defineEquipmentStatusMetrics(),
+		pollsSinceChangeMetrics: definePollsSinceChangeMetrics(),
+		pumpAnomalyMetrics:      definePumpAnomalyMetrics(),
+		scheduleMetrics:         defineScheduleMetrics(),
+	}
+}
+
+// register registers the set with r. The opt-in families register only when
+// cfg turns them on. Several controllers register through
+// prometheus.WrapRegistererWith, so each set's series carry its controller
+// label.
+func (m *controllerMetrics) register(r prometheus.Registerer, cfg *appConfig) {
+	r.MustRegister(m.poolTemperature)
+	r.MustRegister(m.airTemperature)
+	r.MustRegister(m.airSensorConnected)
+	r.MustRegister(m.solarTemperature)
+	r.MustRegister(m.connectionFailure)
+	r.MustRegister(m.queryFailure)
+	r.MustRegister(m.lastRefreshTimestamp)
+	r.MustRegister(m.lastPushTimestamp)
+	r.MustRegister(m.targetInfo)
+	r.MustRegister(m.pumpRPM)
+	r.MustRegister(m.pumpWatts)
+	r.MustRegister(m.pumpGPM)
+	r.MustRegister(m.pumpTotalWatts)
+	r.MustRegister(m.circuitStatus)
+	r.MustRegister(m.masterCircuitStatus)
+	r.MustRegister(m.thermalStatus)
+	r.MustRegister(m.thermalStatusMismatch)
+	r.MustRegister(m.thermalLowSetpoint)
+	r.MustRegister(m.thermalHighSetpoint)
+	r.MustRegister(m.featureStatus)
+	r.MustRegister(m.bodyFiltrationSeconds)
+	r.MustRegister(m.heaterHeatingSeconds)
+	r.MustRegister(m.heaterCycles)
+	r.MustRegister(m.circuitRuntimeSeconds)
+	r.MustRegister(m.featureRuntimeSeconds)
+	r.MustRegister(m.poolTurnoversPerDay)
+	r.MustRegister(m.poolPH)
+	r.MustRegister(m.poolORP)
+	r.MustRegister(m.chemTankLevel)
+	r.MustRegister(m.chemTankLevelPercent)
+	r.MustRegister(m.chemFlowOK)
+	r.MustRegister(m.saltLevelPPM)
+	r.MustRegister(m.chlorinatorOutputPercent)
+	r.MustRegister(m.pushesSkipped)
+	r.MustRegister(m.pushesDropped)
+	r.MustRegister(m.reconnects)
+	r.MustRegister(m.panelReboots)
+	r.MustRegister(m.queryResponseSeconds)
+	r.MustRegister(m.emptyResponses)
+	r.MustRegister(m.pollsSinceChange)
+	r.MustRegister(m.vacationMode)
+	r.MustRegister(m.unitMismatch)
+	r.MustRegister(m.boardTemperature)
+	r.MustRegister(m.circuitTimerRemaining)
+	r.MustRegister(m.bodyTemperatureError)
+	r.MustRegister(m.bodyHeatMode)
+	r.MustRegister(m.heaterActive)
+	r.MustRegister(m.pumpEfficiencyAnomaly)
+	r.MustRegister(m.enumValue)
+	r.MustRegister(m.circuitNextRunSeconds)
+	r.MustRegister(m.circuitScheduledSetpoint)
+	if cfg.statusEncoding == statusEncodingBoolean {
+		r.MustRegister(m.circuitFreezeProtected)
+		r.MustRegister(m.featureFreezeProtected)
+	}
+	if cfg.alerts {
+		r.MustRegister(m.activeAlerts)
+		r.MustRegister(m.alertInfo)
+	}
+	if cfg.equipmentStatus {
+		r.MustRegister(m.equipmentStatus)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// recordingRegisterer collects what controllerMetrics.register registers.
+type recordingRegisterer struct {
+	prometheus.Registerer
+	collectors []prometheus.Collector
+}
+
+func (r *recordingRegisterer) MustRegister(cs ...prometheus.Collector) {
+	r.collectors = append(r.collectors, cs...)
+}
+
+// TestRegisterCoversControllerMetrics checks that, with every opt-in on,
+// register registers each collector of the set exactly once, so a metric added
+// to one of the embedded structs can't be left out.
+func TestRegisterCoversControllerMetrics(t *testing.T) {
+	m := defineControllerMetrics(false)
+	r := &recordingRegisterer{}
+	m.register(r, &appConfig{statusEncoding: statusEncodingBoolean, alerts: true, equipmentStatus: true})
+	registered := make(map[uintptr]bool)
+	for _, c := range r.collectors {
+		registered[reflect.ValueOf(c).Pointer()] = true
+	}
+	if len(registered) != len(r.collectors) {
+		t.Errorf("register registers %d collectors, only %d distinct", len(r.collectors), len(registered))
+	}
+
+	fields := 0
+	sets := reflect.ValueOf(m).Elem()
+	for i := range sets.NumField() {
+		set := sets.Field(i)
+		for j := range set.NumField() {
+			field := set.Field(j)
+			if field.Kind() == reflect.Interface {
+				field = field.Elem()
+			}
+			fields++
+			if !registered[field.Pointer()] {
+				t.Errorf("%s.%s is never registered", set.Type().Name(), set.Type().Field(j).Name)
+			}
+		}
+	}
+	if fields != len(registered) {
+		t.Errorf("register registers %d collectors, the set has %d", len(registered), fields)
+	}
+}
+
+// TestMetricsEngineExportsEachController runs two controllers in one process:
+// each must get its own series under a controller label, on one registry,
+// including the series the engine's hooks update from its own goroutines.
+func TestMetricsEngineExportsEachController(t *testing.T) {
+	registry := createProcessRegistry(&appConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	var done []<-chan struct{}
+	defer func() {
+		cancel()
+		for _, d := range done {
+			<-d
+		}
+	}()
+
+	temps := map[string]float64{"127.0.0.1": 80, "localhost": 70}
+	monitors := make(map[string]*PoolMonitor)
+	for addr, temp := range temps {
+		mock := newScriptedMock(map[string]IntelliCenterResponse{
+			"GetParamList:OBJTYP=BODY": {ObjectList: []ObjectData{
+				{ObjName: "B1101", Params: map[string]string{"SNAME": "Pool", "STATUS": "ON", "TEMP": fmt.Sprint(temp), "SUBTYP": "POOL"}},
+			}},
+		}, nil)
+		server := mock.serve(t)
+		defer server.Close()
+		_, port, _ := strings.Cut(strings.TrimPrefix(server.URL, "http://"), ":")
+		// debugEndpoint exposes each engine, so the test can fire its hooks.
+		cfg := &appConfig{intelliCenterPort: port, pollInterval: 50 * time.Millisecond, debugEndpoint: true}
+		pm, engineDone := startController(ctx, cfg, addr, registry, nil, true)
+		monitors[addr] = pm
+		done = append(done, engineDone)
+	}
+
+	// series maps each controller label to the value of the named metric's
+	// series whose labels include want.
+	series := func(name string, want map[string]string) map[string]float64 {
+		families, err := registry.Gather()
+		if err != nil {
+			t.Fatalf("gather: %v", err)
+		}
+		got := make(map[string]float64)
+		for _, mf := range families {
+			if mf.GetName() != name {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				labels := make(map[string]string)
+				for _, lp := range m.GetLabel() {
+					labels[lp.GetName()] = lp.GetValue()
+				}
+				if !containsLabels(labels, want) {
+					continue
+				}
+				switch {
+				case m.GetGauge() != nil:
+					got[labels[controllerLabel]] = m.GetGauge().GetValue()
+				case m.GetCounter() != nil:
+					got[labels[controllerLabel]] = m.GetCounter().GetValue()
+				case m.GetHistogram() != nil:
+					got[labels[controllerLabel]] = float64(m.GetHistogram().GetSampleCount())
+				}
+			}
+		}
+		return got
+	}
+	waitForCond(t, func() bool { return len(series("water_temperature_fahrenheit", nil)) == len(temps) })
+	for addr, got := range series("water_temperature_fahrenheit", nil) {
+		if want := temps[addr]; got != want {
+			t.Errorf("water temperature for controller %s = %v, want %v", addr, got, want)
+		}
+	}
+
+	// Hooks fired on one controller's engine count on that controller only.
+	engine := monitors["localhost"].stateEngine
+	engine.OnReboot(time.Minute)
+	engine.OnQuery("TestQuery", time.Millisecond)
+	if got := series("intellicenter_reboots_total", nil); got["localhost"] != 1 || got["127.0.0.1"] != 0 {
+		t.Errorf("reboots by controller = %v, want localhost 1 and 127.0.0.1 0", got)
+	}
+	if got := series("intellicenter_response_seconds", map[string]string{"command": "TestQuery"}); len(got) != 1 || got["localhost"] != 1 {
+		t.Errorf("TestQuery responses by controller = %v, want one on localhost", got)
+	}
+}
+
+// containsLabels reports whether labels has every name/value pair in want.
+func containsLabels(labels, want map[string]string) bool {
+	for name, value := range want {
+		if labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
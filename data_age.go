@@ -0,0 +1,34 @@
+package main
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// dataAgeCollector exports pentameter_data_age_seconds, computed at scrape time
+// from the last successful refresh, so staleness is always current and needs
+// no clock agreement between the exporter and Prometheus. Nothing is exported
+// before the first refresh.
+type dataAgeCollector struct {
+	pm   *PoolMonitor
+	desc *prometheus.Desc
+}
+
+func newDataAgeCollector(pm *PoolMonitor) *dataAgeCollector {
+	return &dataAgeCollector{
+		pm: pm,
+		desc: prometheus.NewDesc("pentameter_data_age_seconds",
+			"Seconds since the last successful refresh from the panel, computed at scrape time", nil, nil),
+	}
+}
+
+func (c *dataAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *dataAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.pm.mu.Lock()
+	last := c.pm.lastRefresh
+	c.pm.mu.Unlock()
+	if last.IsZero() {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, c.pm.now().Sub(last).Seconds())
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestDataAgeCollector(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	clock := time.Unix(1_700_000_000, 0)
+	pm.now = func() time.Time { return clock }
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(newDataAgeCollector(pm))
+
+	dataAge := func() (float64, bool) {
+		families, err := registry.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mf := range families {
+			if mf.GetName() == "pentameter_data_age_seconds" {
+				return mf.GetMetric()[0].GetGauge().GetValue(), true
+			}
+		}
+		return 0, false
+	}
+
+	if _, ok := dataAge(); ok {
+		t.Error("data age exported before the first refresh")
+	}
+	pm.updateRefreshTimestamp()
+	clock = clock.Add(90 * time.Second)
+	if got, ok := dataAge(); !ok || got != 90 {
+		t.Errorf("data age = %v (%v), want 90", got, ok)
+	}
+	// Computed per scrape: it keeps growing without another refresh.
+	clock = clock.Add(30 * time.Second)
+	if got, _ := dataAge(); got != 120 {
+		t.Errorf("data age = %v, want 120", got)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/astrostl/pentameter/intellicenter"
+)
+
+// debugStatePath serves the engine's state as JSON when --debug-endpoint is set.
+const debugStatePath = "/debug/state"
+
+// debugState is the /debug/state document: the engine's connection health
+// (target address, connected, last error, consecutive failures) and its
+// current view of the equipment. It answers "why do the metrics look stale"
+// without shell access, but exposes the pool's topology, so it is opt-in.
+type debugState struct {
+	Engine    intellicenter.Status   `json:"engine"`
+	Equipment intellicenter.Snapshot `json:"equipment"`
+}
+
+// newDebugStateHandler serves engine's debugState.
+func newDebugStateHandler(engine *intellicenter.Engine) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(debugState{Engine: engine.Status(), Equipment: engine.Snapshot()}); err != nil {
+			log.Printf("Failed to write debug state: %v", err)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/astrostl/pentameter/intellicenter"
+)
+
+// TestDebugStateEndpoint verifies /debug/state is served only with
+// --debug-endpoint and reports the engine's status as JSON.
+func TestDebugStateEndpoint(t *testing.T) {
+	pm := NewPoolMonitor("192.0.2.10", "6680", false)
+	rec := httptest.NewRecorder()
+	newMetricsMux(createPrometheusRegistry(&appConfig{}, pm.metrics), pm, &appConfig{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, debugStatePath, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("%s without --debug-endpoint = %d, want 404", debugStatePath, rec.Code)
+	}
+
+	pm.stateEngine = intellicenter.NewEngine("192.0.2.10", "6680", time.Minute)
+	rec = httptest.NewRecorder()
+	newMetricsMux(createPrometheusRegistry(&appConfig{}, pm.metrics), pm, &appConfig{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, debugStatePath, nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("%s = %d %q, want 200 application/json", debugStatePath, rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var state debugState
+	if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil {
+		t.Fatalf("decode %s: %v", debugStatePath, err)
+	}
+	if state.Engine.Host != "192.0.2.10" || state.Engine.Port != "6680" || state.Engine.Connected {
+		t.Errorf("engine status = %+v, want disconnected at 192.0.2.10:6680", state.Engine)
+	}
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"strings"
 	"time"
+	"unicode"
 
 	"golang.org/x/net/dns/dnsmessage"
 )
@@ -14,43 +16,170 @@ const (
 	discoveryTimeout = 60 * time.Second
 	retryInterval    = 2 * time.Second
 	mdnsAddress      = "224.0.0.251:5353"
+	mdnsAddress6     = "[ff02::fb]:5353"
 	readTimeout      = 100 * time.Millisecond
 	maxBufSize       = 1500
+	maxLabelLen      = 63 // longest DNS label
+
+	// ipv4HeadStart is how long an IPv6 answer waits for an IPv4 one while
+	// IPv4 discovery is still running, so a dual-stack panel is reached over
+	// IPv4 whenever it answers there too.
+	ipv4HeadStart = 500 * time.Millisecond
+
+	// defaultDiscoverHostname is the name a stock IntelliCenter answers to
+	// (--discover-hostname).
+	defaultDiscoverHostname = "pentair.local"
 )
 
-// DiscoverIntelliCenter discovers IntelliCenter via mDNS by querying for the
-// pentair.local hostname (an A-record lookup) and returning its IPv4 address.
+// DiscoverIntelliCenter discovers IntelliCenter via mDNS by querying for
+// hostname (pentair.local unless --discover-hostname says otherwise) over IPv4
+// and IPv6 at once, and returning the first address found: an A answer on IPv4,
+// an AAAA answer on IPv6. IPv4 gets a short head start (see firstDiscovered).
 // This intentionally does NOT do full DNS-SD service discovery (PTR/SRV/TXT), so
 // it yields only the IP — never a port. The protocol WebSocket port is fixed at
 // 6680 (see the ic-port flag), not advertised over mDNS.
 // Returns the IP address if found, or an error if discovery fails.
-// If verbose is true, logs each retry attempt.
-func DiscoverIntelliCenter(verbose bool) (string, error) {
-	// Setup multicast connection
-	mcastAddr, err := net.ResolveUDPAddr("udp4", mdnsAddress)
+// If verbose is true, logs each retry attempt. Every call is recorded in the
+// discovery duration and attempts metrics.
+func DiscoverIntelliCenter(hostname string, verbose bool) (string, error) {
+	start := time.Now()
+	ip, err := discoverIntelliCenter(hostname, verbose)
+	recordDiscovery(time.Since(start), err)
+	return ip, err
+}
+
+// recordDiscovery publishes the outcome of one discovery attempt.
+func recordDiscovery(elapsed time.Duration, err error) {
+	discoveryDuration.Set(elapsed.Seconds())
+	result := "success"
 	if err != nil {
-		return "", fmt.Errorf("failed to resolve mDNS address: %w", err)
+		result = "failure"
 	}
+	discoveryAttempts.WithLabelValues(result).Inc()
+}
+
+// mdnsFamily is one IP family discovery queries on.
+type mdnsFamily struct {
+	network string // udp4 or udp6
+	address string // the family's mDNS group
+	qtype   dnsmessage.Type
+}
+
+// mdnsFamilies are queried in parallel. IPv4 comes first: its error is the one
+// reported when neither finds the panel.
+var mdnsFamilies = []mdnsFamily{
+	{"udp4", mdnsAddress, dnsmessage.TypeA},
+	{"udp6", mdnsAddress6, dnsmessage.TypeAAAA},
+}
 
+func discoverIntelliCenter(hostname string, verbose bool) (string, error) {
 	// Get the appropriate interface for multicast listening
 	iface, err := getBestMulticastInterface(verbose)
 	if err != nil && verbose {
 		log.Printf("Warning: Could not find best interface, using default: %v", err)
 	}
 
-	conn, err := net.ListenMulticastUDP("udp4", iface, mcastAddr)
-	if err != nil {
-		return "", fmt.Errorf("failed to create multicast UDP listener: %w", err)
+	results := make(chan discoveryResult, len(mdnsFamilies))
+	errs := make([]error, len(mdnsFamilies))
+	running := 0
+	for i, family := range mdnsFamilies {
+		mcastAddr, conn, err := joinMDNSGroup(family, iface)
+		if err != nil {
+			errs[i] = err
+			if verbose && i > 0 {
+				log.Printf("Warning: %s mDNS unavailable, discovering over IPv4 only: %v", family.network, err)
+			}
+			continue
+		}
+		// Closing the listener on return ends a collector still waiting once
+		// the other family has answered.
+		defer conn.Close()
+		running++
+		go func() {
+			ip, err := collectHostnameResponseWithRetry(conn, mcastAddr, hostname, family.qtype, verbose)
+			if err == nil {
+				ip, err = withZone(ip, iface)
+			}
+			results <- discoveryResult{i, ip, err}
+		}()
 	}
-	defer conn.Close()
 
-	// Collect responses and find Pentair IntelliCenter IP with retries
-	ip, err := collectHostnameResponseWithRetry(conn, mcastAddr, verbose)
+	if ip, ok := firstDiscovered(results, running, errs[0] == nil, ipv4HeadStart, errs); ok {
+		return ip, nil
+	}
+	for _, err := range errs {
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", errors.New("no mDNS listener could be opened")
+}
+
+// discoveryResult is one family's discovery outcome.
+type discoveryResult struct {
+	family int // index into mdnsFamilies; 0 is IPv4
+	ip     string
+	err    error
+}
+
+// firstDiscovered waits for running results and returns the first address
+// found, preferring IPv4: an IPv6 answer that arrives while IPv4 is still
+// running is held for up to headStart. Each family's failure is stored in errs.
+func firstDiscovered(results <-chan discoveryResult, running int, ipv4Running bool, headStart time.Duration, errs []error) (string, bool) {
+	var held string // an IPv6 answer waiting out IPv4's head start
+	var timeout <-chan time.Time
+	for running > 0 {
+		select {
+		case r := <-results:
+			running--
+			if r.family == 0 {
+				ipv4Running = false
+			}
+			if r.err != nil {
+				errs[r.family] = r.err
+				if held != "" && !ipv4Running {
+					return held, true
+				}
+				continue
+			}
+			if r.family == 0 || !ipv4Running {
+				return r.ip, true
+			}
+			held = r.ip
+			timeout = time.After(headStart)
+		case <-timeout:
+			return held, true
+		}
+	}
+	return held, held != ""
+}
+
+// joinMDNSGroup joins family's mDNS group on iface (nil = the system default).
+func joinMDNSGroup(family mdnsFamily, iface *net.Interface) (*net.UDPAddr, *net.UDPConn, error) {
+	mcastAddr, err := net.ResolveUDPAddr(family.network, family.address)
 	if err != nil {
-		return "", err
+		return nil, nil, fmt.Errorf("failed to resolve mDNS address: %w", err)
 	}
+	conn, err := net.ListenMulticastUDP(family.network, iface, mcastAddr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create multicast UDP listener: %w", err)
+	}
+	return mcastAddr, conn, nil
+}
 
-	return ip, nil
+// withZone scopes an IPv6 link-local address to iface, where it was heard,
+// since fe80:: addresses can't be dialed without one. Others pass through. A
+// link-local answer heard on the default interface (iface nil) has no zone to
+// give it, so it is rejected.
+func withZone(ip string, iface *net.Interface) (string, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil || parsed.To4() != nil || !parsed.IsLinkLocalUnicast() {
+		return ip, nil
+	}
+	if iface == nil {
+		return "", fmt.Errorf("link-local answer %s has no interface to scope it to", ip)
+	}
+	return ip + "%" + iface.Name, nil
 }
 
 // getBestMulticastInterface finds the best network interface for multicast mDNS.
@@ -123,15 +252,16 @@ func isUsableMulticastInterface(iface *net.Interface) bool {
 	return iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagMulticast != 0
 }
 
-// sendHostnameQuery sends an mDNS query for a specific hostname.
-func sendHostnameQuery(conn *net.UDPConn, mcastAddr *net.UDPAddr, hostname string) error {
+// sendHostnameQuery sends an mDNS query for a specific hostname's qtype (A or
+// AAAA) records.
+func sendHostnameQuery(conn *net.UDPConn, mcastAddr *net.UDPAddr, hostname string, qtype dnsmessage.Type) error {
 	var msg dnsmessage.Message
 	msg.ID = 0
 	msg.RecursionDesired = false
 	msg.Questions = []dnsmessage.Question{
 		{
 			Name:  dnsmessage.MustNewName(hostname),
-			Type:  dnsmessage.TypeA,
+			Type:  qtype,
 			Class: dnsmessage.ClassINET,
 		},
 	}
@@ -149,8 +279,11 @@ func sendHostnameQuery(conn *net.UDPConn, mcastAddr *net.UDPAddr, hostname strin
 	return nil
 }
 
-// collectHostnameResponseWithRetry collects mDNS responses for pentair.local hostname with periodic query retries.
-func collectHostnameResponseWithRetry(conn *net.UDPConn, mcastAddr *net.UDPAddr, verbose bool) (string, error) {
+// collectHostnameResponseWithRetry collects mDNS responses for hostname with
+// periodic qtype query retries, until one names the panel, the deadline passes
+// or conn is closed.
+func collectHostnameResponseWithRetry(conn *net.UDPConn, mcastAddr *net.UDPAddr, hostname string, qtype dnsmessage.Type, verbose bool) (string, error) {
+	match := discoveryMatch(hostname)
 	deadline := time.Now().Add(discoveryTimeout)
 	lastQueryTime := time.Time{} // Force immediate first query
 	buffer := make([]byte, maxBufSize)
@@ -161,15 +294,18 @@ func collectHostnameResponseWithRetry(conn *net.UDPConn, mcastAddr *net.UDPAddr,
 		if time.Since(lastQueryTime) >= retryInterval {
 			queryCount++
 			if verbose {
-				log.Printf("Sending mDNS query #%d for pentair.local...", queryCount)
+				log.Printf("Sending mDNS query #%d for %s (%s)...", queryCount, hostname, qtype)
 			}
-			if err := sendHostnameQuery(conn, mcastAddr, "pentair.local."); err != nil {
+			if err := sendHostnameQuery(conn, mcastAddr, hostname+".", qtype); err != nil {
 				return "", err
 			}
 			lastQueryTime = time.Now()
 		}
 
-		ip, found, err := readAndProcessResponse(conn, buffer)
+		ip, found, err := readAndProcessResponse(conn, buffer, match)
+		if errors.Is(err, net.ErrClosed) {
+			return "", err
+		}
 		if err != nil {
 			continue // Continue trying on errors
 		}
@@ -181,10 +317,11 @@ func collectHostnameResponseWithRetry(conn *net.UDPConn, mcastAddr *net.UDPAddr,
 	return "", fmt.Errorf("IntelliCenter not found on network after %v. Ensure IntelliCenter is powered on and connected to the same network", discoveryTimeout)
 }
 
-// readAndProcessResponse reads one mDNS response and checks for pentair IP.
+// readAndProcessResponse reads one mDNS response and checks for an IP whose
+// name contains match.
 //
 //nolint:nonamedreturns // Multiple return values benefit from named returns for clarity
-func readAndProcessResponse(conn *net.UDPConn, buffer []byte) (ip string, found bool, err error) {
+func readAndProcessResponse(conn *net.UDPConn, buffer []byte, match string) (ip string, found bool, err error) {
 	if err = conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
 		return "", false, fmt.Errorf("failed to set read deadline: %w", err)
 	}
@@ -194,21 +331,22 @@ func readAndProcessResponse(conn *net.UDPConn, buffer []byte) (ip string, found
 		return "", false, fmt.Errorf("failed to read from connection: %w", err)
 	}
 
-	return processResponse(buffer[:bytesRead])
+	return processResponse(buffer[:bytesRead], match)
 }
 
-// processResponse unpacks and processes a DNS message looking for pentair IP.
+// processResponse unpacks and processes a DNS message looking for an IP whose
+// name contains match.
 //
 //nolint:nonamedreturns // Multiple return values benefit from named returns for clarity
-func processResponse(data []byte) (ip string, found bool, err error) {
+func processResponse(data []byte, match string) (ip string, found bool, err error) {
 	var response dnsmessage.Message
 	if err = response.Unpack(data); err != nil {
 		return "", false, fmt.Errorf("failed to unpack DNS message: %w", err)
 	}
 
-	// Check A records in answers for pentair.local
+	// Check A and AAAA records in answers for the discovery hostname
 	for i := range response.Answers {
-		if foundIP, foundAnswer := checkAnswerForPentair(&response.Answers[i]); foundAnswer {
+		if foundIP, foundAnswer := matchDiscoveryAnswer(&response.Answers[i], match); foundAnswer {
 			return foundIP, true, nil
 		}
 	}
@@ -216,21 +354,52 @@ func processResponse(data []byte) (ip string, found bool, err error) {
 	return "", false, nil
 }
 
-// checkAnswerForPentair checks if a DNS answer contains pentair IP address.
-func checkAnswerForPentair(answer *dnsmessage.Resource) (string, bool) {
-	if answer.Header.Type != dnsmessage.TypeA {
+// matchDiscoveryAnswer checks if a DNS answer is an A or AAAA record whose
+// name contains match (see discoveryMatch) and returns its IP address.
+func matchDiscoveryAnswer(answer *dnsmessage.Resource, match string) (string, bool) {
+	if answer.Header.Type != dnsmessage.TypeA && answer.Header.Type != dnsmessage.TypeAAAA {
 		return "", false
 	}
 
-	if !strings.Contains(strings.ToLower(answer.Header.Name.String()), "pentair") {
+	if !strings.Contains(strings.ToLower(answer.Header.Name.String()), match) {
 		return "", false
 	}
 
-	a, ok := answer.Body.(*dnsmessage.AResource)
-	if !ok {
-		return "", false
+	switch body := answer.Body.(type) {
+	case *dnsmessage.AResource:
+		if answer.Header.Type == dnsmessage.TypeA {
+			return net.IP(body.A[:]).String(), true
+		}
+	case *dnsmessage.AAAAResource:
+		if answer.Header.Type == dnsmessage.TypeAAAA {
+			return net.IP(body.AAAA[:]).String(), true
+		}
 	}
+	return "", false
+}
 
-	ip := net.IP(a.A[:])
-	return ip.String(), true
+// discoveryMatch returns the substring an answer's name must contain to be the
+// panel: the hostname's first label, lowercased. The stock pentair.local gives
+// "pentair", which also matches the names some firmwares answer with.
+func discoveryMatch(hostname string) string {
+	label, _, _ := strings.Cut(hostname, ".")
+	return strings.ToLower(label)
+}
+
+// parseDiscoverHostname validates a --discover-hostname value and returns it
+// without a trailing dot, e.g. "poolpanel.local".
+func parseDiscoverHostname(value string) (string, error) {
+	hostname := strings.TrimSuffix(strings.TrimSpace(value), ".")
+	if hostname == "" {
+		return "", errors.New("hostname is empty")
+	}
+	for label := range strings.SplitSeq(hostname, ".") {
+		if label == "" || len(label) > maxLabelLen || strings.ContainsFunc(label, unicode.IsSpace) {
+			return "", fmt.Errorf("%q is not a valid hostname", value)
+		}
+	}
+	if _, err := dnsmessage.NewName(hostname + "."); err != nil {
+		return "", fmt.Errorf("%q is not a valid hostname: %w", value, err)
+	}
+	return hostname, nil
 }
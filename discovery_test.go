@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/astrostl/pentameter/intellicenter"
 	"golang.org/x/net/dns/dnsmessage"
 )
 
@@ -19,7 +23,7 @@ func TestDiscoverIntelliCenterTimeout(t *testing.T) {
 		t.Skip("Skipping discovery timeout test in short mode")
 	}
 
-	_, err := DiscoverIntelliCenter(false)
+	_, err := DiscoverIntelliCenter(defaultDiscoverHostname, false)
 	if err == nil {
 		// This could succeed if there's actually an IntelliCenter on the network
 		t.Log("DiscoverIntelliCenter succeeded - IntelliCenter may be present on network")
@@ -45,7 +49,7 @@ func TestSendHostnameQuery(t *testing.T) {
 	defer conn.Close()
 
 	// Test successful query sending
-	err = sendHostnameQuery(conn, mcastAddr, "pentair.local.")
+	err = sendHostnameQuery(conn, mcastAddr, "pentair.local.", dnsmessage.TypeA)
 	if err != nil {
 		t.Errorf("sendHostnameQuery failed: %v", err)
 	}
@@ -64,7 +68,7 @@ func TestSendHostnameQueryClosedConnection(t *testing.T) {
 	conn.Close()
 
 	// Test with closed connection - should fail on WriteTo
-	err = sendHostnameQuery(conn, mcastAddr, "pentair.local.")
+	err = sendHostnameQuery(conn, mcastAddr, "pentair.local.", dnsmessage.TypeA)
 	if err == nil {
 		t.Error("Expected error for closed connection")
 	}
@@ -79,7 +83,7 @@ func TestReadAndProcessResponseSetDeadlineError(t *testing.T) {
 	conn.Close()
 
 	buffer := make([]byte, maxBufSize)
-	_, _, err = readAndProcessResponse(conn, buffer)
+	_, _, err = readAndProcessResponse(conn, buffer, "pentair")
 	if err == nil {
 		t.Error("Expected error from closed connection")
 	}
@@ -99,7 +103,7 @@ func TestReadAndProcessResponseReadError(t *testing.T) {
 	}
 
 	buffer := make([]byte, maxBufSize)
-	_, _, err = readAndProcessResponse(conn, buffer)
+	_, _, err = readAndProcessResponse(conn, buffer, "pentair")
 	if err == nil {
 		t.Error("Expected timeout error from read")
 	}
@@ -109,7 +113,7 @@ func TestProcessResponseInvalidData(t *testing.T) {
 	// Test with invalid DNS message data
 	invalidData := []byte{0x00, 0x01, 0x02}
 
-	_, found, err := processResponse(invalidData)
+	_, found, err := processResponse(invalidData, "pentair")
 	if err == nil {
 		t.Error("Expected error for invalid DNS message")
 	}
@@ -142,7 +146,7 @@ func TestProcessResponseValidButNoPentair(t *testing.T) {
 		t.Fatalf("Failed to pack DNS message: %v", err)
 	}
 
-	ip, found, err := processResponse(packed)
+	ip, found, err := processResponse(packed, "pentair")
 	if err != nil {
 		t.Errorf("processResponse failed: %v", err)
 	}
@@ -178,7 +182,7 @@ func TestProcessResponseWithPentairIP(t *testing.T) {
 		t.Fatalf("Failed to pack DNS message: %v", err)
 	}
 
-	ip, found, err := processResponse(packed)
+	ip, found, err := processResponse(packed, "pentair")
 	if err != nil {
 		t.Errorf("processResponse failed: %v", err)
 	}
@@ -190,25 +194,26 @@ func TestProcessResponseWithPentairIP(t *testing.T) {
 	}
 }
 
-func TestCheckAnswerForPentairNotTypeA(t *testing.T) {
+func TestMatchDiscoveryAnswerNotAddressRecord(t *testing.T) {
 	answer := dnsmessage.Resource{
 		Header: dnsmessage.ResourceHeader{
 			Name:  dnsmessage.MustNewName("pentair.local."),
-			Type:  dnsmessage.TypeAAAA, // IPv6, not A record
+			Type:  dnsmessage.TypeTXT, // neither A nor AAAA
 			Class: dnsmessage.ClassINET,
 		},
+		Body: &dnsmessage.TXTResource{TXT: []string{"192.168.1.1"}},
 	}
 
-	ip, found := checkAnswerForPentair(&answer)
+	ip, found := matchDiscoveryAnswer(&answer, "pentair")
 	if found {
-		t.Error("Should not match non-A record type")
+		t.Error("Should not match a record that isn't A or AAAA")
 	}
 	if ip != "" {
 		t.Errorf("Expected empty IP, got: %s", ip)
 	}
 }
 
-func TestCheckAnswerForPentairNotPentairName(t *testing.T) {
+func TestMatchDiscoveryAnswerNotPentairName(t *testing.T) {
 	answer := dnsmessage.Resource{
 		Header: dnsmessage.ResourceHeader{
 			Name:  dnsmessage.MustNewName("other.local."),
@@ -220,7 +225,7 @@ func TestCheckAnswerForPentairNotPentairName(t *testing.T) {
 		},
 	}
 
-	ip, found := checkAnswerForPentair(&answer)
+	ip, found := matchDiscoveryAnswer(&answer, "pentair")
 	if found {
 		t.Error("Should not match non-pentair hostname")
 	}
@@ -229,7 +234,7 @@ func TestCheckAnswerForPentairNotPentairName(t *testing.T) {
 	}
 }
 
-func TestCheckAnswerForPentairInvalidBody(t *testing.T) {
+func TestMatchDiscoveryAnswerInvalidBody(t *testing.T) {
 	// Create answer with wrong body type (AAAA instead of A)
 	answer := dnsmessage.Resource{
 		Header: dnsmessage.ResourceHeader{
@@ -242,7 +247,7 @@ func TestCheckAnswerForPentairInvalidBody(t *testing.T) {
 		},
 	}
 
-	ip, found := checkAnswerForPentair(&answer)
+	ip, found := matchDiscoveryAnswer(&answer, "pentair")
 	if found {
 		t.Error("Should not match when body type is incorrect")
 	}
@@ -251,7 +256,7 @@ func TestCheckAnswerForPentairInvalidBody(t *testing.T) {
 	}
 }
 
-func TestCheckAnswerForPentairSuccess(t *testing.T) {
+func TestMatchDiscoveryAnswerSuccess(t *testing.T) {
 	answer := dnsmessage.Resource{
 		Header: dnsmessage.ResourceHeader{
 			Name:  dnsmessage.MustNewName("pentair.local."),
@@ -263,7 +268,7 @@ func TestCheckAnswerForPentairSuccess(t *testing.T) {
 		},
 	}
 
-	ip, found := checkAnswerForPentair(&answer)
+	ip, found := matchDiscoveryAnswer(&answer, "pentair")
 	if !found {
 		t.Error("Should match pentair hostname with A record")
 	}
@@ -272,7 +277,105 @@ func TestCheckAnswerForPentairSuccess(t *testing.T) {
 	}
 }
 
-func TestCheckAnswerForPentairCaseInsensitive(t *testing.T) {
+func TestMatchDiscoveryAnswerIPv6(t *testing.T) {
+	answer := dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{
+			Name:  dnsmessage.MustNewName("pentair.local."),
+			Type:  dnsmessage.TypeAAAA,
+			Class: dnsmessage.ClassINET,
+		},
+		Body: &dnsmessage.AAAAResource{
+			AAAA: [16]byte{0xfd, 0x00, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, 0x18},
+		},
+	}
+
+	ip, found := matchDiscoveryAnswer(&answer, "pentair")
+	if !found || ip != "fd00::118" {
+		t.Errorf("AAAA answer = %q, %v; want fd00::118, true", ip, found)
+	}
+}
+
+func TestWithZone(t *testing.T) {
+	iface := &net.Interface{Name: "eth0"}
+	for ip, want := range map[string]string{
+		"fe80::1":     "fe80::1%eth0",
+		"fd00::118":   "fd00::118",
+		testPentairIP: testPentairIP,
+	} {
+		if got, err := withZone(ip, iface); err != nil || got != want {
+			t.Errorf("withZone(%q) = %q, %v; want %q", ip, got, err, want)
+		}
+	}
+	if got, err := withZone("fe80::1", nil); err == nil {
+		t.Errorf("withZone with no interface = %q, want an error", got)
+	}
+	if got, err := withZone("fd00::118", nil); err != nil || got != "fd00::118" {
+		t.Errorf("withZone(global, no interface) = %q, %v; want it unchanged", got, err)
+	}
+}
+
+// TestFirstDiscoveredPrefersIPv4 verifies an IPv6 answer waits out IPv4's head
+// start, and is used once IPv4 fails or the head start runs out.
+func TestFirstDiscoveredPrefersIPv4(t *testing.T) {
+	run := func(ipv4Running bool, headStart time.Duration, rs ...discoveryResult) (string, bool) {
+		results := make(chan discoveryResult, len(rs))
+		for _, r := range rs {
+			results <- r
+		}
+		running := len(rs)
+		if ipv4Running && len(rs) == 1 {
+			running = 2 // IPv4 never answers
+		}
+		return firstDiscovered(results, running, ipv4Running, headStart, make([]error, len(mdnsFamilies)))
+	}
+	v4 := discoveryResult{family: 0, ip: testPentairIP}
+	v6 := discoveryResult{family: 1, ip: "fd00::118"}
+	failed4 := discoveryResult{family: 0, err: errors.New("timeout")}
+
+	if ip, ok := run(true, time.Minute, v6, v4); !ok || ip != testPentairIP {
+		t.Errorf("IPv6 first, then IPv4 = %q, %v; want %s", ip, ok, testPentairIP)
+	}
+	if ip, ok := run(true, time.Minute, v6, failed4); !ok || ip != "fd00::118" {
+		t.Errorf("IPv6, IPv4 failed = %q, %v; want fd00::118", ip, ok)
+	}
+	if ip, ok := run(true, 10*time.Millisecond, v6); !ok || ip != "fd00::118" {
+		t.Errorf("IPv6, head start expired = %q, %v; want fd00::118", ip, ok)
+	}
+	if ip, ok := run(false, time.Minute, v6); !ok || ip != "fd00::118" {
+		t.Errorf("IPv6 only = %q, %v; want fd00::118", ip, ok)
+	}
+}
+
+// TestCollectEndsOnClose verifies closing the listener ends a collector, as
+// discovery does with the other family's once one family has answered.
+func TestCollectEndsOnClose(t *testing.T) {
+	mcastAddr, err := net.ResolveUDPAddr("udp4", mdnsAddress)
+	if err != nil {
+		t.Fatalf("Failed to resolve mDNS address: %v", err)
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	if err != nil {
+		t.Fatalf("Failed to create UDP connection: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := collectHostnameResponseWithRetry(conn, mcastAddr, defaultDiscoverHostname, dnsmessage.TypeA, false)
+		done <- err
+	}()
+	time.Sleep(2 * readTimeout)
+	conn.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Errorf("collector ended with %v, want net.ErrClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("collector kept running after its listener closed")
+	}
+}
+
+func TestMatchDiscoveryAnswerCaseInsensitive(t *testing.T) {
 	// Test that "PENTAIR" (uppercase) is also matched
 	answer := dnsmessage.Resource{
 		Header: dnsmessage.ResourceHeader{
@@ -285,7 +388,7 @@ func TestCheckAnswerForPentairCaseInsensitive(t *testing.T) {
 		},
 	}
 
-	ip, found := checkAnswerForPentair(&answer)
+	ip, found := matchDiscoveryAnswer(&answer, "pentair")
 	if !found {
 		t.Error("Should match pentair hostname case-insensitively")
 	}
@@ -294,6 +397,45 @@ func TestCheckAnswerForPentairCaseInsensitive(t *testing.T) {
 	}
 }
 
+func TestMatchDiscoveryAnswerCustomHostname(t *testing.T) {
+	answer := dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{
+			Name:  dnsmessage.MustNewName("PoolPanel.local."),
+			Type:  dnsmessage.TypeA,
+			Class: dnsmessage.ClassINET,
+		},
+		Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}},
+	}
+
+	match := discoveryMatch("poolpanel.local")
+	if ip, found := matchDiscoveryAnswer(&answer, match); !found || ip != "10.0.0.2" {
+		t.Errorf("renamed panel = %q, %v; want 10.0.0.2, true", ip, found)
+	}
+	if _, found := matchDiscoveryAnswer(&answer, discoveryMatch(defaultDiscoverHostname)); found {
+		t.Error("default hostname matched a renamed panel")
+	}
+}
+
+func TestParseDiscoverHostname(t *testing.T) {
+	for value, want := range map[string]string{
+		"pentair.local":     "pentair.local",
+		" poolpanel.local.": "poolpanel.local",
+		"intellicenter":     "intellicenter",
+	} {
+		if got, err := parseDiscoverHostname(value); err != nil || got != want {
+			t.Errorf("parseDiscoverHostname(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", ".", "pool..local", "pool panel.local", strings.Repeat("a", 64) + ".local"} {
+		if _, err := parseDiscoverHostname(value); err == nil {
+			t.Errorf("parseDiscoverHostname(%q) accepted", value)
+		}
+	}
+	if got := discoveryMatch(defaultDiscoverHostname); got != "pentair" {
+		t.Errorf("default match = %q, want pentair", got)
+	}
+}
+
 func TestDiscoveryConstants(t *testing.T) {
 	// Verify discovery constants have reasonable values
 	if discoveryTimeout != 60*time.Second {
@@ -328,7 +470,7 @@ func TestSendHostnameQueryInvalidHostname(t *testing.T) {
 	defer conn.Close()
 
 	// Test with a valid but different hostname
-	err = sendHostnameQuery(conn, mcastAddr, "test.local.")
+	err = sendHostnameQuery(conn, mcastAddr, "test.local.", dnsmessage.TypeA)
 	if err != nil {
 		t.Errorf("sendHostnameQuery with valid hostname should not fail: %v", err)
 	}
@@ -347,3 +489,96 @@ func TestDiscoverIntelliCenterListenError(t *testing.T) {
 	// misconfiguration or permission issues
 	t.Skip("Cannot test ListenMulticastUDP failure without special setup - system-level error path")
 }
+
+// TestRediscoveryReconnects covers the rediscovery integration end to end with
+// a fake discovery: the configured address is stale and the first discovery
+// fails, so the connect attempt fails; the next discovery returns the panel's
+// new address, and the engine reconnects there and clears connection_failure.
+func TestRediscoveryReconnects(t *testing.T) {
+	mock := newScriptedMock(map[string]IntelliCenterResponse{
+		"GetParamList:OBJTYP=BODY": {ObjectList: []ObjectData{
+			{ObjName: "B4101", Params: map[string]string{"SNAME": "Moved Pool", "STATUS": "ON", "TEMP": "79", "SUBTYP": "POOL"}},
+		}},
+	}, nil)
+	server := mock.serve(t)
+	defer server.Close()
+	newHost, port, _ := strings.Cut(strings.TrimPrefix(server.URL, "http://"), ":")
+
+	var calls atomic.Int32
+	discover := func(string, bool) (string, error) {
+		if calls.Add(1) == 1 {
+			return "", errors.New("no mDNS response")
+		}
+		return newHost, nil
+	}
+
+	cfg := &appConfig{intelliCenterIP: testIntelliCenterIP, intelliCenterPort: port, autoDiscover: true}
+	pm := NewPoolMonitor(cfg.intelliCenterIP, port, false)
+	engine := intellicenter.NewEngine(cfg.intelliCenterIP, port, time.Hour)
+	engine.Resolve = newDiscoveryResolver(cfg, pm.metrics, discover)
+
+	pm.metrics.connectionFailure.Set(0)
+	pm.metrics.lastRefreshTimestamp.Set(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	startMetricsEngine(ctx, pm, engine)
+
+	// The failed discovery is reported as a connection failure...
+	waitForCond(t, func() bool { return gaugeVal(t, pm.metrics.connectionFailure) == 1 })
+
+	// ...and after the engine's backoff the rediscovered address connects.
+	waitForCondWithin(t, 10*time.Second, func() bool { return gaugeVal(t, pm.metrics.lastRefreshTimestamp) != 0 })
+	if got := gaugeVal(t, pm.metrics.connectionFailure); got != 0 {
+		t.Errorf("connection_failure after rediscovery = %v, want 0", got)
+	}
+	if got := gaugeVal(t, pm.metrics.poolTemperature.WithLabelValues("B4101", "POOL", "Moved Pool")); got != 79 {
+		t.Errorf("water temp from rediscovered panel = %v, want 79", got)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("discovery calls = %d, want 2 (one failure, one success)", got)
+	}
+}
+
+func TestRecordDiscovery(t *testing.T) {
+	success := counterVal(t, discoveryAttempts.WithLabelValues("success"))
+	failure := counterVal(t, discoveryAttempts.WithLabelValues("failure"))
+
+	recordDiscovery(1500*time.Millisecond, nil)
+	if got := gaugeVal(t, discoveryDuration); got != 1.5 {
+		t.Errorf("discovery duration = %v, want 1.5", got)
+	}
+	recordDiscovery(60*time.Second, errors.New("not found"))
+	if got := gaugeVal(t, discoveryDuration); got != 60 {
+		t.Errorf("discovery duration = %v, want 60", got)
+	}
+
+	if got := counterVal(t, discoveryAttempts.WithLabelValues("success")) - success; got != 1 {
+		t.Errorf("success attempts increased by %v, want 1", got)
+	}
+	if got := counterVal(t, discoveryAttempts.WithLabelValues("failure")) - failure; got != 1 {
+		t.Errorf("failure attempts increased by %v, want 1", got)
+	}
+}
+
+// TestTargetInfo checks intellicenter_target_info follows the static address
+// at startup and then each rediscovered one, exporting only the current one.
+func TestTargetInfo(t *testing.T) {
+	m := defineControllerMetrics(false)
+	newEngine(&appConfig{intelliCenterIP: testIntelliCenterIP, intelliCenterPort: "6680"}, m)
+	if got := gaugeVal(t, m.targetInfo.WithLabelValues(testIntelliCenterIP, "6680")); got != 1 {
+		t.Errorf("static target info = %v, want 1", got)
+	}
+
+	discover := func(string, bool) (string, error) { return "192.168.1.150", nil }
+	resolve := newDiscoveryResolver(&appConfig{intelliCenterPort: "6680", autoDiscover: true}, m, discover)
+	if _, err := resolve(); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if got := gaugeVal(t, m.targetInfo.WithLabelValues("192.168.1.150", "6680")); got != 1 {
+		t.Errorf("rediscovered target info = %v, want 1", got)
+	}
+	if m.targetInfo.DeleteLabelValues(testIntelliCenterIP, "6680") {
+		t.Error("previous address still exported after rediscovery")
+	}
+}
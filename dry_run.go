@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/astrostl/pentameter/intellicenter"
+)
+
+// runDryRun connects like --once, then writes the equipment the first scan
+// found to out, grouped by kind with each object's objnam, and returns the exit
+// status. It is for first-time setup: what pentameter sees and will export,
+// before committing to a long-running deployment. Objects the metrics skip
+// (features hidden from the panel's menu, generic AUX circuits, combo heat
+// sources) are listed but marked.
+func runDryRun(cfg *appConfig, out io.Writer) int {
+	// Nothing is exported, so the scan records into a set no registry holds.
+	engine, err := scanOnce(cfg, defineControllerMetrics(false))
+	if err != nil {
+		log.Printf("Scan failed: %v", err)
+		return exitOnceFailed
+	}
+	if err := writeInventory(out, engine.Snapshot(), engine.Config()); err != nil {
+		log.Printf("Writing equipment failed: %v", err)
+		return exitOnceFailed
+	}
+	return exitOnceOK
+}
+
+// inventoryLine is one object in the --dry-run listing.
+type inventoryLine struct {
+	objnam string
+	desc   string
+}
+
+// writeInventory writes snap as one section per kind, objnams sorted.
+// featureConfig is each feature's SHOMNU (Engine.Config), which decides
+// whether the feature is exported.
+func writeInventory(out io.Writer, snap intellicenter.Snapshot, featureConfig map[string]string) error {
+	var pm PoolMonitor // isValidCircuit reads no monitor state
+	var circuits, features []inventoryLine
+	for id, c := range snap.Circuits {
+		line := inventoryLine{id, fmt.Sprintf("%s (%s)", c.Name, c.SubType)}
+		switch {
+		case strings.HasPrefix(id, "FTR"):
+			if shomnu, ok := featureConfig[id]; ok && !intellicenter.ShouldShowFeature(shomnu) {
+				line.desc += " [hidden on the panel: not exported]"
+			}
+			features = append(features, line)
+			continue
+		case !pm.isValidCircuit(id, c.Name, c.SubType):
+			line.desc += " [not exported]"
+		}
+		circuits = append(circuits, line)
+	}
+
+	sections := []struct {
+		title string
+		lines []inventoryLine
+	}{
+		{"Bodies", inventoryLines(snap.Bodies, func(b intellicenter.Body) string { return b.Name })},
+		{"Pumps", inventoryLines(snap.Pumps, func(p intellicenter.Pump) string {
+			return fmt.Sprintf("%s (max %.0f RPM)", p.Name, p.MaxRPM)
+		})},
+		{"Circuits", circuits},
+		{"Features", features},
+		{"Heaters", inventoryLines(snap.Heaters, func(h intellicenter.Heater) string {
+			if !h.Real {
+				return h.Name + " [combo heat source: not exported]"
+			}
+			return fmt.Sprintf("%s (%s)", h.Name, h.SubType)
+		})},
+		{"Sensors", inventoryLines(snap.Sensors, func(s intellicenter.Sensor) string {
+			return fmt.Sprintf("%s (%s)", s.Name, s.SubType)
+		})},
+	}
+	var b strings.Builder
+	for _, section := range sections {
+		slices.SortFunc(section.lines, func(x, y inventoryLine) int { return strings.Compare(x.objnam, y.objnam) })
+		fmt.Fprintf(&b, "%s (%d):\n", section.title, len(section.lines))
+		for _, line := range section.lines {
+			fmt.Fprintf(&b, "  %-8s %s\n", line.objnam, line.desc)
+		}
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// inventoryLines describes each object of one kind, keyed by objnam.
+func inventoryLines[T any](objs map[string]T, desc func(T) string) []inventoryLine {
+	lines := make([]inventoryLine, 0, len(objs))
+	for id, o := range objs {
+		lines = append(lines, inventoryLine{id, desc(o)})
+	}
+	return lines
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRunDryRun scans a mock IntelliCenter once and checks the equipment is
+// listed by kind with objnams, and that skipped circuits are marked.
+func TestRunDryRun(t *testing.T) {
+	responses := map[string]IntelliCenterResponse{
+		"GetParamList:OBJTYP=BODY": {ObjectList: []ObjectData{
+			{ObjName: "B1101", Params: map[string]string{"SNAME": "Pool", "STATUS": "ON", "TEMP": "81", "SUBTYP": "POOL", "HTMODE": "0"}},
+		}},
+		"GetParamList:OBJTYP=CIRCUIT": {ObjectList: []ObjectData{
+			{ObjName: "C0001", Params: map[string]string{"SNAME": "Pool Light", "STATUS": "ON", "OBJTYP": "CIRCUIT", "SUBTYP": "LIGHT", "FREEZE": "OFF"}},
+			{ObjName: "C0002", Params: map[string]string{"SNAME": "AUX 2", "STATUS": "OFF", "OBJTYP": "CIRCUIT", "SUBTYP": "GENERIC", "FREEZE": "OFF"}},
+		}},
+		"GetParamList:OBJTYP=PUMP": {ObjectList: []ObjectData{
+			{ObjName: "PMP01", Params: map[string]string{"SNAME": "Pump", "STATUS": "ON", "RPM": "2000", "WATTS": "900", "GPM": "60"}},
+		}},
+	}
+	server := createMockWebSocketServer(t, responses)
+	t.Cleanup(server.Close)
+	host, port, _ := strings.Cut(strings.TrimPrefix(server.URL, "http://"), ":")
+
+	var out bytes.Buffer
+	cfg := &appConfig{intelliCenterIP: host, intelliCenterPort: port, pollInterval: time.Hour}
+	if code := runDryRun(cfg, &out); code != exitOnceOK {
+		t.Fatalf("runDryRun exit status = %d, want %d", code, exitOnceOK)
+	}
+	for _, want := range []string{
+		"Bodies (1):\n  B1101    Pool\n",
+		"  C0001    Pool Light (LIGHT)\n",
+		"  C0002    AUX 2 (GENERIC) [not exported]\n",
+		"Pumps (1):\n  PMP01    Pump",
+		"Heaters (0):\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/astrostl/pentameter/intellicenter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// enumMap is the --enum-map configuration: OBJTYP -> param key -> raw value ->
+// the number exported for it. It lets users graph firmware-specific enum params
+// without a code change.
+type enumMap map[string]map[string]map[string]float64
+
+// enumMetrics holds enum_value.
+type enumMetrics struct {
+	enumValue *prometheus.GaugeVec
+}
+
+// defineEnumMetrics builds enumMetrics.
+func defineEnumMetrics() enumMetrics {
+	return enumMetrics{
+		enumValue: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "enum_value",
+				Help: "Numeric value of a param mapped by --enum-map (OBJTYP.KEY tables). Values missing from the table export no series.",
+			},
+			[]string{fieldObjnam, fieldName, "objtyp", "key"},
+		),
+	}
+}
+
+// kindObjTypes is the IntelliCenter OBJTYP of each engine kind, used when an
+// object's params don't carry OBJTYP themselves.
+var kindObjTypes = map[intellicenter.Kind]string{
+	intellicenter.KindCircuit: objTypeCircuit,
+	intellicenter.KindBody:    objTypeBody,
+	intellicenter.KindPump:    objTypePump,
+	intellicenter.KindHeater:  objTypeHeater,
+	intellicenter.KindSensor:  "SENSE",
+	intellicenter.KindPMPCirc: "PMPCIRC",
+	intellicenter.KindCircGrp: objTypeCircGrp,
+	intellicenter.KindSystem:  "SYSTEM",
+}
+
+// parseEnumMap parses --enum-map ("CIRCGRP.USE=White:1,Blue:2;PUMP.STATUS=10:1,4:0")
+// into an enumMap. OBJTYP and KEY are uppercased; values match case-sensitively.
+func parseEnumMap(spec string) (enumMap, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	m := make(enumMap)
+	for _, table := range strings.Split(spec, ";") {
+		target, entries, ok := strings.Cut(table, "=")
+		objTyp, key, okKey := strings.Cut(strings.TrimSpace(target), ".")
+		objTyp = strings.ToUpper(strings.TrimSpace(objTyp))
+		key = strings.ToUpper(strings.TrimSpace(key))
+		if !ok || !okKey || objTyp == "" || key == "" {
+			return nil, fmt.Errorf("invalid table %q, want OBJTYP.KEY=VALUE:NUMBER,...", table)
+		}
+		if kindForObjTyp(objTyp) == "" {
+			return nil, fmt.Errorf("unsupported OBJTYP %q in %q", objTyp, table)
+		}
+		values := make(map[string]float64)
+		for _, entry := range strings.Split(entries, ",") {
+			value, numStr, ok := strings.Cut(entry, ":")
+			value = strings.TrimSpace(value)
+			num, err := strconv.ParseFloat(strings.TrimSpace(numStr), 64)
+			if !ok || value == "" || err != nil {
+				return nil, fmt.Errorf("invalid entry %q in %q, want VALUE:NUMBER", entry, table)
+			}
+			values[value] = num
+		}
+		if m[objTyp] == nil {
+			m[objTyp] = make(map[string]map[string]float64)
+		}
+		m[objTyp][key] = values
+	}
+	return m, nil
+}
+
+// kindForObjTyp returns the engine kind holding objects of objTyp, or "".
+func kindForObjTyp(objTyp string) intellicenter.Kind {
+	for kind, t := range kindObjTypes {
+		if t == objTyp {
+			return kind
+		}
+	}
+	return ""
+}
+
+// extraKeys lists the params the engine must request so every mapped key is
+// present in its raw objects.
+func (m enumMap) extraKeys() map[intellicenter.Kind][]string {
+	if len(m) == 0 {
+		return nil
+	}
+	extra := make(map[intellicenter.Kind][]string)
+	for objTyp, keys := range m {
+		kind := kindForObjTyp(objTyp)
+		for key := range keys {
+			extra[kind] = append(extra[kind], key)
+		}
+		slices.Sort(extra[kind]) // stable request order
+	}
+	return extra
+}
+
+// applyEnumMetrics exports enum_value for every configured OBJTYP.KEY an object
+// carries. A value missing from its table removes the series instead of
+// exporting a guess; unconfigured types and keys are skipped.
+func (pm *PoolMonitor) applyEnumMetrics(objs []intellicenter.RawObject) {
+	if len(pm.enumMap) == 0 {
+		return
+	}
+	for _, o := range objs {
+		objTyp := o.Params[keyOBJTYP]
+		if objTyp == "" {
+			objTyp = kindObjTypes[o.Kind]
+		}
+		name := o.Params[keySNAME]
+		for key, table := range pm.enumMap[objTyp] {
+			raw, present := o.Params[key]
+			if !present {
+				continue
+			}
+			if num, ok := table[raw]; ok {
+				pm.metrics.enumValue.WithLabelValues(o.ObjName, name, objTyp, key).Set(num)
+			} else {
+				pm.metrics.enumValue.DeleteLabelValues(o.ObjName, name, objTyp, key)
+			}
+		}
+	}
+}
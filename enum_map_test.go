@@ -0,0 +1,60 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/astrostl/pentameter/intellicenter"
+)
+
+func TestParseEnumMap(t *testing.T) {
+	m, err := parseEnumMap("circgrp.use=White:1,Blue:2; PUMP.STATUS=10:1,4:0")
+	if err != nil {
+		t.Fatalf("parseEnumMap: %v", err)
+	}
+	if got := m["CIRCGRP"]["USE"]["Blue"]; got != 2 {
+		t.Errorf("CIRCGRP.USE Blue = %v, want 2", got)
+	}
+	if got := m["PUMP"]["STATUS"]["4"]; got != 0 {
+		t.Errorf("PUMP.STATUS 4 = %v, want 0", got)
+	}
+	extra := m.extraKeys()
+	if !slices.Equal(extra[intellicenter.KindCircGrp], []string{"USE"}) || !slices.Equal(extra[intellicenter.KindPump], []string{"STATUS"}) {
+		t.Errorf("extraKeys = %v", extra)
+	}
+
+	if m, err := parseEnumMap(""); err != nil || m != nil {
+		t.Errorf("empty spec = %v, %v; want nil, nil", m, err)
+	}
+	for _, bad := range []string{"USE=White:1", "CIRCGRP.USE", "CIRCGRP.USE=White", "CIRCGRP.USE=White:x", "VALVE.USE=A:1"} {
+		if _, err := parseEnumMap(bad); err == nil {
+			t.Errorf("parseEnumMap(%q) = nil error, want failure", bad)
+		}
+	}
+}
+
+func TestApplyEnumMetrics(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	objs := []intellicenter.RawObject{
+		{ObjName: "c0101", Kind: intellicenter.KindCircGrp, Params: map[string]string{"USE": "Blue"}},
+		{ObjName: "c0102", Kind: intellicenter.KindCircGrp, Params: map[string]string{"USE": "Magenta"}}, // not in the table
+		{ObjName: "PMP01", Kind: intellicenter.KindPump, Params: map[string]string{"SNAME": "VS", "STATUS": "10"}},
+	}
+
+	pm.applyEnumMetrics(objs) // unconfigured: no-op
+	if pm.metrics.enumValue.DeleteLabelValues("c0101", "", "CIRCGRP", "USE") {
+		t.Error("series exported without --enum-map")
+	}
+
+	pm.enumMap, _ = parseEnumMap("CIRCGRP.USE=White:1,Blue:2")
+	pm.applyEnumMetrics(objs)
+	if got := gaugeVal(t, pm.metrics.enumValue.WithLabelValues("c0101", "", "CIRCGRP", "USE")); got != 2 {
+		t.Errorf("c0101 USE = %v, want 2", got)
+	}
+	if pm.metrics.enumValue.DeleteLabelValues("c0102", "", "CIRCGRP", "USE") {
+		t.Error("unmapped value exported a series")
+	}
+	if pm.metrics.enumValue.DeleteLabelValues("PMP01", "VS", "PUMP", "STATUS") {
+		t.Error("unconfigured OBJTYP.KEY exported a series")
+	}
+}
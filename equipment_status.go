@@ -0,0 +1,94 @@
+package main
+
+import (
+	"github.com/astrostl/pentameter/intellicenter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Normalized intellicenter_equipment_status values, shared by every type.
+const (
+	equipmentStatusOff  = 0.0 // off, stopped, or (heaters) not assigned
+	equipmentStatusOn   = 1.0 // on, running, heating or cooling
+	equipmentStatusIdle = 2.0 // heaters only: assigned to a body but not called for
+)
+
+// equipmentStatusMetrics holds intellicenter_equipment_status.
+type equipmentStatusMetrics struct {
+	equipmentStatus *prometheus.GaugeVec
+}
+
+// defineEquipmentStatusMetrics builds equipmentStatusMetrics.
+func defineEquipmentStatusMetrics() equipmentStatusMetrics {
+	return equipmentStatusMetrics{
+		equipmentStatus: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "intellicenter_equipment_status",
+				Help: "Normalized status of every body, circuit, feature, pump and heater in one family: " +
+					"0=off, 1=on/running/heating/cooling, 2=idle (heaters) (--equipment-status only)",
+			},
+			[]string{fieldObjnam, "objtyp", "subtyp", fieldName},
+		),
+	}
+}
+
+// setEquipmentStatus sets intellicenter_equipment_status for one object, from
+// the same processing that sets its type-specific metric. The series is keyed
+// by objnam: when an object's type, subtype or name changes, its old series is
+// deleted so the family keeps one series per object.
+func (pm *PoolMonitor) setEquipmentStatus(objName, objType, subtype, name string, value float64) {
+	if !pm.equipmentStatus {
+		return
+	}
+	if subtype == "" {
+		subtype = pm.configObjects[objName].SubType // pumps don't poll SUBTYP
+	}
+	labels := []string{objName, objType, subtype, name}
+	if prev, ok := pm.equipmentLabels[objName]; ok && prev != [4]string(labels) {
+		pm.metrics.equipmentStatus.DeleteLabelValues(prev[:]...)
+	}
+	pm.equipmentLabels[objName] = [4]string(labels)
+	pm.metrics.equipmentStatus.WithLabelValues(labels...).Set(value)
+}
+
+// pruneEquipmentStatus deletes the series of objects no longer in raw: the
+// engine forgets equipment a full scan stops returning.
+func (pm *PoolMonitor) pruneEquipmentStatus(raw []intellicenter.RawObject) {
+	present := make(map[string]bool, len(raw))
+	for _, o := range raw {
+		present[o.ObjName] = true
+	}
+	for objName, labels := range pm.equipmentLabels {
+		if !present[objName] {
+			pm.metrics.equipmentStatus.DeleteLabelValues(labels[:]...)
+			delete(pm.equipmentLabels, objName)
+		}
+	}
+}
+
+// circuitEquipmentStatus normalizes a circuit/feature status value, counting
+// freeze protection as on.
+func circuitEquipmentStatus(value float64) float64 {
+	if value == circuitStatusOff {
+		return equipmentStatusOff
+	}
+	return equipmentStatusOn
+}
+
+// thermalEquipmentStatus normalizes a heater's thermal_status value.
+func thermalEquipmentStatus(status int) float64 {
+	switch status {
+	case thermalStatusHeating, thermalStatusCooling:
+		return equipmentStatusOn
+	case thermalStatusIdle:
+		return equipmentStatusIdle
+	}
+	return equipmentStatusOff
+}
+
+// equipmentOnOff normalizes a running flag (body STATUS, pump RPM).
+func equipmentOnOff(running bool) float64 {
+	if running {
+		return equipmentStatusOn
+	}
+	return equipmentStatusOff
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/astrostl/pentameter/intellicenter"
+)
+
+func TestEquipmentStatus(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	pm.configObjects = map[string]intellicenter.ConfigObject{
+		"PMP91": {ID: "PMP91", ObjType: objTypePump, SubType: "SPEED", Name: "Unified VS"},
+	}
+	circuit := ObjectData{ObjName: "C0092", Params: map[string]string{"SNAME": "Unified Light", "SUBTYP": "LIGHT", "STATUS": "ON"}}
+	pump := ObjectData{ObjName: "PMP91", Params: map[string]string{"SNAME": "Unified VS", "RPM": "2400"}}
+
+	// Off by default: nothing exported.
+	pm.processCircuitObject(circuit)
+	if pm.metrics.equipmentStatus.DeleteLabelValues("C0092", objTypeCircuit, "LIGHT", "Unified Light") {
+		t.Error("intellicenter_equipment_status exported without --equipment-status")
+	}
+
+	pm.equipmentStatus = true
+	pm.processCircuitObject(circuit)
+	if got := gaugeVal(t, pm.metrics.equipmentStatus.WithLabelValues("C0092", objTypeCircuit, "LIGHT", "Unified Light")); got != equipmentStatusOn {
+		t.Errorf("circuit equipment status = %v, want 1", got)
+	}
+	if err := pm.processPumpObject(pump, 0); err != nil {
+		t.Fatal(err)
+	}
+	// Pumps don't poll SUBTYP; it comes from the configuration.
+	if got := gaugeVal(t, pm.metrics.equipmentStatus.WithLabelValues("PMP91", objTypePump, "SPEED", "Unified VS")); got != equipmentStatusOn {
+		t.Errorf("pump equipment status = %v, want 1", got)
+	}
+
+	// A renamed object keeps a single series.
+	pm.processCircuitObject(ObjectData{ObjName: "C0092", Params: map[string]string{"SNAME": "Deck Light", "SUBTYP": "LIGHT", "STATUS": "OFF"}})
+	if pm.metrics.equipmentStatus.DeleteLabelValues("C0092", objTypeCircuit, "LIGHT", "Unified Light") {
+		t.Error("rename left the old series behind")
+	}
+	if got := gaugeVal(t, pm.metrics.equipmentStatus.WithLabelValues("C0092", objTypeCircuit, "LIGHT", "Deck Light")); got != equipmentStatusOff {
+		t.Errorf("renamed circuit equipment status = %v, want 0", got)
+	}
+}
+
+func TestPruneEquipmentStatus(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	pm.equipmentStatus = true
+	pm.setEquipmentStatus("C0093", objTypeCircuit, "GENERIC", "Kept", equipmentStatusOn)
+	pm.setEquipmentStatus("C0094", objTypeCircuit, "GENERIC", "Removed", equipmentStatusOn)
+
+	pm.pruneEquipmentStatus([]intellicenter.RawObject{{ObjName: "C0093", Kind: intellicenter.KindCircuit}})
+	if pm.metrics.equipmentStatus.DeleteLabelValues("C0094", objTypeCircuit, "GENERIC", "Removed") {
+		t.Error("removed object's equipment status left behind")
+	}
+	if !pm.metrics.equipmentStatus.DeleteLabelValues("C0093", objTypeCircuit, "GENERIC", "Kept") {
+		t.Error("present object's equipment status pruned")
+	}
+}
+
+func TestThermalEquipmentStatus(t *testing.T) {
+	for status, want := range map[int]float64{
+		thermalStatusOff:     equipmentStatusOff,
+		thermalStatusHeating: equipmentStatusOn,
+		thermalStatusIdle:    equipmentStatusIdle,
+		thermalStatusCooling: equipmentStatusOn,
+	} {
+		if got := thermalEquipmentStatus(status); got != want {
+			t.Errorf("thermalEquipmentStatus(%d) = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -172,12 +172,17 @@ func runHomebridge(cfg *appConfig) {
 	cmds := make(chan hbSet, hbCmdQueueSize)
 	go hbReadStdin(ctx, cmds)
 
-	engine := intellicenter.NewEngine(cfg.intelliCenterIP, cfg.intelliCenterPort, cfg.pollInterval)
-	engine.Logf = log.Printf
-	engine.Resolve = newDiscoveryResolver(cfg)
+	pm := newPoolMonitor("", "", false, cfg.tempUnits == tempUnitsCelsius)
+	pm.booleanStatus = cfg.statusEncoding == statusEncodingBoolean
+	engine := newEngine(cfg, pm.metrics)
+	var watchdog *failureWatchdog
+	if cfg.watchdogTimeout > 0 || cfg.startupTimeout > 0 {
+		watchdog = newFailureWatchdog(cfg.watchdogTimeout, cfg.startupTimeout)
+		go watchdog.run(ctx)
+	}
 
 	log.Printf("[homebridge] starting (poll=%v, configured ip=%q)", cfg.pollInterval, cfg.intelliCenterIP)
-	hbRun(ctx, engine, out, cmds, cfg.httpPort)
+	hbRun(ctx, engine, pm, out, cmds, cfg, watchdog)
 	log.Printf("[homebridge] shutting down")
 }
 
@@ -193,11 +198,14 @@ type hbMetrics struct {
 	ready bool
 }
 
-// startHBMetrics registers the gauges, serves /metrics, and starts a push-driven
-// recompute. It returns a handle whose onScan does the full poll-cadence refresh.
-func startHBMetrics(engine *intellicenter.Engine, port string) *hbMetrics {
-	met := &hbMetrics{pm: NewPoolMonitor("", "", false)}
-	registry := createPrometheusRegistry()
+// startHBMetrics registers pm's gauges, serves /metrics, and starts a
+// push-driven recompute. It returns a handle whose onScan does the full
+// poll-cadence refresh.
+func startHBMetrics(ctx context.Context, cfg *appConfig, pm *PoolMonitor, engine *intellicenter.Engine) *hbMetrics {
+	met := &hbMetrics{pm: pm}
+	registry := createPrometheusRegistry(cfg, pm.metrics)
+	metricsRegisterer(registry, cfg).MustRegister(newDataAgeCollector(pm))
+	pm.metrics.instrumentEngine(engine)
 
 	// Push-driven freshness: recompute on every change between polls. A second
 	// engine subscriber, independent of the shim IPC subscriber. Logging is
@@ -217,13 +225,13 @@ func startHBMetrics(engine *intellicenter.Engine, port string) *hbMetrics {
 	// Bind synchronously: metrics is secondary to HomeKit, so a port conflict is
 	// logged and ignored rather than fatal. Binding before we advertise/log means
 	// we never claim to be "serving" an endpoint that failed to bind.
-	ln, err := bindMetricsServer(registry, met.pm, port)
+	ln, err := bindMetricsServer(registry, met.pm, cfg)
 	if err != nil {
 		log.Printf("[homebridge] metrics server disabled: %v (HomeKit unaffected)", err)
 		return met
 	}
 	go func() {
-		if serr := serveMetrics(ln); serr != nil {
+		if serr := serveHTTP(ctx, ln); serr != nil {
 			log.Printf("[homebridge] metrics server stopped: %v", serr)
 		}
 	}()
@@ -231,12 +239,12 @@ func startHBMetrics(engine *intellicenter.Engine, port string) *hbMetrics {
 	// Advertise the metrics endpoint over mDNS, matching standalone metrics mode.
 	// (Note: ineffective from inside bridge-networked Docker — same limitation
 	// that requires a static IP there — but correct when run on the host/LAN.)
-	if adv, err := StartMDNSAdvertiser(port, false); err != nil {
+	if adv, err := StartMDNSAdvertiser(cfg.httpPort, false); err != nil {
 		log.Printf("[homebridge] mDNS advertisement disabled: %v", err)
 	} else {
 		met.adv = adv
 	}
-	log.Printf("[homebridge] serving Prometheus metrics on :%s/metrics (mDNS-advertised)", port)
+	log.Printf("[homebridge] serving Prometheus metrics on :%s%s (mDNS-advertised)", cfg.httpPort, metricsPath)
 	return met
 }
 
@@ -251,19 +259,18 @@ func (m *hbMetrics) close() {
 }
 
 func (m *hbMetrics) recompute(engine *intellicenter.Engine) {
-	m.mu.Lock()
+	lockTimed(&m.mu, "recompute")
 	defer m.mu.Unlock()
 	m.pm.refreshFromEngine(engine)
 }
 
-// onScan mirrors runMetricsEngine: a failed scan flags the connection-failure
-// gauge; a successful scan does a full logged refresh at the poll cadence.
+// onScan mirrors runMetricsEngine: a failed scan flags the connection- or
+// query-failure gauge; a successful scan does a full logged refresh at the poll
+// cadence.
 func (m *hbMetrics) onScan(engine *intellicenter.Engine, err error) {
-	if err != nil {
-		connectionFailure.Set(1)
+	if !m.pm.metrics.recordScanResult(err) {
 		return
 	}
-	connectionFailure.Set(0)
 	m.mu.Lock()
 	m.ready = true
 	m.mu.Unlock()
@@ -447,8 +454,12 @@ func accessorySignature(items []hbAccessory) string {
 
 // hbRun wires an engine to the shim IPC and blocks on the engine run loop until
 // ctx is canceled. Split out from runHomebridge so it can be driven in tests
-// with an in-memory emitter.
-func hbRun(ctx context.Context, engine *intellicenter.Engine, out *hbEmitter, cmds <-chan hbSet, metricsPort string) {
+// with an in-memory emitter. Metrics are pm's, served on cfg.httpPort. A
+// non-nil watchdog records every scan result, as in metrics mode; the caller
+// runs it.
+func hbRun(ctx context.Context, engine *intellicenter.Engine, pm *PoolMonitor, out *hbEmitter, cmds <-chan hbSet,
+	cfg *appConfig, watchdog *failureWatchdog,
+) {
 	pub := &hbPublisher{}
 	engine.OnRawPoll = func(_ *intellicenter.Client, baseline bool) {
 		if baseline {
@@ -458,10 +469,10 @@ func hbRun(ctx context.Context, engine *intellicenter.Engine, out *hbEmitter, cm
 		}
 	}
 	// Prometheus metrics: one sidecar serves both HomeKit and Grafana. Always on
-	// in production (httpPort has a default); tests pass "" to skip binding a port.
+	// in production (httpPort has a default); tests leave it empty to skip binding a port.
 	var metrics *hbMetrics
-	if metricsPort != "" {
-		metrics = startHBMetrics(engine, metricsPort)
+	if cfg.httpPort != "" {
+		metrics = startHBMetrics(ctx, cfg, pm, engine)
 		defer metrics.close()
 	}
 	// Connection health: report connected/disconnected to the shim on change.
@@ -469,6 +480,9 @@ func hbRun(ctx context.Context, engine *intellicenter.Engine, out *hbEmitter, cm
 	// failure. Emit only on change (and only once announced) to avoid spam.
 	lastConn, firstScan := false, true
 	engine.OnScan = func(err error) {
+		if watchdog != nil {
+			watchdog.record(err)
+		}
 		if metrics != nil {
 			metrics.onScan(engine, err) // full metric refresh + liveness gauges
 		}
@@ -341,7 +341,9 @@ func TestHomebridgeConnectionGoesOfflineOnDisconnect(t *testing.T) {
 	cmds := make(chan hbSet, 4)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	go hbRun(ctx, engine, out, cmds, "")
+	watchdog := newFailureWatchdog(time.Hour, 0)
+	watchdog.exit = func(int) {}
+	go hbRun(ctx, engine, nil, out, cmds, &appConfig{}, watchdog)
 
 	// Baseline announce → the connection sensor exists and is online.
 	waitForCond(t, func() bool { return strings.Contains(buf.String(), `"t":"accessories"`) })
@@ -351,6 +353,14 @@ func TestHomebridgeConnectionGoesOfflineOnDisconnect(t *testing.T) {
 	mock.severConns()
 	waitForCond(t, func() bool { return strings.Contains(buf.String(), `"id":"_conn","on":false`) })
 	cancel()
+
+	// The watchdog saw both the success and the failure that followed it.
+	watchdog.mu.Lock()
+	succeeded, failing := watchdog.succeeded, !watchdog.failingSince.IsZero()
+	watchdog.mu.Unlock()
+	if !succeeded || !failing {
+		t.Errorf("watchdog succeeded=%v failing=%v, want both true", succeeded, failing)
+	}
 }
 
 // TestHomebridgeEngineAnnounces drives the engine against a mock and asserts the
@@ -374,7 +384,7 @@ func TestHomebridgeEngineAnnounces(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	go hbRun(ctx, engine, out, cmds, "")
+	go hbRun(ctx, engine, nil, out, cmds, &appConfig{}, nil)
 
 	waitForCond(t, func() bool { return strings.Contains(buf.String(), `"t":"accessories"`) })
 	cancel()
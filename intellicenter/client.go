@@ -2,11 +2,14 @@ package intellicenter
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"net"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -25,11 +28,58 @@ type Client struct {
 	RetryBaseDelay time.Duration
 	RetryMaxDelay  time.Duration
 
+	// PingFailureThreshold is how many consecutive failed health pings Healthy
+	// tolerates before reporting the connection dead (defaulted in New; values
+	// below 1 behave as 1).
+	PingFailureThreshold int
+
+	// ReadTimeout bounds the wait for each response (defaulted in New). Set it
+	// only while no request is in flight.
+	ReadTimeout time.Duration
+
+	// MaxUnsolicited bounds the messages read while awaiting a response,
+	// unsolicited pushes included (defaulted in New). Zero or less lifts the
+	// count limit, leaving ReadTimeout as the only bound. Set it only while no
+	// request is in flight.
+	MaxUnsolicited int
+
+	// OnSkip, if set, receives each unsolicited message skipped while a request
+	// awaits its response, decoded as a generic map. Set before use; it is read
+	// without locking.
+	OnSkip func(msg map[string]any)
+
+	// OnResponse, if set, receives the round-trip time of each request answered
+	// with its matching response (accepted or rejected), labeled by the
+	// request's messageID prefix (e.g. "pump", "air"). Set before use; it is read
+	// without locking.
+	OnResponse func(prefix string, rtt time.Duration)
+
 	mu   sync.Mutex
 	conn *websocket.Conn
 	seq  int
+	// live mirrors conn for Abort, which must not wait for mu.
+	live atomic.Pointer[websocket.Conn]
 
 	lastHealthCheck time.Time
+	pingFailures    int
+}
+
+// QueryError is an application-level failure: the connection worked, but the
+// panel rejected the request (non-200 response) or never answered it. Anything
+// else a request returns is a transport failure (dial, read, write, timeout).
+type QueryError struct {
+	Command string
+	Reason  string
+}
+
+func (e *QueryError) Error() string {
+	return e.Command + " failed: " + e.Reason
+}
+
+// IsQueryError reports whether err (or anything it wraps) is a QueryError.
+func IsQueryError(err error) bool {
+	var qe *QueryError
+	return errors.As(err, &qe)
 }
 
 // New builds a client for ws://host:port. An empty port defaults to 6680.
@@ -38,10 +88,15 @@ func New(host, port string) *Client {
 		port = defaultICPortStr
 	}
 	return &Client{
-		url:            fmt.Sprintf("ws://%s", net.JoinHostPort(host, port)),
+		// url.URL escapes an IPv6 zone (fe80::1%eth0) as the URL syntax requires.
+		url:            (&url.URL{Scheme: "ws", Host: net.JoinHostPort(host, port)}).String(),
 		RetryMax:       maxRetries,
 		RetryBaseDelay: baseDelay,
 		RetryMaxDelay:  maxDelay,
+
+		PingFailureThreshold: DefaultPingFailures,
+		ReadTimeout:          responseReadTimeout,
+		MaxUnsolicited:       DefaultMaxUnsolicited,
 	}
 }
 
@@ -64,7 +119,9 @@ func (c *Client) Connect(ctx context.Context) error {
 
 	c.mu.Lock()
 	c.conn = conn
+	c.live.Store(conn)
 	c.lastHealthCheck = time.Now()
+	c.pingFailures = 0
 	c.mu.Unlock()
 	return nil
 }
@@ -105,6 +162,16 @@ func (c *Client) Close() {
 	if c.conn != nil {
 		_ = c.conn.Close()
 		c.conn = nil
+		c.live.Store(nil)
+	}
+}
+
+// Abort closes the connection without waiting for a request in flight, as
+// Close does: the pending read fails at once instead of running out
+// ReadTimeout. Call Close afterwards to release the client.
+func (c *Client) Abort() {
+	if conn := c.live.Load(); conn != nil {
+		_ = conn.Close()
 	}
 }
 
@@ -116,7 +183,8 @@ func (c *Client) Connected() bool {
 }
 
 // Healthy pings the server (every healthCheckInterval at most) to detect a dead
-// connection. Returns false if the ping fails.
+// connection. A failed ping is retried on the next call rather than throttled;
+// Healthy returns false only once PingFailureThreshold consecutive pings fail.
 func (c *Client) Healthy() bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -128,12 +196,32 @@ func (c *Client) Healthy() bool {
 	}
 	deadline := time.Now().Add(pingTimeout)
 	if err := c.conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
-		return false
+		c.pingFailures++
+		return c.pingFailures < max(c.PingFailureThreshold, 1)
 	}
 	c.lastHealthCheck = time.Now()
+	c.pingFailures = 0
 	return true
 }
 
+func (c *Client) onSkip(msg map[string]any) {
+	if c.OnSkip != nil {
+		c.OnSkip(msg)
+	}
+}
+
+// skipRaw decodes a skipped message for OnSkip. Decoding is skipped entirely
+// when no hook is set, keeping the common read path allocation-free.
+func (c *Client) skipRaw(data []byte) {
+	if c.OnSkip == nil {
+		return
+	}
+	var msg map[string]any
+	if err := json.Unmarshal(data, &msg); err == nil {
+		c.OnSkip(msg)
+	}
+}
+
 func (c *Client) nextMessageID(prefix string) string {
 	c.seq++
 	return fmt.Sprintf("%s-%d-%d", prefix, time.Now().Unix(), time.Now().Nanosecond()%nanosecondMod)
@@ -150,29 +238,41 @@ func (c *Client) roundTrip(prefix string, req Request) (*Response, error) {
 	}
 	req.MessageID = c.nextMessageID(prefix)
 
+	start := time.Now()
 	if err := c.conn.WriteJSON(req); err != nil {
 		return nil, fmt.Errorf("write %s: %w", req.Command, err)
 	}
 
-	if err := c.conn.SetReadDeadline(time.Now().Add(responseReadTimeout)); err != nil {
+	if err := c.conn.SetReadDeadline(time.Now().Add(c.ReadTimeout)); err != nil {
 		return nil, fmt.Errorf("set read deadline: %w", err)
 	}
 	defer func() { _ = c.conn.SetReadDeadline(time.Time{}) }()
 
-	for range maxUnsolicitedMessages {
+	for read := 0; c.underSkipLimit(read); read++ {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return nil, fmt.Errorf("read %s response: %w", req.Command, err)
+		}
 		var resp Response
-		if err := c.conn.ReadJSON(&resp); err != nil {
+		if err := json.Unmarshal(data, &resp); err != nil {
 			return nil, fmt.Errorf("read %s response: %w", req.Command, err)
 		}
 		if resp.MessageID == req.MessageID {
+			if c.OnResponse != nil {
+				c.OnResponse(prefix, time.Since(start))
+			}
 			if resp.Response != "" && resp.Response != "200" {
-				return nil, fmt.Errorf("%s failed: response=%s", req.Command, resp.Response)
+				return nil, &QueryError{Command: req.Command, Reason: "response=" + resp.Response}
 			}
 			return &resp, nil
 		}
-		// Unsolicited push (NotifyList/WriteParamList) — skip; callers poll for state.
+		// Unsolicited push (NotifyList/WriteParamList) — skip, handing it to OnSkip.
+		c.skipRaw(data)
+	}
+	return nil, &QueryError{
+		Command: req.Command,
+		Reason:  fmt.Sprintf("no matching response for %s after %d messages", req.MessageID, c.MaxUnsolicited),
 	}
-	return nil, fmt.Errorf("no matching response for %s after %d messages", req.MessageID, maxUnsolicitedMessages)
 }
 
 // Do runs an arbitrary typed request through the shared connection and returns
@@ -209,6 +309,12 @@ func (c *Client) ReadMessage() (map[string]any, error) {
 	return msg, nil
 }
 
+// underSkipLimit reports whether a round trip that has read messages so far may
+// read another (see MaxUnsolicited).
+func (c *Client) underSkipLimit(read int) bool {
+	return c.MaxUnsolicited <= 0 || read < c.MaxUnsolicited
+}
+
 // DoRaw runs a request expressed as a generic map and returns the matching
 // response as a generic map. Used for GetConfiguration, whose response envelope
 // ("answer") differs from the standard objectList shape. A fresh messageID is
@@ -225,12 +331,12 @@ func (c *Client) DoRaw(req map[string]any) (map[string]any, error) {
 	if err := c.conn.WriteJSON(req); err != nil {
 		return nil, fmt.Errorf("write raw %v: %w", req["command"], err)
 	}
-	if err := c.conn.SetReadDeadline(time.Now().Add(responseReadTimeout)); err != nil {
+	if err := c.conn.SetReadDeadline(time.Now().Add(c.ReadTimeout)); err != nil {
 		return nil, fmt.Errorf("set read deadline: %w", err)
 	}
 	defer func() { _ = c.conn.SetReadDeadline(time.Time{}) }()
 
-	for range maxUnsolicitedMessages {
+	for read := 0; c.underSkipLimit(read); read++ {
 		var resp map[string]any
 		if err := c.conn.ReadJSON(&resp); err != nil {
 			return nil, fmt.Errorf("read raw response: %w", err)
@@ -238,6 +344,7 @@ func (c *Client) DoRaw(req map[string]any) (map[string]any, error) {
 		if id, ok := resp["messageID"].(string); ok && id == mid {
 			return resp, nil
 		}
+		c.onSkip(resp)
 	}
-	return nil, fmt.Errorf("no matching raw response for %s", mid)
+	return nil, &QueryError{Command: fmt.Sprint(req["command"]), Reason: "no matching raw response for " + mid}
 }
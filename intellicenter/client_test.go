@@ -2,6 +2,7 @@ package intellicenter //nolint:testpackage // white-box: tests exercise unexport
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -15,13 +16,14 @@ import (
 // WebSocket, so the client can be validated without hardware.
 type fakeIC struct {
 	srv     *httptest.Server
-	t       *testing.T
+	t       testing.TB
 	lastSet Request
+	pushes  int // unsolicited pushes sent ahead of each GetParamList response
 }
 
-func newFakeIC(t *testing.T) *fakeIC {
+func newFakeIC(t testing.TB) *fakeIC {
 	t.Helper()
-	f := &fakeIC{t: t}
+	f := &fakeIC{t: t, pushes: 1}
 	up := websocket.Upgrader{}
 	f.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		c, err := up.Upgrade(w, r, nil)
@@ -43,8 +45,10 @@ func newFakeIC(t *testing.T) *fakeIC {
 func (f *fakeIC) handle(c *websocket.Conn, req Request) {
 	switch req.Command {
 	case "GetParamList":
-		// One unsolicited push first, to exercise push-skipping.
-		_ = c.WriteJSON(Response{Command: "NotifyList", MessageID: "push-1", Response: "200"})
+		// Unsolicited pushes first (one by default), to exercise push-skipping.
+		for i := range f.pushes {
+			_ = c.WriteJSON(Response{Command: "NotifyList", MessageID: fmt.Sprintf("push-%d", i+1), Response: "200"})
+		}
 		_ = c.WriteJSON(Response{Command: "GetParamList", MessageID: req.MessageID, Response: "200", ObjectList: f.objectsFor(req.Condition)})
 	case "SetParamList":
 		f.lastSet = req
@@ -75,7 +79,7 @@ func (f *fakeIC) objectsFor(condition string) []ObjectData {
 
 func (f *fakeIC) close() { f.srv.Close() }
 
-func dial(t *testing.T, f *fakeIC) *Client {
+func dial(t testing.TB, f *fakeIC) *Client {
 	t.Helper()
 	addr := strings.TrimPrefix(f.srv.URL, "http://")
 	host, port, _ := strings.Cut(addr, ":")
@@ -88,6 +92,41 @@ func dial(t *testing.T, f *fakeIC) *Client {
 	return c
 }
 
+// BenchmarkClientPoll measures one poll's request/response round trips
+// (circuits, bodies, air sensor) against the in-memory mock, including the
+// skipped push ahead of each response and the typed parsing.
+func BenchmarkClientPoll(b *testing.B) {
+	f := newFakeIC(b)
+	defer f.close()
+	c := dial(b, f)
+	defer c.Close()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := c.Circuits(); err != nil {
+			b.Fatalf("Circuits: %v", err)
+		}
+		if _, err := c.Bodies(); err != nil {
+			b.Fatalf("Bodies: %v", err)
+		}
+		if _, err := c.Sensor("_A135"); err != nil {
+			b.Fatalf("Sensor: %v", err)
+		}
+	}
+}
+
+func TestNewURL(t *testing.T) {
+	for host, want := range map[string]string{
+		"192.168.1.100": "ws://192.168.1.100:6680",
+		"fd00::118":     "ws://[fd00::118]:6680",
+		"fe80::1%eth0":  "ws://[fe80::1%25eth0]:6680",
+	} {
+		if got := New(host, "").url; got != want {
+			t.Errorf("New(%q).url = %q, want %q", host, got, want)
+		}
+	}
+}
+
 func TestCircuitsParsesAndSkipsPush(t *testing.T) {
 	f := newFakeIC(t)
 	defer f.close()
@@ -109,6 +148,52 @@ func TestCircuitsParsesAndSkipsPush(t *testing.T) {
 	}
 }
 
+func TestOnSkipReceivesSkippedPushes(t *testing.T) {
+	f := newFakeIC(t)
+	defer f.close()
+	c := dial(t, f)
+	defer c.Close()
+
+	skipped := 0
+	c.OnSkip = func(msg map[string]any) {
+		if msg["command"] == "NotifyList" {
+			skipped++
+		}
+	}
+	for range 3 {
+		if _, err := c.Circuits(); err != nil {
+			t.Fatalf("Circuits: %v", err)
+		}
+	}
+	if skipped != 3 {
+		t.Errorf("want 3 skipped pushes (one per request), got %d", skipped)
+	}
+}
+
+func TestMaxUnsolicited(t *testing.T) {
+	f := newFakeIC(t)
+	defer f.close()
+	f.pushes = 5 // set before dial: the handler goroutine starts on connect
+	c := dial(t, f)
+	defer c.Close()
+
+	c.MaxUnsolicited = 3
+	if _, err := c.Circuits(); !IsQueryError(err) {
+		t.Fatalf("5 pushes ahead of the response with MaxUnsolicited=3: want QueryError, got %v", err)
+	}
+
+	// Lifting the limit reads past the previous request's leftovers and all
+	// five pushes to this request's response.
+	c.MaxUnsolicited = 0
+	circuits, err := c.Circuits()
+	if err != nil {
+		t.Fatalf("Circuits with MaxUnsolicited=0: %v", err)
+	}
+	if len(circuits) != 2 {
+		t.Errorf("want 2 circuits, got %d", len(circuits))
+	}
+}
+
 func TestBodiesAndHeatStatus(t *testing.T) {
 	f := newFakeIC(t)
 	defer f.close()
@@ -230,6 +315,30 @@ func TestPumpFrom(t *testing.T) {
 	}
 }
 
+func TestPumpWatts(t *testing.T) {
+	cases := []struct {
+		name    string
+		params  map[string]string
+		want    float64
+		present bool
+	}{
+		{"PWR", map[string]string{keyPwr: "215", keyWatts: "WATTS"}, 215, true}, // WATTS is a garbage echo
+		{"WATTS", map[string]string{keyWatts: "900"}, 900, true},
+		{"PWR zero falls back", map[string]string{keyPwr: "0", keyWatts: "450"}, 450, true},
+		{"both", map[string]string{keyPwr: "760", keyWatts: "700"}, 760, true}, // PWR preferred
+		{"stopped", map[string]string{keyPwr: "0"}, 0, true},
+		{"neither", map[string]string{keyRPM: "0"}, 0, false},
+		{"unparseable", map[string]string{keyPwr: "", keyWatts: "WATTS"}, 0, false},
+		{"unparseable PWR, zero WATTS", map[string]string{keyPwr: "n/a", keyWatts: "0"}, 0, true},
+	}
+	for _, tc := range cases {
+		got, present := PumpWatts(tc.params)
+		if got != tc.want || present != tc.present {
+			t.Errorf("%s: PumpWatts = %v, %v; want %v, %v", tc.name, got, present, tc.want, tc.present)
+		}
+	}
+}
+
 func TestShouldShowFeature(t *testing.T) {
 	if !ShouldShowFeature("ABCw") {
 		t.Error("ABCw should be visible")
@@ -238,3 +347,44 @@ func TestShouldShowFeature(t *testing.T) {
 		t.Error("ABC should be hidden")
 	}
 }
+
+func TestRejectedRequestIsQueryError(t *testing.T) {
+	f := newFakeIC(t)
+	defer f.close()
+	c := dial(t, f)
+	defer c.Close()
+
+	// The fake answers unknown commands with response=400.
+	_, err := c.Do(Request{Command: "Bogus"})
+	if err == nil {
+		t.Fatal("want error for rejected request")
+	}
+	if !IsQueryError(fmt.Errorf("poll: %w", err)) {
+		t.Errorf("rejected request should be a (wrappable) QueryError, got %T: %v", err, err)
+	}
+
+	c.Close()
+	if _, err := c.Do(Request{Command: "GetParamList"}); err == nil || IsQueryError(err) {
+		t.Errorf("request on a closed client should be a transport error, got %v", err)
+	}
+}
+
+func TestHealthyToleratesTransientPingFailures(t *testing.T) {
+	f := newFakeIC(t)
+	defer f.close()
+	c := dial(t, f)
+	defer c.Close()
+
+	// Break the socket underneath the client and force a ping on every call.
+	_ = c.conn.UnderlyingConn().Close()
+	for i := 1; i < DefaultPingFailures; i++ {
+		c.lastHealthCheck = time.Time{}
+		if !c.Healthy() {
+			t.Fatalf("ping failure %d of %d should not mark the connection dead", i, DefaultPingFailures)
+		}
+	}
+	c.lastHealthCheck = time.Time{}
+	if c.Healthy() {
+		t.Errorf("connection should be unhealthy after %d consecutive ping failures", DefaultPingFailures)
+	}
+}
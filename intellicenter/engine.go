@@ -2,7 +2,9 @@ package intellicenter
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -11,12 +13,17 @@ import (
 const (
 	engineSubBuffer = 64
 	airSensorObjnam = "_A135"
+	systemObjnam    = "_5451" // system-wide settings (vacation mode)
 	engineReconnect = 2 * time.Second
-	// configRefreshPolls re-pulls the static config (feature visibility + the
-	// circuit⇄pump graph) every N successful polls so a reconfiguration is picked
-	// up without waiting for a reconnect. Cadence rides the poll interval (60
-	// polls = 1h at the default 60s); both fetches are lighter than one equipment
-	// poll, so erring fast just means slightly fresher config.
+	// rebootDowntime is the default RebootDowntime: a panel reboot keeps the
+	// sockets down for a minute or more, while a network blip or a socket reset
+	// reconnects on the first backoff step.
+	rebootDowntime = 60 * time.Second
+	// configRefreshPolls re-pulls the static config (feature visibility, the
+	// circuit⇄pump graph, and circuit-group membership) every N successful polls
+	// so a reconfiguration is picked up without waiting for a reconnect. Cadence
+	// rides the poll interval (60 polls = 1h at the default 60s); each fetch is
+	// lighter than one equipment poll, so erring fast just means fresher config.
 	configRefreshPolls = 60
 	// maxConsecutivePollFailures ends the session after this many consecutive
 	// poll failures, forcing Run's reconnect-with-backoff to dial a fresh
@@ -25,8 +32,18 @@ const (
 	// GetParamList timed out for 113 minutes straight) — without this, only the
 	// push socket failing could ever end a session.
 	maxConsecutivePollFailures = 3
+	// pushQueueSize is the default PushQueue: the pushes read but not yet
+	// processed. Reading runs ahead of processing so a slow OnRawPush consumer
+	// (listen mode holds its monitor lock while printing) cannot stall the
+	// socket during a burst such as a light show; beyond this backlog, pushes
+	// are dropped, not queued.
+	pushQueueSize = 256
 )
 
+// DefaultShutdownGrace is the default Engine.ShutdownGrace: long enough for a
+// typical poll to finish, short enough that a stalled one doesn't hold up exit.
+const DefaultShutdownGrace = 5 * time.Second
+
 // Snapshot is the engine's current view of all known equipment, keyed by objnam.
 type Snapshot struct {
 	Circuits map[string]Circuit
@@ -110,23 +127,169 @@ type Engine struct {
 	// instead of maintaining its own.
 	OnRawPoll func(req *Client, baseline bool)
 
+	// OnPushSkipped, if set, is called for each unsolicited push the request
+	// connection skips while awaiting a poll/control response. A high rate means
+	// the panel is push-heavy. Skipped pushes are still applied to state (see
+	// handleSkippedPush), so no change is lost.
+	OnPushSkipped func()
+
+	// OnPushDropped, if set, is called for each push discarded because push
+	// processing fell more than PushQueue messages behind the socket. The
+	// next poll reconciles whatever the dropped pushes carried.
+	OnPushDropped func()
+
+	// OnReboot, if set, is called when a session comes up after the previous
+	// live session was lost for at least RebootDowntime, with the time spent
+	// down. The panel exposes no uptime, so this is a heuristic: it reads a long
+	// outage as a likely panel reboot, and a network outage that long counts too.
+	OnReboot func(downtime time.Duration)
+
+	// OnReconnect, if set, is called each time both connections come up after
+	// an earlier connect succeeded, so a healthy first connect is not counted
+	// and connection churn is, however short the outage.
+	OnReconnect func()
+
+	// OnQuery, if set, receives the round-trip time of each answered request on
+	// the request connection, labeled by what was asked: the object kind
+	// ("body", "pump", "circuit", "heater", "chem"), "air" for the air sensor, or a
+	// control/config prefix. Set before Run.
+	OnQuery func(command string, rtt time.Duration)
+
+	// OnEmptyResponse, if set, is called when a required query (circuits,
+	// bodies) is answered with an empty object list. Every panel has at least
+	// one body and circuit, so this is a misconfiguration or transient fault
+	// that would otherwise look like a healthy scan. Optional kinds (pumps,
+	// heaters) are legitimately absent on some panels and never report.
+	OnEmptyResponse func(query string)
+
 	// Resolve, if set, is called before every (re)connect to obtain the current
 	// host. It lets the engine follow an IntelliCenter whose IP changes across
 	// reconnects (mDNS rediscovery). nil = always dial the host given to NewEngine.
 	// A Resolve error is treated like a connect failure: backoff, then retry.
 	Resolve func() (string, error)
 
+	// ResolveAfter, once a host is known, holds Resolve back until this many
+	// consecutive connects or sessions have failed, so a brief outage
+	// reconnects to the same host. Scans that only lost some queries (a
+	// QueryError) don't count: the panel answered at this address. Zero or less calls Resolve before
+	// every connect. Set before Run.
+	ResolveAfter int
+
+	// QueryTimeout bounds each steady-state response; BaselineTimeout bounds
+	// each response during a session's baseline, which a cold panel can be slow
+	// to answer. Both are defaulted in NewEngine; set them before Run.
+	QueryTimeout    time.Duration
+	BaselineTimeout time.Duration
+
+	// MaxUnsolicited is the request connection's Client.MaxUnsolicited: how
+	// many messages a response wait may read before giving up, pushes included.
+	// Zero or less leaves QueryTimeout as the only bound. Defaulted in
+	// NewEngine; set it before Run.
+	MaxUnsolicited int
+
+	// PingFailures is the push connection's Client.PingFailureThreshold. The
+	// push socket is pinged every healthCheckInterval, since its reads have no
+	// deadline; this many consecutive failed pings end the session and the
+	// engine reconnects. Defaulted in NewEngine; set it before Run.
+	PingFailures int
+
+	// RebootDowntime is how long a live connection must stay lost before its
+	// reconnect counts as a likely reboot (see OnReboot). Defaulted in NewEngine.
+	RebootDowntime time.Duration
+
+	// QueryPacing, if set, is slept between the sub-queries of a scan, so a slow
+	// panel isn't handed every GetParamList back-to-back. Zero = no delay.
+	QueryPacing time.Duration
+
+	// BatchQueries, if set, reads every equipment type in one unconditioned
+	// GetParamList per scan and splits the answer by OBJTYP, instead of one
+	// request per type. Fewer round trips on a slow panel, at the cost of a
+	// larger answer that includes objects no type uses. Set before Run.
+	BatchQueries bool
+
+	// PollEvery, if set, overrides the poll interval per kind (circuit, body,
+	// pump, heater, chem, sensor, alert), so fast-changing equipment such as
+	// pump RPM can be polled more often than slow-moving bodies. The poll loop
+	// ticks at the shortest interval and delta-scans only the kinds that are
+	// due; each interval is rounded to a multiple of that tick. Full scans, the
+	// config refresh and OnRawPoll keep the engine's poll interval. Set before Run.
+	PollEvery map[Kind]time.Duration
+
+	// PushQueue bounds the pushes read but not yet processed (see
+	// pushQueueSize). Defaulted in NewEngine; set it before Run.
+	PushQueue int
+
+	// ShutdownGrace is how long a poll in flight when ctx is canceled may run
+	// to completion before the engine closes its connections under it. A poll
+	// that finishes applies its results and reports them via OnScan, so
+	// consumers end on a consistent state; zero cuts it short at once.
+	// Defaulted in NewEngine; set it before Run.
+	ShutdownGrace time.Duration
+
+	// ExtraKeys, if set, names params requested for a kind on top of its
+	// built-in keys, on full and delta scans alike, so consumers can read
+	// params the engine doesn't interpret (e.g. config-driven enum metrics).
+	// They land in RawObjects. Set before Run.
+	ExtraKeys map[Kind][]string
+
+	// Schedules, if set, also fetches the SCHED schedule objects at baseline
+	// and on the periodic config refresh. They land in RawObjects. Set before Run.
+	Schedules bool
+
+	// Alerts, if set, also fetches the panel's ALERT objects on every scan,
+	// since faults come and go at runtime. Alerts that disappear from the
+	// answer are dropped. They land in RawObjects. Set before Run.
+	Alerts bool
+
+	// lostAt is when the last live session (one whose baseline completed) ended;
+	// zero until one has. Only Run's goroutine touches it.
+	lostAt time.Time
+	// connectedOnce is set once both connections have come up. Only Run's
+	// goroutine touches it.
+	connectedOnce bool
+
 	mu     sync.RWMutex
 	kind   map[string]Kind
 	params map[string]map[string]string
 	snap   Snapshot
 	config map[string]string // FTR objnam -> SHOMNU (feature visibility), loaded at baseline
+	// objects is the full GetConfiguration object graph (objnam -> identity),
+	// loaded alongside config.
+	objects map[string]ConfigObject
+	// deltaKeys is, per scanned kind, the poll keys the panel actually returned
+	// in the last full scan (see learnKeys); delta polls request only these.
+	deltaKeys map[Kind][]string
+	// emptyKinds is the required kinds whose last answer was empty, so the
+	// warning logs once per streak (see checkEmpty).
+	emptyKinds map[Kind]bool
+	// solarSensors is the solar collector probes the last full scan found (see
+	// scanSolar). Only the scanning goroutine touches it.
+	solarSensors []string
 
 	subsMu sync.Mutex
 	subs   []chan Change
 
 	clientMu  sync.Mutex
 	reqClient *Client
+
+	statusMu sync.Mutex
+	status   Status // Connected is filled in by Status
+	// unreachable counts consecutive failures that leave the panel's address in
+	// doubt (see resolveDue). A QueryError proves the panel answered there, so
+	// it neither counts nor resets the streak.
+	unreachable int
+}
+
+// Status is the engine's connection health, for diagnostics: which panel
+// address it targets, whether a session is up, and how recent scans went.
+type Status struct {
+	Host                string    `json:"host"`
+	Port                string    `json:"port"`
+	Connected           bool      `json:"connected"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastErrorAt         time.Time `json:"last_error_at,omitzero"`
+	LastSuccessAt       time.Time `json:"last_success_at,omitzero"`
 }
 
 // NewEngine builds an engine targeting ws://host:port, polling every pollEvery.
@@ -135,10 +298,25 @@ func NewEngine(host, port string, pollEvery time.Duration) *Engine {
 		host:      host,
 		port:      port,
 		pollEvery: pollEvery,
+
+		QueryTimeout:    responseReadTimeout,
+		BaselineTimeout: baselineReadTimeout,
+		RebootDowntime:  rebootDowntime,
+		PushQueue:       pushQueueSize,
+		MaxUnsolicited:  DefaultMaxUnsolicited,
+		PingFailures:    DefaultPingFailures,
+		ShutdownGrace:   DefaultShutdownGrace,
+
 		kind:      map[string]Kind{},
 		params:    map[string]map[string]string{},
 		snap:      newSnapshot(),
 		config:    map[string]string{},
+		objects:   map[string]ConfigObject{},
+		deltaKeys: map[Kind][]string{},
+
+		emptyKinds: map[Kind]bool{},
+
+		status: Status{Host: host, Port: port},
 	}
 }
 
@@ -149,6 +327,20 @@ func (e *Engine) logf(format string, args ...any) {
 }
 
 func (e *Engine) onScan(err error) {
+	e.statusMu.Lock()
+	if err != nil {
+		e.status.ConsecutiveFailures++
+		e.status.LastError = err.Error()
+		e.status.LastErrorAt = time.Now()
+		if !IsQueryError(err) {
+			e.unreachable++
+		}
+	} else {
+		e.status.ConsecutiveFailures = 0
+		e.status.LastSuccessAt = time.Now()
+		e.unreachable = 0
+	}
+	e.statusMu.Unlock()
 	if e.OnScan != nil {
 		e.OnScan(err)
 	}
@@ -233,6 +425,20 @@ func (e *Engine) Config() map[string]string {
 	return out
 }
 
+// Objects returns a copy of the GetConfiguration object graph (objnam ->
+// type/subtype/name/parent). Empty until the baseline GetConfiguration
+// completes; refreshed on every reconnect and periodic config refresh, and kept
+// as-is when a refresh fails.
+func (e *Engine) Objects() map[string]ConfigObject {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	out := make(map[string]ConfigObject, len(e.objects))
+	for k, v := range e.objects {
+		out[k] = v
+	}
+	return out
+}
+
 // --- control (writes) -----------------------------------------------------
 
 // SetCircuit turns a circuit/feature/body on or off.
@@ -272,11 +478,11 @@ func (e *Engine) setReqClient(c *Client) {
 	e.clientMu.Unlock()
 }
 
-// resolveHost refreshes e.host from the Resolve hook (if set) ahead of a
-// (re)connect. Called only on the Run goroutine, which is the sole reader of
-// e.host, so no lock is needed.
+// resolveHost refreshes e.host from the Resolve hook (if set and due, see
+// ResolveAfter) ahead of a (re)connect. Called only on the Run goroutine, which
+// is the sole reader of e.host, so no lock is needed.
 func (e *Engine) resolveHost() error {
-	if e.Resolve == nil {
+	if e.Resolve == nil || !e.resolveDue() {
 		return nil
 	}
 	host, err := e.Resolve()
@@ -287,9 +493,36 @@ func (e *Engine) resolveHost() error {
 		e.logf("engine: host resolved to %s", host)
 	}
 	e.host = host
+	e.statusMu.Lock()
+	e.status.Host = host
+	e.statusMu.Unlock()
 	return nil
 }
 
+// resolveDue reports whether resolveHost should call Resolve: always until a
+// host is known, then once ResolveAfter consecutive connect or session
+// failures have piled up. Scans that failed only some queries don't count.
+func (e *Engine) resolveDue() bool {
+	if e.host == "" || e.ResolveAfter <= 0 {
+		return true
+	}
+	e.statusMu.Lock()
+	defer e.statusMu.Unlock()
+	return e.unreachable >= e.ResolveAfter
+}
+
+// Status reports the engine's current connection health. Scan results are
+// those reported via OnScan; the last error is kept after a later success.
+func (e *Engine) Status() Status {
+	e.statusMu.Lock()
+	st := e.status
+	e.statusMu.Unlock()
+	e.clientMu.Lock()
+	st.Connected = e.reqClient != nil
+	e.clientMu.Unlock()
+	return st
+}
+
 // --- run loop -------------------------------------------------------------
 
 // Run connects, performs an initial baseline scan, then runs the push stream and
@@ -298,6 +531,9 @@ func (e *Engine) Run(ctx context.Context) error {
 	delay := engineReconnect
 	for ctx.Err() == nil {
 		if err := e.resolveHost(); err != nil {
+			if shutdownErr(ctx, err) {
+				break
+			}
 			e.logf("engine: resolve host failed: %v", err)
 			e.onScan(err)
 			if !sleepCtx(ctx, delay) {
@@ -308,18 +544,25 @@ func (e *Engine) Run(ctx context.Context) error {
 		}
 
 		req := New(e.host, e.port)
+		req.OnSkip = e.handleSkippedPush
+		req.OnResponse = e.OnQuery
+		req.ReadTimeout = e.BaselineTimeout
+		req.MaxUnsolicited = e.MaxUnsolicited
 		push := New(e.host, e.port)
+		push.PingFailureThreshold = e.PingFailures
 
 		if err := req.ConnectWithRetry(ctx); err != nil {
-			e.logf("engine: connect (req) failed: %v", err)
-			e.onScan(err)
+			e.scanFailed(ctx, "connect (req) failed", err)
 		} else if err := push.ConnectWithRetry(ctx); err != nil {
-			e.logf("engine: connect (push) failed: %v", err)
-			e.onScan(err)
+			e.scanFailed(ctx, "connect (push) failed", err)
 			req.Close()
-		} else if err := e.session(ctx, req, push); err != nil {
-			e.logf("engine: session ended: %v", err)
-			e.onScan(err)
+		} else {
+			e.noteConnect()
+			disarm := e.abortAfterGrace(ctx, req, push)
+			if err := e.session(ctx, req, push); err != nil {
+				e.scanFailed(ctx, "session ended", err)
+			}
+			disarm()
 		}
 
 		req.Close()
@@ -336,20 +579,66 @@ func (e *Engine) Run(ctx context.Context) error {
 	return nil // exits only on ctx cancellation — a clean shutdown, not an error
 }
 
+// abortAfterGrace aborts req and push ShutdownGrace after ctx is canceled,
+// unless the session ends first. Close can't be used: it waits for the round
+// trip in flight, which is what the grace bounds. The returned func disarms it.
+func (e *Engine) abortAfterGrace(ctx context.Context, req, push *Client) func() {
+	ended := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		select {
+		case <-ended:
+		case <-time.After(e.ShutdownGrace):
+			if e.ShutdownGrace > 0 {
+				e.logf("engine: poll still in flight %v after shutdown; closing the connections", e.ShutdownGrace)
+			}
+			req.Abort()
+			push.Abort()
+		}
+	})
+	return func() {
+		stop()
+		close(ended)
+	}
+}
+
+// scanFailed logs err and reports it via OnScan, unless it is shutdown noise.
+func (e *Engine) scanFailed(ctx context.Context, what string, err error) {
+	if shutdownErr(ctx, err) {
+		return
+	}
+	e.logf("engine: %s: %v", what, err)
+	e.onScan(err)
+}
+
+// shutdownErr reports whether err is a side effect of ctx being canceled, a
+// graceful shutdown rather than a panel or network fault. Such errors skip the
+// failure accounting: a failed OnScan sets connection_failure and can trigger
+// rediscovery, and a poll error counts toward maxConsecutivePollFailures. A
+// timeout while ctx is still live is a real failure.
+func shutdownErr(ctx context.Context, err error) bool {
+	return ctx.Err() != nil || errors.Is(err, context.Canceled)
+}
+
 // session runs one connected lifetime: baseline, then poll ticker + push loop.
 func (e *Engine) session(ctx context.Context, req, push *Client) error {
-	if err := e.scan(req); err != nil {
+	if err := e.scan(ctx, req, true, nil); err != nil {
 		return fmt.Errorf("baseline: %w", err)
 	}
-	e.loadConfig(req)       // best-effort: feature visibility, never fatal to a session
-	e.scanPumpCircuits(req) // best-effort: static circuit⇄pump graph, fetched once per session
+	e.loadConfig(req)                // best-effort: feature visibility, never fatal to a session
+	e.scanPumpCircuits(req)          // best-effort: static circuit⇄pump graph, fetched once per session
+	e.scanCircuitGroups(req)         // best-effort: static group⇄circuit membership, fetched once per session
+	e.scanSystem(req)                // best-effort: vacation mode
+	e.scanSchedules(req)             // best-effort, opt-in: circuit schedules
+	req.ReadTimeout = e.QueryTimeout // baseline done; nothing else holds req yet
 	e.setReqClient(req)
 	e.onScan(nil) // baseline succeeded → live
 	e.onRawPoll(req, true)
 	e.logf("engine: connected to %s:%s (baseline complete)", e.host, e.port)
+	e.noteReconnect()
+	defer func() { e.lostAt = time.Now() }()
 
 	// pollLoop and pushLoop run on independent sockets (see Engine doc comment);
-	// either can end the session on its own. Whichever returns first wins: Run
+	// either can end the session on its own, as can healthLoop. Whichever returns first wins: Run
 	// then closes both connections, which unblocks whichever loop is still
 	// running (pushLoop's ReadMessage has no deadline, so only a closed socket —
 	// not ctx cancellation — can unblock it) so its goroutine exits cleanly
@@ -360,11 +649,67 @@ func (e *Engine) session(ctx context.Context, req, push *Client) error {
 	pushErr := make(chan error, 1)
 	go func() { pushErr <- e.pushLoop(ctx, push) }()
 
+	healthCtx, stopHealth := context.WithCancel(ctx)
+	defer stopHealth()
+	healthErr := make(chan error, 1)
+	go func() {
+		// Only an unhealthy connection ends the session; on shutdown the poll
+		// and push loops decide when it is over.
+		if err := e.healthLoop(healthCtx, push, healthCheckInterval); err != nil {
+			healthErr <- err
+		}
+	}()
+
 	select {
 	case err := <-pollErr:
 		return err
 	case err := <-pushErr:
 		return err
+	case err := <-healthErr:
+		return err
+	}
+}
+
+// healthLoop pings the push connection every interval and returns an error
+// once it reports unhealthy (PingFailures consecutive failed pings). A push
+// socket left half-open by the panel would otherwise block pushLoop's read
+// forever. It returns nil when ctx is canceled.
+func (e *Engine) healthLoop(ctx context.Context, push *Client, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if !push.Healthy() {
+				return fmt.Errorf("push connection unhealthy: %d consecutive pings failed", max(push.PingFailureThreshold, 1))
+			}
+		}
+	}
+}
+
+// noteConnect reports every connect after the first via OnReconnect.
+func (e *Engine) noteConnect() {
+	if e.connectedOnce && e.OnReconnect != nil {
+		e.OnReconnect()
+	}
+	e.connectedOnce = true
+}
+
+// noteReconnect reports a likely panel reboot via OnReboot when this session
+// follows a live session lost for at least RebootDowntime.
+func (e *Engine) noteReconnect() {
+	if e.lostAt.IsZero() {
+		return // first connection: nothing was lost
+	}
+	down := time.Since(e.lostAt)
+	if down < e.RebootDowntime {
+		return
+	}
+	e.logf("engine: likely panel reboot (connection was down %v)", down.Round(time.Second))
+	if e.OnReboot != nil {
+		e.OnReboot(down)
 	}
 }
 
@@ -375,18 +720,36 @@ func (e *Engine) session(ctx context.Context, req, push *Client) error {
 // GetParamList) would otherwise retry forever on the same broken connection,
 // since only pushLoop failing previously ended a session.
 func (e *Engine) pollLoop(ctx context.Context, req *Client) error {
-	ticker := time.NewTicker(e.pollEvery)
+	tick := e.pollTick()
+	ticker := time.NewTicker(tick)
 	defer ticker.Stop()
 	// Runs in its own goroutine, one call at a time (ticker-driven), so
 	// static-config refreshes reuse req without racing the connection.
 	pollsSinceConfig := 0
 	consecutiveFailures := 0
+	ticks := 0
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		case <-ticker.C:
-			err := e.scan(req)
+			if ctx.Err() != nil {
+				return nil // select picks at random: a tick pending at shutdown must not poll again
+			}
+			ticks++
+			// Without PollEvery every tick is a poll at the engine's interval.
+			poll := ticks%ticksPer(e.pollEvery, tick) == 0
+			// The poll that triggers a config refresh is also a full scan, so
+			// renamed or newly added equipment is picked up on the same cadence.
+			full := poll && pollsSinceConfig+1 >= configRefreshPolls
+			var due func(Kind) bool
+			if !full {
+				due = func(kind Kind) bool { return ticks%e.pollTicks(kind, tick) == 0 }
+			}
+			err := e.scan(ctx, req, full, due)
+			if err != nil && shutdownErr(ctx, err) {
+				return nil
+			}
 			e.onScan(err)
 			if err != nil {
 				consecutiveFailures++
@@ -397,25 +760,84 @@ func (e *Engine) pollLoop(ctx context.Context, req *Client) error {
 				continue
 			}
 			consecutiveFailures = 0
+			if !poll {
+				continue // a PollEvery tick: only the due kinds were scanned
+			}
 			e.onRawPoll(req, false)
 			pollsSinceConfig++
 			if pollsSinceConfig >= configRefreshPolls {
 				pollsSinceConfig = 0
-				e.loadConfig(req)       // best-effort: feature visibility
-				e.scanPumpCircuits(req) // best-effort: circuit⇄pump graph
+				e.loadConfig(req)        // best-effort: feature visibility
+				e.scanPumpCircuits(req)  // best-effort: circuit⇄pump graph
+				e.scanCircuitGroups(req) // best-effort: group⇄circuit membership
+				e.scanSystem(req)        // best-effort: vacation mode
+				e.scanSchedules(req)     // best-effort, opt-in: circuit schedules
 			}
 		}
 	}
 }
 
+// pollTick is the poll loop's ticker period: the shortest of the engine's poll
+// interval and every PollEvery override.
+func (e *Engine) pollTick() time.Duration {
+	tick := e.pollEvery
+	for _, d := range e.PollEvery {
+		if d > 0 && d < tick {
+			tick = d
+		}
+	}
+	return tick
+}
+
+// pollTicks is how many poll-loop ticks apart kind is scanned.
+func (e *Engine) pollTicks(kind Kind, tick time.Duration) int {
+	if d := e.PollEvery[kind]; d > 0 {
+		return ticksPer(d, tick)
+	}
+	return ticksPer(e.pollEvery, tick)
+}
+
+// ticksPer rounds every to a whole number of ticks, at least one.
+func ticksPer(every, tick time.Duration) int {
+	return max(int((every+tick/2)/tick), 1)
+}
+
+// pushLoop reads the push socket and hands each message to a worker through a
+// bounded queue, so processing never blocks the read (see PushQueue). The
+// worker drains what was queued, in order, before the loop returns.
 func (e *Engine) pushLoop(ctx context.Context, push *Client) error {
+	queue := make(chan map[string]any, e.PushQueue)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range queue {
+			e.onRawPush(msg)
+			e.handlePush(msg)
+		}
+	}()
+	defer func() {
+		close(queue)
+		<-done
+	}()
+
+	dropping := false
 	for ctx.Err() == nil {
 		msg, err := push.ReadMessage()
 		if err != nil {
 			return fmt.Errorf("push stream: %w", err)
 		}
-		e.onRawPush(msg)
-		e.handlePush(msg)
+		select {
+		case queue <- msg:
+			dropping = false
+		default:
+			if !dropping {
+				e.logf("engine: push processing %d messages behind; dropping pushes until it catches up", e.PushQueue)
+				dropping = true
+			}
+			if e.OnPushDropped != nil {
+				e.OnPushDropped()
+			}
+		}
 	}
 	return nil // ctx canceled — shutdown, not an error
 }
@@ -423,40 +845,278 @@ func (e *Engine) pushLoop(ctx context.Context, push *Client) error {
 // --- state updates --------------------------------------------------------
 
 type scanGroup struct {
-	kind Kind
-	cond string
-	keys []string
+	kind     Kind
+	cond     string
+	keys     []string
+	pollKeys []string
+	required bool // an empty answer is suspicious, not just an absent kind
 }
 
 var scanGroups = []scanGroup{
-	{KindCircuit, condCircuit, circuitKeys},
-	{KindBody, condBody, bodyKeys},
-	{KindPump, condPump, pumpKeys},
-	{KindHeater, condHeater, heaterKeys},
+	{KindCircuit, condCircuit, circuitKeys, circuitPollKeys, true},
+	{KindBody, condBody, bodyKeys, bodyPollKeys, true},
+	{KindPump, condPump, pumpKeys, pumpPollKeys, false},
+	{KindHeater, condHeater, heaterKeys, heaterPollKeys, false},
+	{KindChem, condChem, chemKeys, chemPollKeys, false},
 }
 
-// scan does a full request/response read of every equipment type plus the air
-// sensor, merging results and emitting changes. Used for the initial baseline
-// and for each poll tick (idempotent: only differences emit).
-func (e *Engine) scan(req *Client) error {
-	for _, g := range scanGroups {
-		objs, err := req.query(string(g.kind), g.cond, g.keys)
-		if err != nil {
-			return err
+// scan does a request/response read of every equipment type plus the air and
+// solar sensors, merging results and emitting changes (idempotent: only differences
+// emit). A full scan requests every key and admits any object with an SNAME; it
+// is used for the baseline and every configRefreshPolls polls. Other polls are
+// delta scans: IntelliCenter has no documented subscription or changed-since
+// query (pushes are its delta mechanism, and pump values are never pushed), so
+// a delta scan narrows each request to the runtime keys and only updates
+// objects a full scan already admitted.
+//
+// due, if non-nil, limits the scan to the kinds it reports (see PollEvery).
+//
+// A type the panel rejects or never answers (a QueryError) doesn't stop the
+// scan: the other types are still read and applied, and the QueryErrors are
+// returned together once it ends, so one flaky sub-query doesn't freeze every
+// metric. A transport error ends the scan at once, since the connection is gone.
+//
+// QueryPacing gaps are cut short once ctx is canceled, so a scan in flight at
+// shutdown finishes within ShutdownGrace instead of sleeping pacing × queries.
+func (e *Engine) scan(ctx context.Context, req *Client, full bool, due func(Kind) bool) error {
+	if due == nil {
+		due = func(Kind) bool { return true }
+	}
+	sent := false
+	pace := func() {
+		if sent && e.QueryPacing > 0 {
+			sleepCtx(ctx, e.QueryPacing)
 		}
-		for _, o := range objs {
-			if o.Params[keySName] == "" {
+		sent = true
+	}
+	var queryErrs []error
+	if e.BatchQueries {
+		pace()
+		if err := e.scanBatched(req, full, due); err != nil {
+			if !IsQueryError(err) {
+				return err
+			}
+			queryErrs = append(queryErrs, err)
+		}
+	} else {
+		for _, g := range scanGroups {
+			if !due(g.kind) {
+				continue
+			}
+			keys, ok := e.scanKeys(&g, full)
+			if !ok {
+				continue
+			}
+			pace()
+			objs, err := req.query(string(g.kind), g.cond, keys)
+			if err != nil {
+				if !IsQueryError(err) {
+					return err
+				}
+				queryErrs = append(queryErrs, err)
 				continue
 			}
-			e.applyAndEmit(g.kind, o.ObjName, o.Params)
+			e.applyGroup(g, full, objs)
+		}
+	}
+	if due(KindSensor) {
+		pace()
+		if params, ok := e.queryObject(req, "air", airSensorObjnam, e.withExtraKeys(KindSensor, sensorKeys)); ok {
+			e.applyAndEmit(KindSensor, airSensorObjnam, params)
+		}
+		e.scanSolar(req, full, pace)
+	}
+	if e.Alerts && due(KindAlert) {
+		pace()
+		e.scanAlerts(req, full)
+	}
+	return errors.Join(queryErrs...)
+}
+
+// scanKeys adds the ExtraKeys to g and returns the keys a scan requests for
+// it: every key on a full scan, the learned runtime keys otherwise. ok is false
+// when a delta scan should skip g (the panel returns none of its runtime keys).
+func (e *Engine) scanKeys(g *scanGroup, full bool) (keys []string, ok bool) {
+	g.keys = e.withExtraKeys(g.kind, g.keys)
+	g.pollKeys = e.withExtraKeys(g.kind, g.pollKeys)
+	if full {
+		return g.keys, true
+	}
+	return e.pollKeysFor(*g)
+}
+
+// learnKeys records which of g's poll keys the panel returned for any object in
+// a full scan, so delta polls stop requesting keys this panel never supplies.
+// Full scans always request every key, so a key that starts appearing (new
+// equipment, a firmware update) is picked up again at the next full scan. With
+// no objects to learn from, delta polls keep the complete poll key set.
+func (e *Engine) learnKeys(g scanGroup, objs []ObjectData) {
+	if len(objs) == 0 {
+		e.mu.Lock()
+		delete(e.deltaKeys, g.kind)
+		e.mu.Unlock()
+		return
+	}
+	seen := map[string]bool{}
+	for _, o := range objs {
+		for k := range o.Params {
+			seen[k] = true
+		}
+	}
+	var keys, dropped []string
+	for _, k := range g.pollKeys {
+		if seen[k] {
+			keys = append(keys, k)
+		} else {
+			dropped = append(dropped, k)
+		}
+	}
+
+	e.mu.Lock()
+	prev, learned := e.deltaKeys[g.kind]
+	e.deltaKeys[g.kind] = keys
+	e.mu.Unlock()
+	if len(dropped) > 0 && (!learned || !slices.Equal(prev, keys)) {
+		e.logf("engine: %s polls skip keys the panel never returns: %v", g.kind, dropped)
+	}
+}
+
+// pollKeysFor returns the keys a delta poll of g requests, and false when the
+// panel returned none of them (the delta query is then skipped).
+func (e *Engine) pollKeysFor(g scanGroup) ([]string, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	keys, learned := e.deltaKeys[g.kind]
+	if !learned {
+		return g.pollKeys, true
+	}
+	return keys, len(keys) > 0
+}
+
+// applyGroup merges one type's answer into the engine state. A full scan also
+// forgets objects of the type it no longer returns (removed from the panel),
+// so their metrics don't linger; an empty answer forgets nothing, since it is
+// more often a transient fault than every object of the type going away.
+func (e *Engine) applyGroup(g scanGroup, full bool, objs []ObjectData) {
+	if g.required {
+		e.checkEmpty(g.kind, len(objs) == 0)
+	}
+	if full {
+		e.learnKeys(g, objs)
+	}
+	seen := make(map[string]bool, len(objs))
+	for _, o := range objs {
+		if full && o.Params[keySName] == "" {
+			continue
+		}
+		if !full && !e.known(g.kind, o.ObjName) {
+			continue
+		}
+		seen[o.ObjName] = true
+		e.applyAndEmit(g.kind, o.ObjName, o.Params)
+	}
+	if full && len(seen) > 0 {
+		e.forgetMissing(g.kind, seen)
+	}
+}
+
+// scanBatched is the BatchQueries form of the scanGroups loop: one
+// GetParamList with no condition, requesting the union of every type's keys
+// plus OBJTYP. The answer is split by OBJTYP and each object keeps only its
+// type's keys, so applyGroup sees what the per-type query would have returned.
+// Objects of other types (valves, sensors, ...) are dropped.
+func (e *Engine) scanBatched(req *Client, full bool, due func(Kind) bool) error {
+	groups := make(map[string]scanGroup, len(scanGroups))
+	wanted := map[string][]string{}
+	union := []string{keyObjTyp}
+	for _, g := range scanGroups {
+		if !due(g.kind) {
+			continue
+		}
+		keys, ok := e.scanKeys(&g, full)
+		if !ok {
+			continue
+		}
+		objType := strings.TrimPrefix(g.cond, "OBJTYP=")
+		groups[objType] = g
+		wanted[objType] = keys
+		for _, k := range keys {
+			if !slices.Contains(union, k) {
+				union = append(union, k)
+			}
 		}
 	}
-	if params, ok := e.querySensor(req, airSensorObjnam); ok {
-		e.applyAndEmit(KindSensor, airSensorObjnam, params)
+	if len(groups) == 0 {
+		return nil
+	}
+	objs, err := req.query("equipment", "", union)
+	if err != nil {
+		return err
+	}
+	byType := map[string][]ObjectData{}
+	for _, o := range objs {
+		objType := o.Params[keyObjTyp]
+		keys, ok := wanted[objType]
+		if !ok {
+			continue
+		}
+		params := make(map[string]string, len(keys))
+		for _, k := range keys {
+			if v, ok := o.Params[k]; ok {
+				params[k] = v
+			}
+		}
+		byType[objType] = append(byType[objType], ObjectData{ObjName: o.ObjName, Params: params})
+	}
+	for _, g := range scanGroups { // scanGroups order: circuits before bodies, as unbatched
+		objType := strings.TrimPrefix(g.cond, "OBJTYP=")
+		if g, ok := groups[objType]; ok {
+			e.applyGroup(g, full, byType[objType])
+		}
 	}
 	return nil
 }
 
+// checkEmpty reports an empty answer to a required query via OnEmptyResponse
+// every time, and logs it once until the kind answers with objects again.
+// The scan still succeeds: the previous values stay, flagged rather than
+// dropped, since an empty answer is usually transient.
+func (e *Engine) checkEmpty(kind Kind, empty bool) {
+	e.mu.Lock()
+	was := e.emptyKinds[kind]
+	if empty {
+		e.emptyKinds[kind] = true
+	} else {
+		delete(e.emptyKinds, kind)
+	}
+	e.mu.Unlock()
+
+	switch {
+	case !empty && was:
+		e.logf("engine: %s query returned objects again", kind)
+	case empty && !was:
+		e.logf("engine: warning: %s query returned no objects; keeping previous values", kind)
+	}
+	if empty && e.OnEmptyResponse != nil {
+		e.OnEmptyResponse(string(kind))
+	}
+}
+
+// withExtraKeys returns keys plus any ExtraKeys for kind not already in it.
+func (e *Engine) withExtraKeys(kind Kind, keys []string) []string {
+	extra := e.ExtraKeys[kind]
+	if len(extra) == 0 {
+		return keys
+	}
+	out := slices.Clone(keys)
+	for _, k := range extra {
+		if !slices.Contains(out, k) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
 // scanPumpCircuits records the PMPCIRC speed-assignment objects that map each
 // driven circuit/feature (CIRCUIT) to the pump that runs it (PARENT). These have
 // no real SNAME, so they bypass the SNAME-gated equipment loop. Stored raw (no
@@ -470,7 +1130,7 @@ func (e *Engine) scan(req *Client) error {
 // reconnect re-baselines and picks up any reconfiguration. Best-effort: a failure
 // here must not break a session.
 func (e *Engine) scanPumpCircuits(req *Client) {
-	objs, err := req.query(string(KindPMPCirc), condPMPCirc, pmpCircKeys)
+	objs, err := req.query(string(KindPMPCirc), condPMPCirc, e.withExtraKeys(KindPMPCirc, pmpCircKeys))
 	if err != nil {
 		e.logf("engine: PMPCIRC scan failed (pump-delivery gating degraded): %v", err)
 		return
@@ -483,11 +1143,135 @@ func (e *Engine) scanPumpCircuits(req *Client) {
 	}
 }
 
-func (e *Engine) querySensor(c *Client, objnam string) (map[string]string, bool) {
-	resp, err := c.roundTrip("sensor", Request{
+// scanCircuitGroups records the CIRCGRP member objects that map each member
+// circuit/feature (CIRCUIT) to the group that contains it (PARENT), so consumers
+// can label member metrics by group. Like PMPCIRC this is static configuration:
+// fetched at baseline and on the periodic config refresh, stored raw, and
+// surfaced via RawObjects. Best-effort: a failure here must not break a session.
+func (e *Engine) scanCircuitGroups(req *Client) {
+	objs, err := req.query(string(KindCircGrp), condCircGrp, e.withExtraKeys(KindCircGrp, circGrpKeys))
+	if err != nil {
+		e.logf("engine: CIRCGRP scan failed (group labels degraded): %v", err)
+		return
+	}
+	for _, o := range objs {
+		if o.Params[keyCircuit] == "" || o.Params[keyParent] == "" {
+			continue
+		}
+		e.applyAndEmit(KindCircGrp, o.ObjName, o.Params)
+	}
+}
+
+// scanSystem records the system object's vacation-mode flag (VACFLO), which
+// changes how schedules and heating behave while the owner is away. Like the
+// static config it is fetched at baseline and on the periodic config refresh;
+// pushes for it apply in between. Panels that don't answer for the object are
+// skipped. Best-effort: a failure here must not break a session.
+func (e *Engine) scanSystem(req *Client) {
+	if params, ok := e.queryObject(req, "system", systemObjnam, e.withExtraKeys(KindSystem, systemKeys)); ok {
+		e.applyAndEmit(KindSystem, systemObjnam, params)
+	}
+}
+
+// scanSchedules records the SCHED objects (which circuit each schedule starts,
+// on which days, and when) when Schedules is set. Schedules are configuration:
+// fetched at baseline and on the periodic config refresh, with pushes (e.g. a
+// schedule enabled or disabled) applied in between. Stored raw and surfaced via
+// RawObjects. Best-effort: a failure here must not break a session.
+func (e *Engine) scanSchedules(req *Client) {
+	if !e.Schedules {
+		return
+	}
+	objs, err := req.query(string(KindSched), condSched, e.withExtraKeys(KindSched, schedKeys))
+	if err != nil {
+		e.logf("engine: SCHED scan failed (schedule metrics degraded): %v", err)
+		return
+	}
+	for _, o := range objs {
+		if o.Params[keyCircuit] == "" {
+			continue
+		}
+		e.applyAndEmit(KindSched, o.ObjName, o.Params)
+	}
+}
+
+// scanSolar reads the solar collector probes: SENSE objects with SUBTYP
+// SOLAR, which panels with solar heating report besides the air sensor. A full
+// scan finds them with one OBJTYP=SENSE query; delta scans read each one found
+// by objnam, as for the air sensor, so panels without solar pay for the query
+// on full scans only. Best-effort: a failure here must not fail the scan.
+func (e *Engine) scanSolar(req *Client, full bool, pace func()) {
+	keys := e.withExtraKeys(KindSensor, sensorKeys)
+	if !full {
+		for _, objnam := range e.solarSensors {
+			pace()
+			if params, ok := e.queryObject(req, "solar", objnam, keys); ok {
+				e.applyAndEmit(KindSensor, objnam, params)
+			}
+		}
+		return
+	}
+	pace()
+	objs, err := req.query("solar", condSense, keys)
+	if err != nil {
+		e.logf("engine: SENSE scan failed (solar metrics degraded): %v", err)
+		return
+	}
+	e.solarSensors = e.solarSensors[:0]
+	for _, o := range objs {
+		if o.Params[keySubTyp] != subTypSolar || o.Params[keySName] == "" {
+			continue
+		}
+		e.solarSensors = append(e.solarSensors, o.ObjName)
+		e.applyAndEmit(KindSensor, o.ObjName, o.Params)
+	}
+}
+
+// scanAlerts replaces the tracked ALERT objects with the panel's current set,
+// so a cleared alert stops being reported. Firmware that exposes no alerts
+// answers with none or rejects the query; either way no alerts are tracked.
+// Failures are logged on full scans only, not every poll. Best-effort: a
+// failure here must not fail the scan.
+func (e *Engine) scanAlerts(req *Client, full bool) {
+	objs, err := req.query(string(KindAlert), condAlert, e.withExtraKeys(KindAlert, alertKeys))
+	if err != nil {
+		if full {
+			e.logf("engine: ALERT scan failed (alert metrics report none): %v", err)
+		}
+		objs = nil
+	}
+	current := make(map[string]bool, len(objs))
+	for _, o := range objs {
+		current[o.ObjName] = true
+		e.applyAndEmit(KindAlert, o.ObjName, o.Params)
+	}
+	e.forgetMissing(KindAlert, current)
+}
+
+// forgetMissing drops every tracked object of kind not in current, from the
+// raw params and the typed snapshot alike.
+func (e *Engine) forgetMissing(kind Kind, current map[string]bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for objnam, k := range e.kind {
+		if k == kind && !current[objnam] {
+			delete(e.kind, objnam)
+			delete(e.params, objnam)
+			delete(e.snap.Circuits, objnam)
+			delete(e.snap.Bodies, objnam)
+			delete(e.snap.Pumps, objnam)
+			delete(e.snap.Heaters, objnam)
+			delete(e.snap.Sensors, objnam)
+		}
+	}
+}
+
+// queryObject reads keys from a single object by objnam.
+func (e *Engine) queryObject(c *Client, prefix, objnam string, keys []string) (map[string]string, bool) {
+	resp, err := c.roundTrip(prefix, Request{
 		Command: cmdGetParamList,
 		// No condition: queried by objnam, matching the hardware-proven air-sensor request.
-		ObjectList: []Object{{ObjName: objnam, Keys: sensorKeys}},
+		ObjectList: []Object{{ObjName: objnam, Keys: keys}},
 	})
 	if err != nil {
 		return nil, false
@@ -501,8 +1285,9 @@ func (e *Engine) querySensor(c *Client, objnam string) (map[string]string, bool)
 }
 
 // loadConfig fetches GetConfiguration and records each feature's SHOMNU flag for
-// visibility decisions. Best-effort: failures leave the config empty (consumers
-// then default to showing all features), never aborting the session.
+// visibility decisions, plus the full objnam -> identity graph. Best-effort:
+// failures keep the previously loaded config (empty before the first success,
+// where consumers default to showing all features), never aborting the session.
 func (e *Engine) loadConfig(req *Client) {
 	resp, err := req.DoRaw(map[string]any{
 		fieldCommand:   cmdGetQuery,
@@ -517,32 +1302,33 @@ func (e *Engine) loadConfig(req *Client) {
 	if !ok {
 		return
 	}
-	cfg := map[string]string{}
-	for _, item := range answer {
-		obj, ok := item.(map[string]any)
-		if !ok {
-			continue
-		}
-		objnam, ok := obj["objnam"].(string)
-		if !ok || !strings.HasPrefix(objnam, ftrPrefix) {
-			continue
-		}
-		params, ok := obj["params"].(map[string]any)
-		if !ok {
-			continue
-		}
-		if shomnu, ok := params[keyShomnu].(string); ok {
-			cfg[objnam] = shomnu
-		}
-	}
+	cfg, objects := parseConfiguration(answer)
 	e.mu.Lock()
 	e.config = cfg
+	e.objects = objects
 	e.mu.Unlock()
 }
 
 // handlePush applies an unsolicited push (WriteParamList/NotifyList). Objects not
-// seen during baseline are skipped; the next poll will pick them up.
+// seen during baseline are skipped; the next poll will pick them up. Frames that
+// don't fit the typed PushFrame model go through the generic map walk instead.
 func (e *Engine) handlePush(msg map[string]any) {
+	frame, err := PushFrameFromMap(msg)
+	if err != nil {
+		e.handlePushMap(msg)
+		return
+	}
+	for _, po := range frame.Objects() {
+		kind, known := e.kindOf(po.ObjName)
+		if !known {
+			continue
+		}
+		e.applyAndEmit(kind, po.ObjName, po.Params.Strings())
+	}
+}
+
+// handlePushMap is handlePush's fallback for frames the typed model rejects.
+func (e *Engine) handlePushMap(msg map[string]any) {
 	for _, po := range extractPushObjects(msg) {
 		kind, known := e.kindOf(po.objnam)
 		if !known {
@@ -552,6 +1338,24 @@ func (e *Engine) handlePush(msg map[string]any) {
 	}
 }
 
+// handleSkippedPush applies a push that arrived on the request connection while
+// a poll/control request awaited its response, so the change lands now instead
+// of waiting on the push connection or the next poll. Only push commands are
+// applied: a stale response to an earlier, timed-out request would otherwise
+// roll state back. The same push typically also arrives on the push connection;
+// applying it twice is harmless, since applyAndEmit only emits real differences.
+// It is deliberately not echoed through OnRawPush, so listen mode prints each
+// push once, from the push connection.
+func (e *Engine) handleSkippedPush(msg map[string]any) {
+	if e.OnPushSkipped != nil {
+		e.OnPushSkipped()
+	}
+	switch msg[fieldCommand] {
+	case cmdWriteParamList, cmdNotifyList:
+		e.handlePush(msg)
+	}
+}
+
 func (e *Engine) kindOf(objnam string) (Kind, bool) {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
@@ -559,6 +1363,13 @@ func (e *Engine) kindOf(objnam string) (Kind, bool) {
 	return k, ok
 }
 
+// known reports whether objnam has already been admitted as kind.
+func (e *Engine) known(kind Kind, objnam string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.kind[objnam] == kind
+}
+
 // applyAndEmit merges partial params for an object, reparses it, and emits a
 // Change if the typed value changed.
 func (e *Engine) applyAndEmit(kind Kind, objnam string, partial map[string]string) {
@@ -636,10 +1447,13 @@ func (e *Engine) reparseLocked(kind Kind, objnam string, params map[string]strin
 	case KindSensor:
 		v := sensorFrom(objnam, params)
 		return Change{Sensor: &v}, diffStore(e.snap.Sensors, objnam, v)
-	case KindPMPCirc:
-		// Raw-only: PMPCIRC speed assignments are merged into e.params for the
-		// metrics engine's circuit⇄pump gating, but carry no typed snapshot and
-		// emit no Change (static config, not live equipment state).
+	case KindPMPCirc, KindCircGrp, KindSystem, KindSched, KindAlert, KindChem:
+		// Raw-only: PMPCIRC speed assignments, CIRCGRP memberships, the system
+		// object, schedules, alerts and IntelliChem readings are merged into
+		// e.params for the metrics engine (circuit⇄pump gating, group labels,
+		// vacation mode, next-run countdowns, alert counts, water chemistry),
+		// but carry no typed snapshot and emit no Change (configuration and
+		// panel status; chemistry has no consumer that needs a typed value).
 		return Change{}, false
 	default:
 		return Change{}, false
@@ -653,9 +1467,10 @@ type pushObject struct {
 	params map[string]string
 }
 
-// extractPushObjects pulls {objnam, params} pairs out of an IntelliCenter push.
-// It tolerates both shapes seen in the wild: objectList[].{objnam,params} and
-// objectList[].changes[].{objnam,params}.
+// extractPushObjects pulls {objnam, params} pairs out of an IntelliCenter push
+// by generic map traversal, salvaging what it can from frames ParsePushFrame
+// rejects. It tolerates both shapes seen in the wild: objectList[].{objnam,params}
+// and objectList[].changes[].{objnam,params}.
 func extractPushObjects(msg map[string]any) []pushObject {
 	list, ok := msg["objectList"].([]any)
 	if !ok {
@@ -710,11 +1525,15 @@ func toPushObject(obj map[string]any) (pushObject, bool) {
 
 // --- backoff helpers ------------------------------------------------------
 
+// sleepCtx sleeps for d and reports true, or returns false as soon as ctx is
+// canceled.
 func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
 	select {
 	case <-ctx.Done():
 		return false
-	case <-time.After(d):
+	case <-timer.C:
 		return true
 	}
 }
@@ -2,8 +2,10 @@ package intellicenter //nolint:testpackage // white-box: exercises unexported en
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -123,6 +125,18 @@ func TestEngineRunBaselineControlPush(t *testing.T) {
 	if !ShouldShowFeature(cfg["FTR01"]) || ShouldShowFeature(cfg["FTR02"]) {
 		t.Errorf("visibility wrong: FTR01=%v FTR02=%v", cfg["FTR01"], cfg["FTR02"])
 	}
+	if _, ok := cfg["B1101"]; ok {
+		t.Error("feature visibility config should hold only FTR objects")
+	}
+
+	// ...and the full object graph, including nested OBJLIST children.
+	objs := e.Objects()
+	if b := objs["B1101"]; b.ObjType != "BODY" || b.SubType != "POOL" || b.Name != "Pool" || b.Parent != "" {
+		t.Errorf("body identity wrong: %+v", b)
+	}
+	if v := objs["V0101"]; v.ObjType != "VALVE" || v.Name != "Intake" || v.Parent != "B1101" {
+		t.Errorf("nested valve identity wrong: %+v", v)
+	}
 
 	// RawObjects exposes merged params + kind for full-fidelity sweeps.
 	raw := map[string]RawObject{}
@@ -189,11 +203,518 @@ func TestEnginePMPCircBaselineAndRefresh(t *testing.T) {
 	if pc.Kind != KindPMPCirc || pc.Params["CIRCUIT"] != "C0001" || pc.Params["PARENT"] != "PMP01" {
 		t.Fatalf("PMPCIRC not surfaced via RawObjects at baseline: %+v", pc)
 	}
+	// Later baseline scans land after the PMPCIRC query returns, so wait for
+	// each of their objects rather than for a query count.
+	rawObject := func(objnam string) RawObject {
+		var found RawObject
+		waitFor(t, func() bool {
+			for _, o := range e.RawObjects() {
+				if o.ObjName == objnam {
+					found = o
+					return true
+				}
+			}
+			return false
+		})
+		return found
+	}
+	if cg := rawObject("c0101"); cg.Kind != KindCircGrp || cg.Params["CIRCUIT"] != "C0001" || cg.Params["PARENT"] != "GRP01" {
+		t.Fatalf("CIRCGRP not surfaced via RawObjects at baseline: %+v", cg)
+	}
+	if sys := rawObject(systemObjnam); sys.Kind != KindSystem || sys.Params["VACFLO"] != "ON" {
+		t.Fatalf("system object not surfaced via RawObjects at baseline: %+v", sys)
+	}
 
 	// After configRefreshPolls successful polls, both static-config fetches run again.
 	waitFor(t, func() bool { return mock.pmpcQueries.Load() >= 2 && mock.cfgQueries.Load() >= 2 })
 }
 
+// TestEngineSchedules verifies SCHED objects are fetched and surfaced via
+// RawObjects only when Schedules is set.
+func TestEngineSchedules(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	off := NewEngine(host, port, time.Hour)
+	go func() { _ = off.Run(ctx) }()
+	waitFor(t, func() bool { return mock.pmpcQueries.Load() >= 1 })
+
+	on := NewEngine(host, port, time.Hour)
+	on.Schedules = true
+	go func() { _ = on.Run(ctx) }()
+	waitFor(t, func() bool { return mock.schedQueries.Load() >= 1 })
+	waitFor(t, func() bool {
+		for _, o := range on.RawObjects() {
+			if o.ObjName == "SCH01" {
+				return o.Kind == KindSched && o.Params["TIME"] == "19,30,00"
+			}
+		}
+		return false
+	})
+	for _, o := range off.RawObjects() {
+		if o.Kind == KindSched {
+			t.Errorf("schedule surfaced without Schedules: %+v", o)
+		}
+	}
+}
+
+// TestEngineOnQuery verifies answered requests report their round-trip time
+// labeled by what was asked.
+func TestEngineOnQuery(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	var mu sync.Mutex
+	seen := map[string]int{}
+	e := NewEngine(host, port, time.Hour)
+	e.OnQuery = func(command string, rtt time.Duration) {
+		if rtt < 0 {
+			t.Errorf("%s: negative round trip %v", command, rtt)
+		}
+		mu.Lock()
+		seen[command]++
+		mu.Unlock()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = e.Run(ctx) }()
+
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, cmd := range []string{"circuit", "body", "pump", "heater", "air"} {
+			if seen[cmd] == 0 {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// TestEngineEmptyResponse verifies an empty answer to a required query is
+// reported through OnEmptyResponse while empty optional kinds (this fixture
+// has no pumps or heaters) are not, and that the scan still succeeds.
+func TestEngineEmptyResponse(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	mock.noBodies.Store(true)
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	var mu sync.Mutex
+	seen := map[string]int{}
+	var scanned atomic.Bool
+	e := NewEngine(host, port, time.Hour)
+	e.OnEmptyResponse = func(query string) {
+		mu.Lock()
+		seen[query]++
+		mu.Unlock()
+	}
+	e.OnScan = func(err error) {
+		if err == nil {
+			scanned.Store(true)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = e.Run(ctx) }()
+
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return seen[string(KindBody)] > 0
+	})
+	waitFor(t, scanned.Load) // the scan still succeeds
+	mu.Lock()
+	defer mu.Unlock()
+	for _, kind := range []Kind{KindCircuit, KindPump, KindHeater} {
+		if seen[string(kind)] != 0 {
+			t.Errorf("%s reported empty: %v", kind, seen)
+		}
+	}
+}
+
+// TestEngineChem verifies IntelliChem objects are scanned and surfaced raw.
+func TestEngineChem(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	e := NewEngine(host, port, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = e.Run(ctx) }()
+
+	waitFor(t, func() bool {
+		for _, o := range e.RawObjects() {
+			if o.Kind == KindChem && o.ObjName == "CHR01" {
+				return o.Params[keyPHVal] == "7.4" && o.Params[keyORPTank] == "5"
+			}
+		}
+		return false
+	})
+}
+
+// TestEngineBatchQueries verifies BatchQueries reads every type in one
+// unconditioned query, splits it by OBJTYP, and ignores types no scan uses.
+func TestEngineBatchQueries(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	e := NewEngine(host, port, 20*time.Millisecond)
+	e.BatchQueries = true
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = e.Run(ctx) }()
+
+	waitFor(t, func() bool { return mock.batchQueries.Load() >= 3 }) // baseline plus delta polls
+	snap := e.Snapshot()
+	if c := snap.Circuits["C0001"]; c.Name != "Pool Light" || !c.On {
+		t.Errorf("circuit = %+v, want Pool Light on", c)
+	}
+	if b := snap.Bodies["B1101"]; b.Name != "Pool" || b.Temp != 82 {
+		t.Errorf("body = %+v, want Pool at 82", b)
+	}
+	chem := ""
+	for _, o := range e.RawObjects() {
+		if o.ObjName == "V0101" {
+			t.Errorf("valve admitted as %s", o.Kind)
+		}
+		if o.Kind == KindChem {
+			chem = o.Params[keyPHVal]
+		}
+	}
+	if chem != "7.4" {
+		t.Errorf("chem pH = %q, want 7.4", chem)
+	}
+	if n := mock.circuitCalls.Load(); n != 0 {
+		t.Errorf("%d per-type circuit queries with BatchQueries set, want 0", n)
+	}
+}
+
+// TestEnginePollEvery verifies a PollEvery override polls its kind on its own,
+// faster cadence while other kinds keep the engine's poll interval.
+func TestEnginePollEvery(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	e := NewEngine(host, port, 100*time.Millisecond)
+	e.PollEvery = map[Kind]time.Duration{KindPump: 20 * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = e.Run(ctx) }()
+
+	// Baseline plus five pump ticks; only the fifth is also a circuit poll.
+	waitFor(t, func() bool { return mock.pumpCalls.Load() >= 6 })
+	if n := mock.circuitCalls.Load(); n > 2 {
+		t.Errorf("%d circuit queries after five 20ms pump ticks, want at most 2 (baseline + one 100ms poll)", n)
+	}
+	waitFor(t, func() bool { return mock.circuitCalls.Load() >= 3 })
+	if p, c := mock.pumpCalls.Load(), mock.circuitCalls.Load(); p < 2*c {
+		t.Errorf("pump queries = %d, circuit queries = %d; want pumps polled more often", p, c)
+	}
+}
+
+// TestEngineSolarSensors verifies the full scan finds the solar collector
+// probe among the SENSE objects, and delta polls read it by objnam.
+func TestEngineSolarSensors(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	e := NewEngine(host, port, 10*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = e.Run(ctx) }()
+
+	waitFor(t, func() bool { return e.Snapshot().Sensors["SSS11"].Name == "Solar Sensor" })
+	waitFor(t, func() bool { return e.Snapshot().Sensors["SSS11"].Temp == 110 })
+	if mock.solarCalls.Load() == 0 {
+		t.Error("delta polls never read the solar sensor by objnam")
+	}
+}
+
+// TestEngineAlerts verifies ALERT objects are surfaced via RawObjects only
+// when Alerts is set, and that a cleared alert is dropped on the next poll.
+func TestEngineAlerts(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	off := NewEngine(host, port, time.Hour)
+	go func() { _ = off.Run(ctx) }()
+	waitFor(t, func() bool { return mock.pmpcQueries.Load() >= 1 })
+
+	hasAlert := func(e *Engine) bool {
+		for _, o := range e.RawObjects() {
+			if o.Kind == KindAlert && o.ObjName == "ALT01" {
+				return true
+			}
+		}
+		return false
+	}
+	on := NewEngine(host, port, 20*time.Millisecond)
+	on.Alerts = true
+	go func() { _ = on.Run(ctx) }()
+	waitFor(t, func() bool { return hasAlert(on) })
+	if hasAlert(off) {
+		t.Error("alert surfaced without Alerts")
+	}
+
+	mock.alertCleared.Store(true)
+	waitFor(t, func() bool { return !hasAlert(on) })
+}
+
+// TestEngineExtraKeys verifies ExtraKeys are appended to a kind's requested
+// keys without duplicating a built-in key.
+func TestEngineExtraKeys(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	e := NewEngine(host, port, time.Hour)
+	e.ExtraKeys = map[Kind][]string{KindCircuit: {"USAGE", keyStatus}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = e.Run(ctx) }()
+
+	waitFor(t, func() bool { return mock.circuitCalls.Load() >= 1 })
+	want := strings.Join(append(slices.Clone(circuitKeys), "USAGE"), ",")
+	if got := strings.Join(mock.circuitKeysAt(0), ","); got != want {
+		t.Errorf("baseline keys = %v, want %v", got, want)
+	}
+}
+
+// TestEngineDeltaPolls verifies polls between full scans request only the
+// runtime keys and update only objects a full scan admitted, while the baseline
+// and the periodic refresh poll request every key, pick up new equipment and
+// forget removed equipment.
+func TestEngineDeltaPolls(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	e := NewEngine(host, port, time.Millisecond) // fast poll so the refresh full scan fires quickly
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = e.Run(ctx) }()
+
+	waitFor(t, func() bool { return e.Snapshot().Circuits["C0001"].Name == "Pool Light" })
+	waitFor(t, func() bool { return mock.circuitCalls.Load() >= 2 })
+	if got := mock.circuitKeysAt(0); strings.Join(got, ",") != strings.Join(circuitKeys, ",") {
+		t.Errorf("baseline keys = %v, want full %v", got, circuitKeys)
+	}
+	if got := mock.circuitKeysAt(1); strings.Join(got, ",") != strings.Join(circuitPollKeys, ",") {
+		t.Errorf("poll keys = %v, want delta %v", got, circuitPollKeys)
+	}
+
+	// New equipment is ignored by delta polls, then admitted by the next full scan.
+	mock.extraCircuit.Store(true)
+	seen := mock.circuitCalls.Load()
+	waitFor(t, func() bool { return mock.circuitCalls.Load() >= seen+2 })
+	if _, ok := e.Snapshot().Circuits["C0002"]; ok && mock.circuitCalls.Load() < configRefreshPolls {
+		t.Error("delta poll should not admit an object no full scan has seen")
+	}
+	waitFor(t, func() bool { return e.Snapshot().Circuits["C0002"].Name == "Cleaner" })
+	if got := mock.circuitKeysAt(configRefreshPolls); strings.Join(got, ",") != strings.Join(circuitKeys, ",") {
+		t.Errorf("refresh poll keys = %v, want full %v", got, circuitKeys)
+	}
+
+	// Removed equipment is forgotten by the next full scan, raw params included.
+	mock.extraCircuit.Store(false)
+	waitFor(t, func() bool { _, ok := e.Snapshot().Circuits["C0002"]; return !ok })
+	for _, o := range e.RawObjects() {
+		if o.ObjName == "C0002" {
+			t.Error("removed circuit still in RawObjects")
+		}
+	}
+	if _, ok := e.Snapshot().Circuits["C0001"]; !ok {
+		t.Error("full scan forgot a circuit it still returns")
+	}
+}
+
+// TestEnginePushBackpressure stalls push processing (a slow OnRawPush) under a
+// burst: reads keep going, pushes beyond the queue are dropped and counted, and
+// once processing resumes later pushes are applied normally.
+func TestEnginePushBackpressure(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	e := NewEngine(host, port, time.Hour)
+	release := make(chan struct{})
+	var stalled, dropped atomic.Int32
+	e.OnRawPush = func(map[string]any) {
+		if stalled.Add(1) == 1 {
+			<-release
+		}
+	}
+	e.OnPushDropped = func() { dropped.Add(1) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = e.Run(ctx) }()
+	waitFor(t, func() bool { return e.Snapshot().Circuits["C0001"].Name == "Pool Light" })
+
+	burst := map[string]any{
+		"command":    "NotifyList",
+		"objectList": []any{map[string]any{"objnam": "C0001", "params": map[string]any{"STATUS": "ON"}}},
+	}
+	for range pushQueueSize + 10 {
+		mock.broadcast(burst)
+	}
+	waitFor(t, func() bool { return dropped.Load() > 0 })
+	close(release)
+
+	mock.broadcast(map[string]any{
+		"command":    "NotifyList",
+		"objectList": []any{map[string]any{"objnam": "C0001", "params": map[string]any{"STATUS": "OFF"}}},
+	})
+	waitFor(t, func() bool { return !e.Snapshot().Circuits["C0001"].On })
+}
+
+// TestEngineLearnsPollKeys verifies delta polls drop runtime keys the panel
+// never returned in a full scan, and fall back to the full poll set when the
+// full scan found no objects to learn from.
+func TestEngineLearnsPollKeys(t *testing.T) {
+	e := NewEngine("h", "6680", time.Hour)
+	circuits := scanGroups[0]
+
+	if keys, ok := e.pollKeysFor(circuits); !ok || !slices.Equal(keys, circuitPollKeys) {
+		t.Fatalf("before any full scan: keys = %v, %v; want all of %v", keys, ok, circuitPollKeys)
+	}
+
+	// This panel never returns FREEZE.
+	e.learnKeys(circuits, []ObjectData{
+		{ObjName: "C0001", Params: map[string]string{keySName: "Pool Light", keyStatus: "ON"}},
+		{ObjName: "C0002", Params: map[string]string{keySName: "Cleaner", keyStatus: "OFF"}},
+	})
+	if keys, ok := e.pollKeysFor(circuits); !ok || !slices.Equal(keys, []string{keyStatus}) {
+		t.Errorf("after learning: keys = %v, %v; want [%s]", keys, ok, keyStatus)
+	}
+
+	// None of the runtime keys returned: the delta query is skipped entirely.
+	e.learnKeys(circuits, []ObjectData{{ObjName: "C0001", Params: map[string]string{keySName: "Pool Light"}}})
+	if _, ok := e.pollKeysFor(circuits); ok {
+		t.Error("delta poll should be skipped when the panel returns no runtime keys")
+	}
+
+	// No objects to learn from: back to the full poll key set.
+	e.learnKeys(circuits, nil)
+	if keys, ok := e.pollKeysFor(circuits); !ok || !slices.Equal(keys, circuitPollKeys) {
+		t.Errorf("after empty scan: keys = %v, %v; want all of %v", keys, ok, circuitPollKeys)
+	}
+}
+
+// TestEngineBaselineTimeout verifies a panel that is slow to answer its first
+// query still completes the baseline under BaselineTimeout, even though the
+// same delay exceeds the steady-state QueryTimeout.
+func TestEngineBaselineTimeout(t *testing.T) {
+	run := func(baseline time.Duration) (*Engine, *atomic.Bool, func()) {
+		mock := newEngineMock(t)
+		mock.firstCircuitDelay = 300 * time.Millisecond
+		host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+		e := NewEngine(host, port, time.Hour)
+		e.QueryTimeout = 100 * time.Millisecond
+		e.BaselineTimeout = baseline
+		var failed atomic.Bool
+		e.OnScan = func(err error) {
+			if err != nil {
+				failed.Store(true)
+			}
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() { _ = e.Run(ctx) }()
+		return e, &failed, func() { cancel(); mock.close() }
+	}
+
+	e, failed, stop := run(2 * time.Second)
+	defer stop()
+	waitFor(t, func() bool { return e.Snapshot().Circuits["C0001"].Name == "Pool Light" })
+	if failed.Load() {
+		t.Error("slow baseline should succeed within the extended BaselineTimeout")
+	}
+
+	_, failed, stop = run(100 * time.Millisecond)
+	defer stop()
+	waitFor(t, failed.Load) // control: the same delay fails at the steady-state timeout
+}
+
+// TestEngineQueryPacing verifies QueryPacing spaces a scan's sub-queries: the
+// baseline's five queries (four groups + air sensor) take at least four gaps.
+func TestEngineQueryPacing(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	const pacing = 100 * time.Millisecond
+	e := NewEngine(host, port, time.Hour)
+	e.QueryPacing = pacing
+	live := make(chan time.Time, 1)
+	e.OnScan = func(err error) {
+		if err == nil {
+			select {
+			case live <- time.Now():
+			default:
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now()
+	go func() { _ = e.Run(ctx) }()
+
+	select {
+	case at := <-live:
+		if elapsed := at.Sub(start); elapsed < 4*pacing {
+			t.Errorf("baseline took %v, want >= %v with pacing", elapsed, 4*pacing)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for baseline")
+	}
+}
+
+// TestEngineQueryPacingShutdown verifies a canceled context cuts pacing short,
+// so shutdown isn't held up by QueryPacing × sub-queries.
+func TestEngineQueryPacingShutdown(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	e := NewEngine(host, port, time.Hour)
+	e.QueryPacing = time.Hour
+	req := New(host, port)
+	if err := req.Connect(context.Background()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer req.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan error, 1)
+	go func() { done <- e.scan(ctx, req, true, nil) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("scan: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("scan still pacing after cancel")
+	}
+}
+
 // TestEngineResolveDrivesDial verifies the engine dials the host returned by the
 // Resolve hook (not the placeholder passed to NewEngine), and calls it before
 // connecting.
@@ -250,6 +771,42 @@ func TestEngineResolveErrorRetries(t *testing.T) {
 	waitFor(t, func() bool { return e.Snapshot().Circuits["C0001"].Name == "Pool Light" })
 }
 
+// TestEngineResolveAfter verifies a known host is redialed without Resolve
+// until ResolveAfter consecutive connects or sessions have failed.
+func TestEngineResolveAfter(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	var resolveCalls atomic.Int32
+	e := NewEngine(host, port, time.Hour)
+	e.ResolveAfter = 2
+	e.Resolve = func() (string, error) {
+		resolveCalls.Add(1)
+		return host, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = e.Run(ctx) }()
+
+	waitFor(t, func() bool { return e.Status().Connected })
+	mock.dropConns() // one failure (the session ending): redial the same host
+	waitForTimeout(t, 6*time.Second, func() bool { return mock.connCount() >= 4 && e.Status().Connected })
+	if n := resolveCalls.Load(); n != 0 {
+		t.Fatalf("Resolve called %d times after one failure, want 0", n)
+	}
+
+	// A partial scan (QueryError) neither counts toward the streak nor resets it.
+	e.onScan(errTestResolve)
+	e.onScan(&QueryError{Command: "GetParamList", Reason: "timeout"})
+	if e.resolveDue() {
+		t.Fatal("a QueryError made Resolve due")
+	}
+
+	mock.dropConns() // plus the next session ending: two in a row
+	waitForTimeout(t, 8*time.Second, func() bool { return resolveCalls.Load() > 0 })
+}
+
 var errTestResolve = resolveError("resolve boom")
 
 type resolveError string
@@ -305,6 +862,236 @@ func TestEnginePollFailuresForceReconnect(t *testing.T) {
 	waitForTimeout(t, 6*time.Second, sawScanOKAfterErr.Load)
 }
 
+// TestEngineShutdownIsNotFailure verifies errors caused by canceling Run's ctx,
+// a connect canceled mid-retry or a poll cut off by the closing sockets, are not
+// reported as failed scans.
+func TestEngineShutdownIsNotFailure(t *testing.T) {
+	run := func(t *testing.T, e *Engine, ready func() bool, stop func()) {
+		t.Helper()
+		var scanErrs atomic.Int32
+		e.OnScan = func(err error) {
+			if err != nil {
+				scanErrs.Add(1)
+				t.Logf("OnScan(%v)", err)
+			}
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = e.Run(ctx)
+		}()
+		waitFor(t, ready)
+		cancel()
+		stop()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run did not return after cancel")
+		}
+		if n := scanErrs.Load(); n != 0 {
+			t.Errorf("shutdown reported %d failed scan(s), want 0", n)
+		}
+	}
+
+	t.Run("connect", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		host, port, _ := net.SplitHostPort(ln.Addr().String())
+		ln.Close() // refused: ConnectWithRetry sits in its 1s backoff
+
+		e := NewEngine(host, port, time.Hour)
+		start := time.Now()
+		run(t, e, func() bool { return time.Since(start) > 100*time.Millisecond }, func() {})
+	})
+
+	t.Run("poll", func(t *testing.T) {
+		mock := newEngineMock(t)
+		defer mock.close()
+		host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+		e := NewEngine(host, port, 10*time.Millisecond)
+		run(t, e, func() bool { return e.Snapshot().Circuits["C0001"].Name == "Pool Light" }, mock.dropConns)
+	})
+}
+
+// TestEngineStatus verifies Status tracks the target, the session and the scan
+// results: a failed poll counts and is remembered, and a later success resets
+// the count but keeps the last error.
+func TestEngineStatus(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	mock.failCircuitLo.Store(3) // baseline and first poll succeed, the second fails
+	mock.failCircuitHi.Store(3)
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	e := NewEngine(host, port, 10*time.Millisecond)
+	if st := e.Status(); st.Connected || st.Host != host || st.Port != port {
+		t.Errorf("status before Run = %+v, want disconnected at %s:%s", st, host, port)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = e.Run(ctx) }()
+
+	waitFor(t, func() bool { return e.Status().LastError != "" })
+	waitFor(t, func() bool { return e.Status().ConsecutiveFailures == 0 })
+	st := e.Status()
+	if !st.Connected || st.LastSuccessAt.IsZero() || st.LastErrorAt.IsZero() {
+		t.Errorf("status after recovery = %+v, want connected with success and error times", st)
+	}
+	if !strings.Contains(st.LastError, "GetParamList") {
+		t.Errorf("last error = %q, want the failed GetParamList", st.LastError)
+	}
+}
+
+// TestEnginePartialScan verifies a rejected sub-query doesn't abort the scan:
+// the types after it are still read, and the scan reports a QueryError rather
+// than a transport failure.
+func TestEnginePartialScan(t *testing.T) {
+	mock := newEngineMock(t)
+	defer mock.close()
+	mock.failCircuitLo.Store(2) // the first poll's circuit query is rejected
+	mock.failCircuitHi.Store(2)
+	host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+	e := NewEngine(host, port, 10*time.Millisecond)
+	type result struct {
+		err   error
+		pumps int32
+	}
+	failed := make(chan result, 1)
+	e.OnScan = func(err error) {
+		if err == nil {
+			return
+		}
+		select {
+		case failed <- result{err, mock.pumpCalls.Load()}:
+		default:
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = e.Run(ctx) }()
+
+	var r result
+	select {
+	case r = <-failed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no failed scan reported")
+	}
+	if !IsQueryError(r.err) {
+		t.Errorf("scan error = %v (%T), want a QueryError", r.err, r.err)
+	}
+	if r.pumps < 2 {
+		t.Errorf("pump queries when the scan failed = %d, want 2 (baseline + the failing poll)", r.pumps)
+	}
+}
+
+// TestEngineShutdownGrace verifies a poll in flight at shutdown finishes and
+// reports its result within ShutdownGrace, and that a zero grace cuts it short
+// without reporting a failure.
+func TestEngineShutdownGrace(t *testing.T) {
+	run := func(t *testing.T, grace, stall time.Duration) (completed, failed int32, took time.Duration) {
+		t.Helper()
+		mock := newEngineMock(t)
+		defer mock.close()
+		host, port, _ := strings.Cut(strings.TrimPrefix(mock.srv.URL, "http://"), ":")
+
+		e := NewEngine(host, port, 10*time.Millisecond)
+		e.ShutdownGrace = grace
+		ctx, cancel := context.WithCancel(context.Background())
+		var afterCancel, scanErrs atomic.Int32
+		e.OnScan = func(err error) {
+			switch {
+			case err != nil:
+				scanErrs.Add(1)
+			case ctx.Err() != nil:
+				afterCancel.Add(1)
+			}
+		}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = e.Run(ctx)
+		}()
+		waitFor(t, func() bool { return e.Snapshot().Circuits["C0001"].Name == "Pool Light" })
+		mock.circuitDelay.Store(int64(stall))
+		calls := mock.circuitCalls.Load()
+		waitFor(t, func() bool { return mock.circuitCalls.Load() > calls+1 }) // a stalled poll is in flight
+
+		start := time.Now()
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run did not return after cancel")
+		}
+		return afterCancel.Load(), scanErrs.Load(), time.Since(start)
+	}
+
+	t.Run("poll completes", func(t *testing.T) {
+		completed, failed, _ := run(t, 2*time.Second, 100*time.Millisecond)
+		if completed != 1 {
+			t.Errorf("%d successful scan(s) reported after cancel, want the in-flight poll's 1", completed)
+		}
+		if failed != 0 {
+			t.Errorf("%d failed scan(s) reported, want 0", failed)
+		}
+	})
+
+	t.Run("zero grace", func(t *testing.T) {
+		completed, failed, took := run(t, 0, time.Second)
+		if took >= 500*time.Millisecond {
+			t.Errorf("Run took %v to return with no grace, want the stalled poll cut short", took)
+		}
+		if completed != 0 || failed != 0 {
+			t.Errorf("scans reported after cancel: %d ok, %d failed; want none", completed, failed)
+		}
+	})
+}
+
+// TestEngineDetectsReboot verifies a reconnect after RebootDowntime or more
+// without a live session is reported via OnReboot, and the first connection
+// (nothing lost yet) or a brief drop is not. OnReconnect follows the same
+// first/later split. lostAt is set directly, so no backoff or clock is waited on.
+func TestEngineDetectsReboot(t *testing.T) {
+	e := NewEngine("127.0.0.1", "6680", time.Hour)
+	e.RebootDowntime = time.Minute
+	var reboots int
+	var downtime time.Duration
+	e.OnReboot = func(down time.Duration) {
+		reboots++
+		downtime = down
+	}
+	var reconnects int
+	e.OnReconnect = func() { reconnects++ }
+
+	e.noteConnect()
+	e.noteReconnect()
+	if reboots != 0 || reconnects != 0 {
+		t.Fatalf("first connection counted as %d reboot(s), %d reconnect(s)", reboots, reconnects)
+	}
+
+	e.lostAt = time.Now().Add(-e.RebootDowntime / 2) // a brief drop
+	e.noteConnect()
+	e.noteReconnect()
+	if reboots != 0 || reconnects != 1 {
+		t.Errorf("brief drop: reboots = %d, reconnects = %d; want 0, 1", reboots, reconnects)
+	}
+
+	e.lostAt = time.Now().Add(-2 * e.RebootDowntime) // down long enough to be a reboot
+	e.noteConnect()
+	e.noteReconnect()
+	if reboots != 1 || reconnects != 2 {
+		t.Errorf("long drop: reboots = %d, reconnects = %d; want 1, 2", reboots, reconnects)
+	}
+	if downtime < 2*e.RebootDowntime {
+		t.Errorf("reported downtime %v, want >= %v", downtime, 2*e.RebootDowntime)
+	}
+}
+
 // --- test helpers ---------------------------------------------------------
 
 func recvChange(t *testing.T, ch <-chan Change) Change {
@@ -369,12 +1156,16 @@ func waitForTimeout(t *testing.T, timeout time.Duration, cond func() bool) {
 // engineMock is a write-safe mock IntelliCenter supporting multiple connections
 // (the engine opens two) and unsolicited broadcast pushes.
 type engineMock struct {
-	srv         *httptest.Server
-	mu          sync.Mutex
-	conns       []*safeConn
-	lastReq     Request
-	cfgQueries  atomic.Int32 // GetConfiguration (feature visibility) calls
-	pmpcQueries atomic.Int32 // PMPCIRC (circuit⇄pump graph) calls
+	srv          *httptest.Server
+	mu           sync.Mutex
+	conns        []*safeConn
+	lastReq      Request
+	cfgQueries   atomic.Int32 // GetConfiguration (feature visibility) calls
+	pmpcQueries  atomic.Int32 // PMPCIRC (circuit⇄pump graph) calls
+	schedQueries atomic.Int32 // SCHED (schedule) calls
+	batchQueries atomic.Int32 // unconditioned INCR (BatchQueries) calls
+	pumpCalls    atomic.Int32 // condPump GetParamList calls
+	solarCalls   atomic.Int32 // solar sensor reads by objnam (delta scans)
 
 	// circuitCalls counts condCircuit GetParamList calls (1-indexed); calls
 	// numbered within [failCircuitLo, failCircuitHi] (inclusive) get an error
@@ -382,6 +1173,23 @@ type engineMock struct {
 	// answering. Zero values disable failure injection.
 	circuitCalls                 atomic.Int32
 	failCircuitLo, failCircuitHi atomic.Int32
+
+	circuitReqKeys [][]string  // keys requested by each condCircuit call, in order
+	extraCircuit   atomic.Bool // also answer condCircuit with C0002
+	alertCleared   atomic.Bool // answer condAlert with no alerts
+	noBodies       atomic.Bool // answer condBody with an empty object list
+
+	firstCircuitDelay time.Duration // stall the first condCircuit answer (a cold panel); set before use
+	circuitDelay      atomic.Int64  // stall later condCircuit answers by this many nanoseconds
+}
+
+func (m *engineMock) circuitKeysAt(i int) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if i >= len(m.circuitReqKeys) {
+		return nil
+	}
+	return m.circuitReqKeys[i]
 }
 
 type safeConn struct {
@@ -425,8 +1233,22 @@ func (m *engineMock) handle(sc *safeConn, req Request) {
 		if req.Condition == condPMPCirc {
 			m.pmpcQueries.Add(1)
 		}
+		if req.Condition == condSched {
+			m.schedQueries.Add(1)
+		}
+		if req.Condition == condPump {
+			m.pumpCalls.Add(1)
+		}
 		if req.Condition == condCircuit {
+			m.mu.Lock()
+			m.circuitReqKeys = append(m.circuitReqKeys, req.ObjectList[0].Keys)
+			m.mu.Unlock()
 			n := m.circuitCalls.Add(1)
+			if n == 1 {
+				time.Sleep(m.firstCircuitDelay)
+			} else {
+				time.Sleep(time.Duration(m.circuitDelay.Load()))
+			}
 			if lo, hi := m.failCircuitLo.Load(), m.failCircuitHi.Load(); lo > 0 && n >= lo && n <= hi {
 				sc.writeJSON(Response{Command: req.Command, MessageID: req.MessageID, Response: "400"})
 				return
@@ -447,6 +1269,12 @@ func (m *engineMock) handle(sc *safeConn, req Request) {
 			"answer": []any{
 				map[string]any{"objnam": "FTR01", "params": map[string]any{"SHOMNU": "hide w"}},
 				map[string]any{"objnam": "FTR02", "params": map[string]any{"SHOMNU": "hide"}},
+				map[string]any{"objnam": "B1101", "params": map[string]any{
+					"OBJTYP": "BODY", "SUBTYP": "POOL", "SNAME": "Pool",
+					"OBJLIST": []any{
+						map[string]any{"objnam": "V0101", "params": map[string]any{"OBJTYP": "VALVE", "SUBTYP": "LEGACY", "SNAME": "Intake"}},
+					},
+				}},
 			},
 		})
 	default:
@@ -457,25 +1285,82 @@ func (m *engineMock) handle(sc *safeConn, req Request) {
 func (m *engineMock) objectsFor(req Request) []ObjectData {
 	switch req.Condition {
 	case condCircuit:
-		return []ObjectData{{ObjName: "C0001", Params: map[string]string{
+		objs := []ObjectData{{ObjName: "C0001", Params: map[string]string{
 			"SNAME": "Pool Light", "STATUS": "ON", "OBJTYP": "CIRCUIT", "SUBTYP": "LIGHT", "FREEZE": "OFF",
 		}}}
+		if m.extraCircuit.Load() {
+			objs = append(objs, ObjectData{ObjName: "C0002", Params: map[string]string{
+				"SNAME": "Cleaner", "STATUS": "ON", "OBJTYP": "CIRCUIT", "SUBTYP": "GENERIC", "FREEZE": "OFF",
+			}})
+		}
+		return objs
 	case condBody:
+		if m.noBodies.Load() {
+			return nil
+		}
 		return []ObjectData{{ObjName: "B1101", Params: map[string]string{
 			"SNAME": "Pool", "STATUS": "ON", "TEMP": "82", "SUBTYP": "POOL", "HTMODE": "1", "HTSRC": "H0001", "LOTMP": "85", "HITMP": "104",
 		}}}
 	case condPMPCirc:
 		return []ObjectData{{ObjName: "p0101", Params: map[string]string{"CIRCUIT": "C0001", "PARENT": "PMP01"}}}
+	case condCircGrp:
+		return []ObjectData{{ObjName: "c0101", Params: map[string]string{"CIRCUIT": "C0001", "PARENT": "GRP01"}}}
+	case condSched:
+		return []ObjectData{{ObjName: "SCH01", Params: map[string]string{
+			"SNAME": "Pool Light", "CIRCUIT": "C0001", "STATUS": "ON", "DAY": "MTWRFAU", "START": "ABSTIM", "TIME": "19,30,00",
+		}}}
+	case condChem:
+		return []ObjectData{{ObjName: "CHR01", Params: map[string]string{
+			"SNAME": "IntelliChem", "SUBTYP": "ICHEM", "PHVAL": "7.4", "ORPVAL": "680", "PHTNK": "4", "ORPTNK": "5",
+		}}}
+	case condSense:
+		return []ObjectData{
+			{ObjName: airSensorObjnam, Params: map[string]string{"SNAME": "Air", "PROBE": "75", "SUBTYP": "AIR"}},
+			{ObjName: "SSS11", Params: map[string]string{"SNAME": "Solar Sensor", "PROBE": "104", "SUBTYP": "SOLAR"}},
+		}
+	case condAlert:
+		if m.alertCleared.Load() {
+			return nil
+		}
+		return []ObjectData{{ObjName: "ALT01", Params: map[string]string{
+			"SNAME": "Pump Communication Error", "SUBTYP": "PMPCOM", "STATUS": "ON",
+		}}}
+	}
+	if req.Condition == "" && len(req.ObjectList) == 1 && req.ObjectList[0].ObjName == "INCR" {
+		m.batchQueries.Add(1)
+		return m.allObjects()
 	}
-	// Air sensor is queried by objnam with no condition.
+	// Air sensor and system object are queried by objnam with no condition.
 	if len(req.ObjectList) == 1 && req.ObjectList[0].ObjName == airSensorObjnam {
 		return []ObjectData{{ObjName: airSensorObjnam, Params: map[string]string{
 			"SNAME": "Air", "PROBE": "75", "SUBTYP": "AIR",
 		}}}
 	}
+	if len(req.ObjectList) == 1 && req.ObjectList[0].ObjName == "SSS11" {
+		m.solarCalls.Add(1)
+		return []ObjectData{{ObjName: "SSS11", Params: map[string]string{"PROBE": "110", "SUBTYP": "SOLAR"}}}
+	}
+	if len(req.ObjectList) == 1 && req.ObjectList[0].ObjName == systemObjnam {
+		return []ObjectData{{ObjName: systemObjnam, Params: map[string]string{"VACFLO": "ON"}}}
+	}
 	return nil // pumps, heaters: none in this fixture
 }
 
+// allObjects answers an unconditioned INCR query: every type's objects tagged
+// with their OBJTYP, plus a valve no scan group uses.
+func (m *engineMock) allObjects() []ObjectData {
+	objs := []ObjectData{{ObjName: "V0101", Params: map[string]string{
+		"SNAME": "Intake", "OBJTYP": "VALVE", "STATUS": "ON",
+	}}}
+	for _, g := range scanGroups {
+		for _, o := range m.objectsFor(Request{Condition: g.cond}) {
+			o.Params[keyObjTyp] = strings.TrimPrefix(g.cond, "OBJTYP=")
+			objs = append(objs, o)
+		}
+	}
+	return objs
+}
+
 func (m *engineMock) broadcast(push any) {
 	m.mu.Lock()
 	conns := append([]*safeConn(nil), m.conns...)
@@ -493,8 +1378,45 @@ func (m *engineMock) lastSet() Request {
 
 func (m *engineMock) close() { m.srv.Close() }
 
+// dropConns closes every open connection server-side, as a rebooting panel would.
+func (m *engineMock) dropConns() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sc := range m.conns {
+		_ = sc.c.Close()
+	}
+}
+
 func (m *engineMock) connCount() int {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	return len(m.conns)
 }
+
+// TestEngineHealthLoop checks the push health loop ends the session only once
+// PingFailures consecutive pings have failed, and stops cleanly on cancel.
+func TestEngineHealthLoop(t *testing.T) {
+	f := newFakeIC(t)
+	defer f.close()
+	e := NewEngine("", "", time.Hour)
+
+	live := dial(t, f)
+	defer live.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	liveErr := make(chan error, 1)
+	go func() { liveErr <- e.healthLoop(ctx, live, time.Millisecond) }()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-liveErr; err != nil {
+		t.Errorf("healthy connection: healthLoop = %v, want nil", err)
+	}
+
+	dead := dial(t, f)
+	defer dead.Close()
+	dead.PingFailureThreshold = 2
+	_ = dead.conn.UnderlyingConn().Close()
+	dead.lastHealthCheck = time.Time{} // ping on the first tick
+	if err := e.healthLoop(context.Background(), dead, time.Millisecond); err == nil {
+		t.Error("broken connection: healthLoop returned nil, want an error")
+	}
+}
@@ -1,5 +1,10 @@
 package intellicenter
 
+import (
+	"strconv"
+	"strings"
+)
+
 // Key sets requested per object type, shared by the Client query methods and the
 // Engine's baseline/poll so the wire requests stay identical.
 var (
@@ -9,8 +14,55 @@ var (
 	heaterKeys  = []string{keySName, keyStatus, keySubTyp, keyObjTyp, keyBody, keyCool}
 	sensorKeys  = []string{keySName, keyProbe, keySubTyp, keyStatus}
 	pmpCircKeys = []string{keyCircuit, keyParent}
+	circGrpKeys = []string{keyCircuit, keyParent}
+	systemKeys  = []string{keyVacFlo, keyMode}
+	schedKeys   = []string{keySName, keyCircuit, keyStatus, keyDay, keyStart, keyTime, keyStop, keyTimeout, keyLoTmp}
+	alertKeys   = []string{keySName, keySubTyp, keyStatus}
+	chemKeys    = []string{keySName, keySubTyp, keyPHVal, keyORPVal, keyPHTank, keyORPTank, keySalt, keyPrim}
+)
+
+// Narrower key sets for delta polls: only the values that change at runtime.
+// Names, types and ratings are static configuration, so they come from the full
+// scans (baseline and every configRefreshPolls) and persist in the merged
+// params between them.
+var (
+	circuitPollKeys = []string{keyStatus, keyFreeze}
+	bodyPollKeys    = []string{keyStatus, keyTemp, keyHTMode, keyHTSrc, keyLoTmp, keyHiTmp}
+	pumpPollKeys    = []string{keyStatus, keyRPM, keyPwr, keyWatts, keyGPM}
+	heaterPollKeys  = []string{keyStatus, keyCool}
+	chemPollKeys    = []string{keyPHVal, keyORPVal, keyPHTank, keyORPTank, keySalt, keyPrim}
 )
 
+// Candidate keys per logical value, in preference order. Firmwares differ in
+// which key carries a value; firstPositive reads the first one that does.
+var wattsKeys = []string{keyPwr, keyWatts}
+
+// firstPositive returns the first of keys whose value parses to a positive
+// number, or 0 if none does. present reports whether any of keys holds a
+// number at all, so a partial push without the value, or an unparseable echo,
+// can be told apart from a zero reading.
+func firstPositive(params map[string]string, keys []string) (v float64, present bool) {
+	for _, key := range keys {
+		f, err := strconv.ParseFloat(params[key], 64)
+		if err != nil {
+			continue
+		}
+		if f > 0 {
+			return f, true
+		}
+		present = true
+	}
+	return 0, present
+}
+
+// PumpWatts returns a pump's power draw from its params: PWR, falling back to
+// WATTS for firmwares that populate it instead. It reads 0 when neither holds a
+// reading; present is false when neither key holds a number (missing, or an
+// unparseable value), so callers skip the metric rather than report 0.
+func PumpWatts(params map[string]string) (watts float64, present bool) {
+	return firstPositive(params, wattsKeys)
+}
+
 // Per-object parsers: build a typed domain value from a (possibly merged) param
 // map. Used both by one-shot queries and by incremental push merges.
 
@@ -41,11 +93,7 @@ func bodyFrom(objnam string, params map[string]string) Body {
 
 func pumpFrom(objnam string, params map[string]string) Pump {
 	rpm := parseFloat(params[keyRPM])
-	// Power lives under PWR; fall back to WATTS for firmwares that populate it.
-	watts := parseFloat(params[keyPwr])
-	if watts == 0 {
-		watts = parseFloat(params[keyWatts])
-	}
+	watts, _ := PumpWatts(params)
 	return Pump{
 		ID:      objnam,
 		Name:    params[keySName],
@@ -83,3 +131,47 @@ func sensorFrom(objnam string, params map[string]string) Sensor {
 		Valid:   probe != "",
 	}
 }
+
+// parseConfiguration walks a GetConfiguration answer, recursing into each
+// entry's OBJLIST children. It returns every feature's SHOMNU visibility flag
+// and the identity (type, subtype, name, parent) of every object it contains.
+func parseConfiguration(answer []any) (map[string]string, map[string]ConfigObject) {
+	shomnu := map[string]string{}
+	objects := map[string]ConfigObject{}
+	var walk func(items []any, parent string)
+	walk = func(items []any, parent string) {
+		for _, item := range items {
+			obj, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			objnam, ok := obj["objnam"].(string)
+			if !ok || objnam == "" {
+				continue
+			}
+			params, ok := obj["params"].(map[string]any)
+			if !ok {
+				continue
+			}
+			str := func(key string) string {
+				v, _ := params[key].(string)
+				return v
+			}
+			objects[objnam] = ConfigObject{
+				ID:      objnam,
+				ObjType: str(keyObjTyp),
+				SubType: str(keySubTyp),
+				Name:    str(keySName),
+				Parent:  parent,
+			}
+			if v, ok := params[keyShomnu].(string); ok && strings.HasPrefix(objnam, ftrPrefix) {
+				shomnu[objnam] = v
+			}
+			if children, ok := params[keyObjList].([]any); ok {
+				walk(children, objnam)
+			}
+		}
+	}
+	walk(answer, "")
+	return shomnu, objects
+}
@@ -0,0 +1,206 @@
+package intellicenter
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PushFrame is an unsolicited IntelliCenter push (WriteParamList/NotifyList).
+// Objects arrive in two shapes: objectList[].{objnam,params} and
+// objectList[].changes[].{objnam,params}; Objects flattens both.
+type PushFrame struct {
+	Command    string       `json:"command"`
+	MessageID  string       `json:"messageID,omitempty"`
+	Response   string       `json:"response,omitempty"`
+	ObjectList []PushObject `json:"objectList,omitempty"`
+}
+
+// PushObject is one objectList entry: an object's params directly, a nested
+// changes list, or both.
+type PushObject struct {
+	ObjName string       `json:"objnam,omitempty"`
+	Params  PushParams   `json:"params,omitempty"`
+	Changes []PushChange `json:"changes,omitempty"`
+}
+
+// PushChange is one entry of a PushObject's changes list.
+type PushChange struct {
+	ObjName string     `json:"objnam"`
+	Params  PushParams `json:"params,omitempty"`
+}
+
+// PushParams holds param values undecoded. Values are strings on the wire, but
+// keeping them raw means an unexpected value type cannot fail the whole frame.
+type PushParams map[string]json.RawMessage
+
+// ParsePushFrame decodes a push. An error means the frame does not fit the
+// known shapes; callers fall back to generic map handling.
+func ParsePushFrame(data []byte) (PushFrame, error) {
+	var f PushFrame
+	if err := json.Unmarshal(data, &f); err != nil {
+		return PushFrame{}, fmt.Errorf("parse push frame: %w", err)
+	}
+	return f, nil
+}
+
+// PushFrameFromMap decodes a push already read as a generic map (as Client
+// delivers it), with the same contract as ParsePushFrame. It walks the map
+// directly instead of re-encoding the whole frame; only param values are
+// encoded, to keep them raw.
+func PushFrameFromMap(msg map[string]any) (PushFrame, error) {
+	var f PushFrame
+	var err error
+	if f.Command, err = mapString(msg, "command"); err != nil {
+		return PushFrame{}, err
+	}
+	if f.MessageID, err = mapString(msg, "messageID"); err != nil {
+		return PushFrame{}, err
+	}
+	if f.Response, err = mapString(msg, "response"); err != nil {
+		return PushFrame{}, err
+	}
+	list, err := mapList(msg, "objectList")
+	if err != nil {
+		return PushFrame{}, err
+	}
+	for _, item := range list {
+		o, err := pushObjectFromMap(item)
+		if err != nil {
+			return PushFrame{}, err
+		}
+		f.ObjectList = append(f.ObjectList, o)
+	}
+	return f, nil
+}
+
+// pushObjectFromMap decodes one objectList entry; null decodes as empty, as
+// json.Unmarshal would.
+func pushObjectFromMap(item any) (PushObject, error) {
+	if item == nil {
+		return PushObject{}, nil
+	}
+	m, ok := item.(map[string]any)
+	if !ok {
+		return PushObject{}, fmt.Errorf("parse push frame: objectList entry is %T, not an object", item)
+	}
+	var o PushObject
+	var err error
+	if o.ObjName, err = mapString(m, "objnam"); err != nil {
+		return PushObject{}, err
+	}
+	if o.Params, err = mapParams(m); err != nil {
+		return PushObject{}, err
+	}
+	changes, err := mapList(m, "changes")
+	if err != nil {
+		return PushObject{}, err
+	}
+	for _, item := range changes {
+		var c PushChange
+		if item != nil {
+			cm, ok := item.(map[string]any)
+			if !ok {
+				return PushObject{}, fmt.Errorf("parse push frame: changes entry is %T, not an object", item)
+			}
+			if c.ObjName, err = mapString(cm, "objnam"); err != nil {
+				return PushObject{}, err
+			}
+			if c.Params, err = mapParams(cm); err != nil {
+				return PushObject{}, err
+			}
+		}
+		o.Changes = append(o.Changes, c)
+	}
+	return o, nil
+}
+
+// mapString returns m[key] as a string: "" when absent or null, an error for
+// any other type.
+func mapString(m map[string]any, key string) (string, error) {
+	switch v := m[key].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("parse push frame: %s is %T, not a string", key, v)
+	}
+}
+
+// mapList returns m[key] as a list: nil when absent or null, an error for any
+// other type.
+func mapList(m map[string]any, key string) ([]any, error) {
+	switch v := m[key].(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("parse push frame: %s is %T, not an array", key, v)
+	}
+}
+
+// mapParams returns m["params"] with each value kept as its JSON encoding.
+func mapParams(m map[string]any) (PushParams, error) {
+	switch v := m["params"].(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		params := make(PushParams, len(v))
+		for k, val := range v {
+			raw, err := json.Marshal(val)
+			if err != nil {
+				return nil, fmt.Errorf("parse push frame: param %s: %w", k, err)
+			}
+			params[k] = raw
+		}
+		return params, nil
+	default:
+		return nil, fmt.Errorf("parse push frame: params is %T, not an object", v)
+	}
+}
+
+// Objects returns every {objnam, params} pair in the frame, direct and nested,
+// in wire order. Entries without an objnam or params are skipped.
+func (f PushFrame) Objects() []PushChange {
+	var out []PushChange
+	for _, o := range f.ObjectList {
+		if o.ObjName != "" && o.Params != nil {
+			out = append(out, PushChange{ObjName: o.ObjName, Params: o.Params})
+		}
+		for _, c := range o.Changes {
+			if c.ObjName != "" && c.Params != nil {
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
+// Strings returns the string-valued params; values of any other type are
+// dropped, as no IntelliCenter param is legitimately non-string.
+func (p PushParams) Strings() map[string]string {
+	out := make(map[string]string, len(p))
+	for k, raw := range p {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			out[k] = s
+		}
+	}
+	return out
+}
+
+// Text returns every param as text: strings unquoted, anything else as its JSON
+// encoding (2400, true). For display paths that must not hide odd values.
+func (p PushParams) Text() map[string]string {
+	out := make(map[string]string, len(p))
+	for k, raw := range p {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			out[k] = s
+		} else {
+			out[k] = string(raw)
+		}
+	}
+	return out
+}
@@ -0,0 +1,160 @@
+package intellicenter //nolint:testpackage // white-box: shares the package's wire constants
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// benchPushFrame is a typical body push, as sent when the water temperature changes.
+const benchPushFrame = `{"command":"WriteParamList","messageID":"a1b2","response":"200","objectList":[` +
+	`{"changes":[{"objnam":"B1101","params":{"SNAME":"Pool","TEMP":"82","STATUS":"ON","HTMODE":"1","LOTMP":"85","HITMP":"104"}}]}]}`
+
+// BenchmarkParsePushFrame measures decoding a push into the typed model.
+func BenchmarkParsePushFrame(b *testing.B) {
+	data := []byte(benchPushFrame)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ParsePushFrame(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPushFrameFromMap measures decoding a push the client already read
+// as a generic map, the engine's path for every push.
+func BenchmarkPushFrameFromMap(b *testing.B) {
+	var msg map[string]any
+	if err := json.Unmarshal([]byte(benchPushFrame), &msg); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := PushFrameFromMap(msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkExtractPushObjects measures the generic map decode and walk the
+// typed model replaces, for comparison with BenchmarkParsePushFrame.
+func BenchmarkExtractPushObjects(b *testing.B) {
+	data := []byte(benchPushFrame)
+	b.ReportAllocs()
+	for b.Loop() {
+		var msg map[string]any
+		if err := json.Unmarshal(data, &msg); err != nil {
+			b.Fatal(err)
+		}
+		if len(extractPushObjects(msg)) != 1 {
+			b.Fatal("want one push object")
+		}
+	}
+}
+
+func TestPushFrameRoundTrip(t *testing.T) {
+	frames := map[string]string{
+		"nested changes": `{"command":"WriteParamList","messageID":"a1b2","response":"200","objectList":[` +
+			`{"changes":[{"objnam":"B1202","params":{"SNAME":"Spa","TEMP":"80","STATUS":"ON"}}]}]}`,
+		"direct params": `{"command":"NotifyList","objectList":[` +
+			`{"objnam":"C0001","params":{"STATUS":"OFF"}},{"objnam":"PMP01","params":{"RPM":"2400"}}]}`,
+		"mixed": `{"command":"NotifyList","objectList":[` +
+			`{"objnam":"C0001","params":{"STATUS":"ON"},"changes":[{"objnam":"C0002","params":{"STATUS":"OFF"}}]}]}`,
+	}
+	for name, data := range frames {
+		f, err := ParsePushFrame([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: parse: %v", name, err)
+		}
+		out, err := json.Marshal(f)
+		if err != nil {
+			t.Fatalf("%s: marshal: %v", name, err)
+		}
+		again, err := ParsePushFrame(out)
+		if err != nil {
+			t.Fatalf("%s: re-parse: %v", name, err)
+		}
+		if !reflect.DeepEqual(f, again) {
+			t.Errorf("%s: round trip changed the frame:\n got %+v\nwant %+v", name, again, f)
+		}
+	}
+}
+
+// TestPushFrameFromMapMatchesParse checks decoding the generic map gives the
+// frame (or rejection) decoding the bytes does.
+func TestPushFrameFromMapMatchesParse(t *testing.T) {
+	frames := map[string]string{
+		"typical": benchPushFrame,
+		"mixed": `{"command":"NotifyList","objectList":[null,` +
+			`{"objnam":"C0001","params":{"STATUS":"ON","RPM":2400,"X":null},"changes":[null,{"objnam":"C0002","params":{"STATUS":"OFF"}}]}]}`,
+		"no params":            `{"command":"NotifyList","objectList":[{"objnam":"C0001","params":null}]}`,
+		"bad objectList":       `{"command":"NotifyList","objectList":"nope"}`,
+		"bad command":          `{"command":7}`,
+		"bad entry":            `{"command":"NotifyList","objectList":["C0001"]}`,
+		"bad params":           `{"command":"NotifyList","objectList":[{"objnam":"C0001","params":["ON"]}]}`,
+		"bad change":           `{"command":"NotifyList","objectList":[{"changes":[{"objnam":1}]}]}`,
+		"bad changes":          `{"command":"NotifyList","objectList":[{"changes":{}}]}`,
+		"unrelated field only": `{"foo":[1,2]}`,
+	}
+	for name, data := range frames {
+		want, wantErr := ParsePushFrame([]byte(data))
+		var msg map[string]any
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			t.Fatalf("%s: decode map: %v", name, err)
+		}
+		got, err := PushFrameFromMap(msg)
+		if (err != nil) != (wantErr != nil) {
+			t.Errorf("%s: PushFrameFromMap error = %v, ParsePushFrame error = %v", name, err, wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: PushFrameFromMap = %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestPushFrameObjects(t *testing.T) {
+	f, err := PushFrameFromMap(map[string]any{
+		"command": "NotifyList",
+		"objectList": []any{
+			map[string]any{"objnam": "C0001", "params": map[string]any{"STATUS": "ON"}},
+			map[string]any{"changes": []any{
+				map[string]any{"objnam": "B1101", "params": map[string]any{"TEMP": "82", "RPM": 2400}},
+				map[string]any{"objnam": "B1102"}, // no params: skipped
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("PushFrameFromMap: %v", err)
+	}
+	objs := f.Objects()
+	if len(objs) != 2 || objs[0].ObjName != "C0001" || objs[1].ObjName != "B1101" {
+		t.Fatalf("Objects() = %+v, want C0001 then B1101", objs)
+	}
+	if got := objs[1].Params.Strings(); !reflect.DeepEqual(got, map[string]string{"TEMP": "82"}) {
+		t.Errorf("Strings() = %v, want only the string-valued TEMP", got)
+	}
+	if got := objs[1].Params.Text(); !reflect.DeepEqual(got, map[string]string{"TEMP": "82", "RPM": "2400"}) {
+		t.Errorf("Text() = %v, want every param as text", got)
+	}
+}
+
+func TestPushFrameUnknownShape(t *testing.T) {
+	if _, err := ParsePushFrame([]byte(`{"command":"NotifyList","objectList":"nope"}`)); err == nil {
+		t.Error("a non-array objectList should be rejected for the map fallback")
+	}
+
+	// The engine still salvages well-formed objects from a rejected frame.
+	e := NewEngine("h", "6680", 0)
+	e.applyAndEmit(KindCircuit, "C0001", map[string]string{keySName: "Pool Light", keyStatus: "OFF"})
+	e.handlePush(map[string]any{
+		"command": "NotifyList",
+		"objectList": []any{
+			"junk",
+			map[string]any{"objnam": "C0001", "params": map[string]any{"STATUS": "ON"}},
+		},
+	})
+	if !e.Snapshot().Circuits["C0001"].On {
+		t.Error("fallback path should still apply C0001 STATUS=ON")
+	}
+}
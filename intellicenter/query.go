@@ -99,10 +99,6 @@ func (c *Client) Sensor(objnam string) (Sensor, error) {
 	return Sensor{ID: objnam}, nil
 }
 
-// NOTE: feature-visibility filtering via GetConfiguration/SHOMNU is deferred.
-// That request uses queryName/arguments and a different response envelope
-// ("answer", not "objectList"); add it during the feature-visibility increment.
-
 func parseFloat(s string) float64 {
 	f, err := strconv.ParseFloat(s, 64)
 	if err != nil {
@@ -19,11 +19,11 @@ const (
 	handshakeTimeout    = 10 * time.Second
 	pingTimeout         = 5 * time.Second
 	responseReadTimeout = 30 * time.Second
+	// A session's baseline (first full scan + static config) reads with a longer
+	// timeout: a panel that is slow on a cold start would otherwise fail it.
+	baselineReadTimeout = 60 * time.Second
 	healthCheckInterval = 30 * time.Second
 
-	// Skip at most this many unsolicited pushes while awaiting a response.
-	maxUnsolicitedMessages = 10
-
 	// Reconnect backoff.
 	maxRetries       = 5
 	baseDelay        = 1 * time.Second
@@ -33,6 +33,17 @@ const (
 	defaultICPortStr = "6680"
 )
 
+// DefaultPingFailures is the default Client.PingFailureThreshold and
+// Engine.PingFailures: consecutive failed pings before Healthy reports the
+// connection dead, so a momentarily busy panel does not force a reconnect.
+const DefaultPingFailures = 3
+
+// DefaultMaxUnsolicited is the default Client.MaxUnsolicited and
+// Engine.MaxUnsolicited: read at most this many messages while awaiting a
+// response. A busy panel (schedule transitions, light shows) can push dozens
+// of changes during one poll.
+const DefaultMaxUnsolicited = 50
+
 // --- wire types (JSON shapes per API.md) ---------------------------------
 
 // Request is an IntelliCenter command. ObjectList items carry Keys for queries
@@ -128,6 +139,17 @@ type Sensor struct {
 	Valid   bool
 }
 
+// ConfigObject is one object from the GetConfiguration answer: the panel's
+// authoritative identity for an objnam, independent of which GetParamList
+// queries pentameter happens to run.
+type ConfigObject struct {
+	ID      string
+	ObjType string // OBJTYP
+	SubType string // SUBTYP
+	Name    string // SNAME
+	Parent  string // objnam of the enclosing object in the answer's OBJLIST tree ("" at top level)
+}
+
 // Heat/thermal status values (match pentameter's metric encoding).
 const (
 	HeatStatusOff     = 0
@@ -149,9 +171,14 @@ const (
 	cmdSetParamList = "SetParamList"
 	cmdGetQuery     = "GetQuery"
 
+	// Unsolicited push command names (state-change broadcasts).
+	cmdWriteParamList = "WriteParamList"
+	cmdNotifyList     = "NotifyList"
+
 	// GetConfiguration query (feature visibility via SHOMNU).
 	queryConfiguration = "GetConfiguration"
 	keyShomnu          = "SHOMNU"
+	keyObjList         = "OBJLIST" // nested child objects within a GetConfiguration entry
 	ftrPrefix          = "FTR"
 
 	// Raw-request field names (DoRaw map keys / GetQuery envelope).
@@ -191,11 +218,42 @@ const (
 	keyCircuit = "CIRCUIT"
 	keyParent  = "PARENT"
 
+	keyVacFlo = "VACFLO" // system object: vacation mode ("ON"/"OFF")
+	keyMode   = "MODE"   // system object: display units ("ENGLISH"/"METRIC")
+
+	// SCHED schedule keys: DAY lists the enabled weekdays (MTWRFAU), START is
+	// how the start is timed (ABSTIM = clock time, SRIS/SSET = sunrise/sunset),
+	// and TIME is the clock start as "HH,MM,SS". STOP and TIMOUT are the same
+	// pair for the end. A body schedule also carries the heat setpoint (LOTMP)
+	// it applies while running.
+	keyDay     = "DAY"
+	keyStart   = "START"
+	keyTime    = "TIME"
+	keyStop    = "STOP"
+	keyTimeout = "TIMOUT"
+
+	// CHEM keys. IntelliChem (SUBTYP ICHEM) reports the pH and ORP (millivolt)
+	// readings and the acid and chlorine feed tank levels; IntelliChlor salt
+	// cells (SUBTYP ICHLOR) report salt ppm and the primary output percent.
+	keyPHVal   = "PHVAL"
+	keyORPVal  = "ORPVAL"
+	keyPHTank  = "PHTNK"
+	keyORPTank = "ORPTNK"
+	keySalt    = "SALT"
+	keyPrim    = "PRIM"
+
 	condCircuit = "OBJTYP=CIRCUIT"
 	condBody    = "OBJTYP=BODY"
 	condPump    = "OBJTYP=PUMP"
 	condHeater  = "OBJTYP=HEATER"
 	condPMPCirc = "OBJTYP=PMPCIRC"
+	condSched   = "OBJTYP=SCHED"
+	condAlert   = "OBJTYP=ALERT"
+	condCircGrp = "OBJTYP=CIRCGRP"
+	condChem    = "OBJTYP=CHEM"
+	condSense   = "OBJTYP=SENSE"
+
+	subTypSolar = "SOLAR" // SENSE: solar collector probe
 
 	valueOff = "OFF"
 )
@@ -210,4 +268,9 @@ const (
 	KindHeater  Kind = "heater"
 	KindSensor  Kind = "sensor"
 	KindPMPCirc Kind = "pmpcirc" // PMPCIRC speed assignment (circuit⇄pump link); raw-only, no typed snapshot
+	KindCircGrp Kind = "circgrp" // CIRCGRP group member (group⇄circuit link); raw-only, no typed snapshot
+	KindSystem  Kind = "system"  // system object (_5451: vacation mode); raw-only, no typed snapshot
+	KindSched   Kind = "sched"   // SCHED schedule (fetched only with Engine.Schedules); raw-only, no typed snapshot
+	KindAlert   Kind = "alert"   // ALERT panel alert (fetched only with Engine.Alerts); raw-only, no typed snapshot
+	KindChem    Kind = "chem"    // CHEM IntelliChem/IntelliChlor (pH, ORP, salt); raw-only, no typed snapshot
 )
@@ -3,8 +3,13 @@ package main
 import (
 	"context"
 	"log"
+	"net"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/astrostl/pentameter/intellicenter"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // runListenEngine serves listen/troubleshooting mode driven by the
@@ -20,29 +25,108 @@ import (
 //   - OnRawPoll: after each scan, typed equipment is recomputed from the engine
 //     snapshot (emitting POLL change lines) and the listen-only discovery queries
 //     (circuit groups, all objects) run over the engine's request client.
+//
+// The same PoolMonitor also backs the HTTP /metrics server, unless --no-metrics:
+// the poll and push paths already Set the gauges, so they only need serving
+// (see serveListenMetrics).
 func runListenEngine(cfg *appConfig) {
-	pm := NewPoolMonitor(cfg.intelliCenterIP, cfg.intelliCenterPort, true)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	pm := newPoolMonitor(cfg.intelliCenterIP, cfg.intelliCenterPort, true, cfg.tempUnits == tempUnitsCelsius)
+	pm.reconnectGrace = cfg.reconnectGrace
+	pm.quietDetection = cfg.quietDetection
+	pm.declaredBodies = cfg.bodies
 	pm.initializeState()
 
-	engine := intellicenter.NewEngine(cfg.intelliCenterIP, cfg.intelliCenterPort, cfg.pollInterval)
-	engine.Logf = log.Printf
-	engine.Resolve = newDiscoveryResolver(cfg)
+	engine := newEngine(cfg, pm.metrics)
+	if cfg.debugEndpoint {
+		pm.stateEngine = engine
+	}
+
+	wireListenHooks(pm, engine)
+	if !cfg.noMetrics {
+		if _, err := serveListenMetrics(ctx, cfg, pm, engine, createPrometheusRegistry(cfg, pm.metrics)); err != nil {
+			log.Fatalf("HTTP server failed: %v", err)
+		}
+		if adv, err := StartMDNSAdvertiser(cfg.httpPort, false); err != nil {
+			log.Printf("Warning: mDNS advertisement disabled: %v", err)
+		} else {
+			defer func() {
+				if cerr := adv.Close(); cerr != nil {
+					log.Printf("Error closing mDNS advertiser: %v", cerr)
+				}
+			}()
+		}
+		log.Printf("Metrics available at http://localhost:%s%s", cfg.httpPort, cfg.metricsPath)
+	}
 
+	log.Println("Listening for real-time changes (Ctrl+C to stop)...")
+	_ = engine.Run(ctx)
+}
+
+// wireListenHooks routes the engine's raw pushes and polls into pm. pm is the
+// only copy of the listen diff state and the heater maps (bodyHeatingStatus,
+// referencedHeaters); both hooks hold pm.mu while they touch it, as do the data
+// age collector and OnScan, so a /metrics scrape never sees them mid-update.
+// Like the other hooks it must be wired before engine.Run.
+func wireListenHooks(pm *PoolMonitor, engine *intellicenter.Engine) {
 	engine.OnRawPush = func(msg map[string]any) {
-		pm.mu.Lock()
+		lockTimed(&pm.mu, "listen_push")
 		defer pm.mu.Unlock()
 		pm.processRawPushNotification(msg)
 		pm.outputRawJSON("PUSH", msg)
 	}
 
 	engine.OnRawPoll = func(req *intellicenter.Client, baseline bool) {
-		pm.mu.Lock()
+		lockTimed(&pm.mu, "listen_poll")
 		defer pm.mu.Unlock()
 		pm.listenPoll(engine, req, baseline)
 	}
+}
 
-	log.Println("Listening for real-time changes (Ctrl+C to stop)...")
-	_ = engine.Run(context.Background())
+// serveListenMetrics wires pm and engine to the registry (wireListenMetrics),
+// binds the metrics server on cfg.httpPort, and serves it until ctx is
+// canceled. It returns the bound address. It sets engine.OnScan, so it must
+// be called before engine.Run.
+func serveListenMetrics(ctx context.Context, cfg *appConfig, pm *PoolMonitor, engine *intellicenter.Engine,
+	registry *prometheus.Registry,
+) (net.Addr, error) {
+	wireListenMetrics(cfg, pm, engine, registry)
+	ln, err := bindMetricsServer(registry, pm, cfg)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if serr := serveHTTP(ctx, ln); serr != nil {
+			log.Printf("HTTP server stopped: %v", serr)
+		}
+	}()
+	return ln.Addr(), nil
+}
+
+// wireListenMetrics prepares a listen-mode PoolMonitor and engine to back the
+// shared registry: the metrics-only settings metrics mode applies, the data age
+// collector, the engine's diagnostic counters, and the scan-result gauges. The
+// gauges themselves are concurrency-safe, and pm's own state stays under pm.mu,
+// which the listen hooks, OnScan and the data age collector all take. It sets
+// engine.OnScan, so call it before engine.Run.
+func wireListenMetrics(cfg *appConfig, pm *PoolMonitor, engine *intellicenter.Engine, registry *prometheus.Registry) {
+	pm.enumMap = cfg.enumMap
+	pm.masterCircuits = cfg.masterCircuits
+	pm.boardTempKey = cfg.boardTempKey
+	pm.circuitTimerKey = cfg.circuitTimerKey
+	pm.chemFlowKey = cfg.chemFlowKey
+	pm.freezeObject = cfg.freezeObject
+	pm.bodyGallons = cfg.bodyGallons
+	pm.booleanStatus = cfg.statusEncoding == statusEncodingBoolean
+	metricsRegisterer(registry, cfg).MustRegister(newDataAgeCollector(pm))
+	pm.metrics.instrumentEngine(engine)
+	engine.OnScan = func(err error) {
+		if pm.metrics.recordScanResult(err) {
+			pm.updateRefreshTimestamp()
+		}
+	}
 }
 
 // listenPoll reproduces a legacy listen poll over the engine's connection: it
@@ -50,14 +134,20 @@ func runListenEngine(cfg *appConfig) {
 // change/detected lines via the listen track* helpers), then runs the
 // listen-only discovery queries over the engine's request client. On a fresh
 // baseline (post-connect/reconnect) it resets the diff state so a full
-// "detected" report is produced, matching the legacy listenLoop reconnect path.
+// "detected" report is produced, matching the legacy listenLoop reconnect path —
+// unless the reconnect came within reconnectGrace of the last poll, in which
+// case the prior baseline is kept and only genuine changes log.
 // Caller holds pm.mu.
 func (pm *PoolMonitor) listenPoll(engine *intellicenter.Engine, req *intellicenter.Client, baseline bool) {
 	pm.ic = req // route discovery queries through the engine's live connection
 	if baseline {
-		pm.previousState = nil
-		pm.initializeState()
-		pm.initialPollDone = false
+		if gap, ok := pm.withinReconnectGrace(); ok {
+			log.Printf("POLL: reconnected after %v; keeping previous baseline", gap.Round(time.Second))
+		} else {
+			pm.previousState = nil
+			pm.initializeState()
+			pm.initialPollDone = false
+		}
 	}
 	wasInitial := !pm.initialPollDone
 	pm.previousState.PollChangeCount = 0
@@ -76,7 +166,20 @@ func (pm *PoolMonitor) listenPoll(engine *intellicenter.Engine, req *intellicent
 
 	changes := pm.previousState.PollChangeCount
 	pm.initialPollDone = true
+	pm.lastListenPoll = pm.now()
 	if !wasInitial && changes == 0 {
 		log.Println("POLL: [no changes]")
 	}
 }
+
+// withinReconnectGrace reports whether a reconnect baseline arrived soon enough
+// after the last completed poll to keep the existing diff state, and the gap.
+// A brief blip keeps the baseline; a long outage (or grace disabled) does not,
+// since anything could have changed unobserved in the meantime.
+func (pm *PoolMonitor) withinReconnectGrace() (time.Duration, bool) {
+	if pm.reconnectGrace <= 0 || pm.previousState == nil || pm.lastListenPoll.IsZero() {
+		return 0, false
+	}
+	gap := pm.now().Sub(pm.lastListenPoll)
+	return gap, gap < pm.reconnectGrace
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"io"
+	"net"
+	"net/http"
 	"strings"
 	"testing"
 	"time"
@@ -9,11 +12,15 @@ import (
 	"github.com/astrostl/pentameter/intellicenter"
 )
 
-// TestListenPollFromEngine drives the engine against a mock IntelliCenter, then
-// runs a listen poll over the engine's snapshot + a live client and asserts the
-// listen diff-state and metrics reflect the equipment — and that a second,
-// unchanged poll detects zero changes.
-func TestListenPollFromEngine(t *testing.T) {
+// startListenTestEngine runs an engine against a mock IntelliCenter until its
+// baseline lands, plus a second live client standing in for the engine's request
+// client that the real OnRawPoll hook hands to listenPoll. The engine polls
+// every pollEvery; wire, if set, gets the engine and mock address before Run,
+// when hooks must be set.
+func startListenTestEngine(
+	t *testing.T, pollEvery time.Duration, wire func(engine *intellicenter.Engine, host, port string),
+) (*intellicenter.Engine, *intellicenter.Client, string, string) {
+	t.Helper()
 	responses := map[string]IntelliCenterResponse{
 		"GetParamList:OBJTYP=CIRCUIT": {ObjectList: []ObjectData{
 			{ObjName: "C0001", Params: map[string]string{"SNAME": "Pool Light", "STATUS": "ON", "OBJTYP": "CIRCUIT", "SUBTYP": "LIGHT", "FREEZE": "OFF"}},
@@ -36,23 +43,44 @@ func TestListenPollFromEngine(t *testing.T) {
 		}},
 	}
 	server := createMockWebSocketServer(t, responses)
-	defer server.Close()
+	t.Cleanup(server.Close)
 
 	host, port, _ := strings.Cut(strings.TrimPrefix(server.URL, "http://"), ":")
-	engine := intellicenter.NewEngine(host, port, time.Hour) // long poll: baseline only
+	engine := intellicenter.NewEngine(host, port, pollEvery)
+	engine.ShutdownGrace = 0 // stop at once, not after an in-flight poll
+	if wire != nil {
+		wire(engine, host, port)
+	}
 
+	// Wait for Run to return so no hook outlives the test and races the next
+	// one's globals.
 	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	go func() { _ = engine.Run(ctx) }()
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	go func() {
+		defer close(done)
+		_ = engine.Run(ctx)
+	}()
 	waitForCond(t, func() bool { return engine.Snapshot().Circuits["C0001"].Name == "Pool Light" })
 
-	// A second live client stands in for the engine's request client that the
-	// real OnRawPoll hook hands to listenPoll.
 	req := intellicenter.New(host, port)
 	if err := req.ConnectWithRetry(ctx); err != nil {
 		t.Fatalf("connect req client: %v", err)
 	}
-	defer req.Close()
+	t.Cleanup(req.Close)
+
+	return engine, req, host, port
+}
+
+// TestListenPollFromEngine drives the engine against a mock IntelliCenter, then
+// runs a listen poll over the engine's snapshot + a live client and asserts the
+// listen diff-state and metrics reflect the equipment — and that a second,
+// unchanged poll detects zero changes.
+func TestListenPollFromEngine(t *testing.T) {
+	engine, req, host, port := startListenTestEngine(t, time.Hour, nil) // long poll: baseline only
 
 	pm := NewPoolMonitor(host, port, true)
 	pm.initializeState()
@@ -69,16 +97,16 @@ func TestListenPollFromEngine(t *testing.T) {
 	if got := pm.previousState.PumpRPMs["Pump"]; got != 2000 {
 		t.Errorf("pump rpm diff-state: got %v, want 2000", got)
 	}
-	if got := pm.previousState.AirTemp; got != 75 {
+	if got := pm.previousState.AirTemps["_A135"]; got != 75 {
 		t.Errorf("air temp diff-state: got %v, want 75", got)
 	}
 	if got := pm.previousState.Circuits["Pool Light"]; got != "ON" {
 		t.Errorf("circuit diff-state: got %q, want ON", got)
 	}
-	if got := gaugeVal(t, poolTemperature.WithLabelValues("POOL", "Pool")); got != 82 {
+	if got := gaugeVal(t, pm.metrics.poolTemperature.WithLabelValues("B1101", "POOL", "Pool")); got != 82 {
 		t.Errorf("water temp gauge: got %v, want 82", got)
 	}
-	if got := gaugeVal(t, pumpRPM.WithLabelValues("PMP01", "Pump")); got != 2000 {
+	if got := gaugeVal(t, pm.metrics.pumpRPM.WithLabelValues("PMP01", "Pump")); got != 2000 {
 		t.Errorf("pump rpm gauge: got %v, want 2000", got)
 	}
 
@@ -89,3 +117,136 @@ func TestListenPollFromEngine(t *testing.T) {
 		t.Errorf("unchanged poll should detect 0 changes, got %d", pm.previousState.PollChangeCount)
 	}
 }
+
+// TestListenPollReconnectGrace checks that a reconnect baseline within the grace
+// gap keeps the diff state (no re-detection), while one after a long outage
+// resets it for a full "detected" report.
+func TestListenPollReconnectGrace(t *testing.T) {
+	engine, req, host, port := startListenTestEngine(t, time.Hour, nil) // long poll: baseline only
+
+	clock := time.Unix(1_700_000_000, 0)
+	pm := NewPoolMonitor(host, port, true)
+	pm.now = func() time.Time { return clock }
+	pm.reconnectGrace = time.Minute
+	pm.initializeState()
+	pm.listenPoll(engine, req, true)
+
+	// Short blip: the baseline survives.
+	kept := pm.previousState
+	clock = clock.Add(20 * time.Second)
+	pm.listenPoll(engine, req, true)
+	if pm.previousState != kept {
+		t.Error("short reconnect should keep the previous baseline")
+	}
+	if pm.previousState.WaterTemps["Pool"] != 82 {
+		t.Error("short reconnect should retain tracked values")
+	}
+	if pm.previousState.PollChangeCount != 0 {
+		t.Errorf("short reconnect over unchanged state should log 0 changes, got %d", pm.previousState.PollChangeCount)
+	}
+
+	// Long outage: the baseline is rebuilt from scratch.
+	clock = clock.Add(2 * time.Minute)
+	pm.listenPoll(engine, req, true)
+	if pm.previousState == kept {
+		t.Error("long reconnect should reset the baseline")
+	}
+
+	// Grace disabled: every reconnect resets.
+	pm.reconnectGrace = 0
+	kept = pm.previousState
+	clock = clock.Add(time.Second)
+	pm.listenPoll(engine, req, true)
+	if pm.previousState == kept {
+		t.Error("reconnect with grace disabled should reset the baseline")
+	}
+}
+
+// TestListenServesMetrics checks that listen mode serves /metrics on
+// --http-port: the gauges a listen poll sets, plus the scan-result and data age
+// metrics, from the shared registry.
+func TestListenServesMetrics(t *testing.T) {
+	var (
+		pm   *PoolMonitor
+		addr net.Addr
+	)
+	engine, req, _, _ := startListenTestEngine(t, time.Hour, func(engine *intellicenter.Engine, host, port string) {
+		pm = NewPoolMonitor(host, port, true)
+		pm.initializeState()
+		var err error
+		addr, err = serveListenMetrics(t.Context(), &appConfig{httpPort: "0"}, pm, engine, createPrometheusRegistry(&appConfig{}, pm.metrics))
+		if err != nil {
+			t.Fatalf("serveListenMetrics: %v", err)
+		}
+	})
+
+	pm.listenPoll(engine, req, true)
+	engine.OnScan(nil)
+
+	resp, err := http.Get("http://" + addr.String() + metricsPath)
+	if err != nil {
+		t.Fatalf("GET %s: %v", metricsPath, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s = %d, want 200", metricsPath, resp.StatusCode)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read metrics: %v", err)
+	}
+	body := string(raw)
+	for _, want := range []string{
+		`water_temperature_fahrenheit{body="POOL",name="Pool",objnam="B1101"} 82`,
+		`pump_rpm{name="Pump",pump="PMP01"} 2000`,
+		"connection_failure 0",
+		"pentameter_data_age_seconds",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
+
+// TestListenScrapeDuringPolls wires listen mode as runListenEngine does and
+// scrapes /metrics and /debug/state while the engine polls, so that under -race
+// any pm state the hooks touch outside pm.mu is reported.
+func TestListenScrapeDuringPolls(t *testing.T) {
+	var (
+		pm   *PoolMonitor
+		addr net.Addr
+	)
+	startListenTestEngine(t, 10*time.Millisecond, func(engine *intellicenter.Engine, host, port string) {
+		pm = NewPoolMonitor(host, port, true)
+		pm.quietDetection = true
+		pm.stateEngine = engine
+		pm.initializeState()
+		wireListenHooks(pm, engine)
+		var err error
+		addr, err = serveListenMetrics(t.Context(), &appConfig{httpPort: "0"}, pm, engine, createPrometheusRegistry(&appConfig{}, pm.metrics))
+		if err != nil {
+			t.Fatalf("serveListenMetrics: %v", err)
+		}
+	})
+
+	polled := func() bool {
+		pm.mu.Lock()
+		defer pm.mu.Unlock()
+		return pm.initialPollDone
+	}
+	waitForCond(t, polled)
+	for range 20 {
+		for _, path := range []string{metricsPath, debugStatePath} {
+			resp, err := http.Get("http://" + addr.String() + path)
+			if err != nil {
+				t.Fatalf("GET %s: %v", path, err)
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("GET %s = %d, want 200", path, resp.StatusCode)
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
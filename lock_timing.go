@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// lockWaitLogThreshold is the monitor-lock wait above which lockTimed logs, so
+// stalls are visible in listen mode too, which serves no /metrics.
+const lockWaitLogThreshold = 100 * time.Millisecond
+
+// lockTiming enables monitor-lock wait instrumentation (--lock-timing). Set once
+// at startup, before any engine runs; off by default to keep Lock() free.
+var lockTiming bool
+
+var lockWaitSeconds = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "pentameter_lock_wait_seconds",
+		Help:    "Time spent waiting to acquire the monitor lock, by call site (--lock-timing only)",
+		Buckets: []float64{0.0001, 0.001, 0.01, 0.05, 0.1, 0.5, 1, 5},
+	},
+	[]string{"site"},
+)
+
+// lockTimed acquires mu. With lockTiming on it also records how long the
+// acquisition waited under site, logging waits over lockWaitLogThreshold.
+func lockTimed(mu *sync.Mutex, site string) {
+	if !lockTiming {
+		mu.Lock()
+		return
+	}
+	start := time.Now()
+	mu.Lock()
+	wait := time.Since(start)
+	lockWaitSeconds.WithLabelValues(site).Observe(wait.Seconds())
+	if wait >= lockWaitLogThreshold {
+		log.Printf("LOCK: %s waited %v for the monitor lock", site, wait.Round(time.Millisecond))
+	}
+}
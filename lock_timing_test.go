@@ -0,0 +1,47 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestLockTimed(t *testing.T) {
+	waits := func() *dto.Histogram {
+		t.Helper()
+		var m dto.Metric
+		if err := lockWaitSeconds.WithLabelValues("test").(prometheus.Metric).Write(&m); err != nil {
+			t.Fatalf("write metric: %v", err)
+		}
+		return m.GetHistogram()
+	}
+
+	var mu sync.Mutex
+	lockTimed(&mu, "test")
+	mu.Unlock()
+	if got := waits().GetSampleCount(); got != 0 {
+		t.Fatalf("lock timing off: recorded %d samples, want 0", got)
+	}
+
+	lockTiming = true
+	defer func() { lockTiming = false }()
+
+	mu.Lock()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		mu.Unlock()
+	}()
+	lockTimed(&mu, "test") // waits for the goroutine's unlock
+	mu.Unlock()
+
+	h := waits()
+	if got := h.GetSampleCount(); got != 1 {
+		t.Errorf("lock timing on: recorded %d samples, want 1", got)
+	}
+	if got := h.GetSampleSum(); got < 0.01 {
+		t.Errorf("recorded wait %vs, want at least the 20ms hold", got)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Log output formats (--log-format).
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// jsonLogger writes structured log entries (--log-format=json); nil keeps the
+// plain-text log package output. Set once at startup by setupLogging, before
+// anything logs from another goroutine.
+var jsonLogger *slog.Logger
+
+// setupLogging switches to JSON log entries on stderr for --log-format=json.
+// Plain log.Printf lines are routed through the same handler, so every line is
+// an entry with the formatted text as msg; logEventf callers also attach fields.
+func setupLogging(format string) {
+	if format != logFormatJSON {
+		return
+	}
+	jsonLogger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	log.SetFlags(0) // the handler adds its own time
+	log.SetOutput(logLineWriter{})
+}
+
+// logLineWriter adapts the log package to jsonLogger: each line written is one
+// entry, leveled by logLevel.
+type logLineWriter struct{}
+
+func (logLineWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	jsonLogger.Log(context.Background(), logLevel(msg), msg)
+	return len(p), nil
+}
+
+// logLevel infers an entry's level from the line's conventional prefix:
+// "Warning:" (or an "engine: warning:" style tag) is WARN, "Error"/"Failed" is
+// ERROR, and everything else is INFO.
+func logLevel(msg string) slog.Level {
+	lower := strings.ToLower(msg)
+	switch {
+	case strings.HasPrefix(lower, "error") || strings.HasPrefix(lower, "failed"):
+		return slog.LevelError
+	case strings.HasPrefix(lower, "warning") || strings.Contains(lower, ": warning:"):
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// logEventf logs a formatted line with structured fields, given as alternating
+// keys and values as for slog. Text output is the line alone, exactly as
+// log.Printf would print it; JSON output attaches the fields to the entry.
+func logEventf(fields []any, format string, v ...any) {
+	msg := fmt.Sprintf(format, v...)
+	if jsonLogger == nil {
+		log.Print(msg)
+		return
+	}
+	jsonLogger.Log(context.Background(), logLevel(msg), msg, fields...)
+}
+
+// changeFields returns the fields for a logChangedf line from its dedupe key,
+// "event:objnam" (e.g. "watertemp:B1101") or a bare event ("freeze").
+func changeFields(key string) []any {
+	event, objnam, ok := strings.Cut(key, ":")
+	if !ok {
+		return []any{"event", event}
+	}
+	return []any{"event", event, fieldObjnam, objnam}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// captureJSONLogs points jsonLogger at a buffer for the test and returns it.
+func captureJSONLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	jsonLogger = slog.New(slog.NewJSONHandler(&buf, nil))
+	t.Cleanup(func() { jsonLogger = nil })
+	return &buf
+}
+
+// lastEntry decodes the last JSON log entry in buf.
+func lastEntry(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &entry); err != nil {
+		t.Fatalf("log entry %q is not JSON: %v", lines[len(lines)-1], err)
+	}
+	return entry
+}
+
+func TestLogEventfJSON(t *testing.T) {
+	buf := captureJSONLogs(t)
+	pm := NewPoolMonitor("test", "6680", false)
+
+	pm.logChangedf("watertemp:B1101", "Updated temperature: %s = %.1f°F", "Pool", 82.0)
+	entry := lastEntry(t, buf)
+	if entry["msg"] != "Updated temperature: Pool = 82.0°F" || entry["level"] != "INFO" {
+		t.Errorf("change entry = %v", entry)
+	}
+	if entry["event"] != "watertemp" || entry["objnam"] != "B1101" {
+		t.Errorf("change fields = %v, want event=watertemp objnam=B1101", entry)
+	}
+
+	pm.handleCircuitPush(ObjectData{ObjName: "C0001", Params: map[string]string{"STATUS": "ON"}}, "Pool Light")
+	entry = lastEntry(t, buf)
+	if entry["objnam"] != "C0001" || entry["name"] != "Pool Light" || entry["status"] != "ON" {
+		t.Errorf("push fields = %v, want objnam=C0001 name=Pool Light status=ON", entry)
+	}
+
+	logEventf(nil, "Warning: %s", "slow panel")
+	if entry = lastEntry(t, buf); entry["level"] != "WARN" {
+		t.Errorf("warning level = %v, want WARN", entry["level"])
+	}
+}
+
+// TestLogEventfText checks that the default text output is the plain line,
+// with no fields appended.
+func TestLogEventfText(t *testing.T) {
+	var buf bytes.Buffer
+	out, flags := log.Writer(), log.Flags()
+	log.SetOutput(&buf)
+	defer log.SetOutput(out)
+	log.SetFlags(0)
+	defer log.SetFlags(flags)
+
+	logEventf([]any{"objnam", "C0001"}, "PUSH: %s status=%s", "Pool Light", "ON")
+	if got := buf.String(); got != "PUSH: Pool Light status=ON\n" {
+		t.Errorf("text line = %q", got)
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	for msg, want := range map[string]slog.Level{
+		"Updated circuit status: Pool":                slog.LevelInfo,
+		"Warning: failed to get circuit groups":       slog.LevelWarn,
+		"engine: warning: BODY query returned no obj": slog.LevelWarn,
+		"Failed to parse temperature x for Pool":      slog.LevelError,
+		"Error closing mDNS advertiser":               slog.LevelError,
+	} {
+		if got := logLevel(msg); got != want {
+			t.Errorf("logLevel(%q) = %v, want %v", msg, got, want)
+		}
+	}
+}
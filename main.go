@@ -1,15 +1,19 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
+	"runtime"
 	"slices"
 	"strconv"
 	"strings"
@@ -35,12 +39,30 @@ const (
 	httpReadTimeout     = 15 * time.Second
 	httpWriteTimeout    = 15 * time.Second
 	httpIdleTimeout     = 60 * time.Second
+	// httpShutdownTimeout bounds how long a shutdown waits for in-flight
+	// scrapes (and the engine's connections) to finish.
+	httpShutdownTimeout = 5 * time.Second
 
 	// Listen mode polling interval (catches equipment that doesn't push).
 	listenModePollInterval = 10
 
-	// Metric key parts count (objnam|name|subtype).
-	metricKeyPartsCount = 3
+	// Default listen-mode reconnect grace in seconds: a reconnect within this gap
+	// of the last poll keeps the change-detection baseline instead of re-detecting.
+	defaultReconnectGrace = 300
+
+	// Default consecutive connect or session failures before an auto-discovered
+	// panel is looked up again via mDNS on reconnect.
+	defaultRediscoveryThreshold = 3
+
+	// Default per-response timeout in seconds for a session's baseline (first
+	// scan + static config), longer than steady state so cold starts succeed.
+	defaultInitialTimeout = 60
+
+	// Minutes per day, for turnovers-per-day from GPM.
+	minutesPerDay = 24 * 60
+
+	// Metric key parts count (objnam|name|subtype|group).
+	metricKeyPartsCount = 4
 
 	// Circuit status constants.
 	statusOn = "ON"
@@ -56,6 +78,10 @@ const (
 	statusDescFreeze   = keyFREEZE
 	statusDescPumpIdle = "OFF (pump not running)"
 
+	// System MODE values (display units).
+	unitsEnglish = "ENGLISH"
+	unitsMetric  = "METRIC"
+
 	// Boolean string constants.
 	trueString = "true"
 
@@ -96,6 +122,11 @@ const (
 
 	// Subtype / body-name values.
 	subtypGeneric = "GENERIC"
+	subtypSolar   = "SOLAR"
+	subtypUltra   = "ULTRA" // heat pump
+	subtypPool    = "POOL"  // body master circuit
+	subtypSpa     = "SPA"   // body master circuit
+	subtypFreeze  = "FRZ"   // freeze-protection feature circuit
 	bodyNamePool  = "pool"
 	bodyNameSpa   = "spa"
 
@@ -110,6 +141,7 @@ const (
 	logFieldBody    = "body"
 	logFieldCircuit = "circuit"
 	logFieldHeater  = "heater"
+	fieldGroup      = "group"
 	fieldName       = "name"
 	fieldSubtyp     = "subtyp"
 
@@ -123,13 +155,24 @@ const (
 	keySUBTYP  = "SUBTYP"
 	keyLOTMP   = "LOTMP"
 	keyHITMP   = "HITMP"
-	keyPWR     = "PWR" // pump real power draw (watts)
+	keyGPM     = "GPM"
 	keyPARENT  = "PARENT"
 	keyCIRCUIT = "CIRCUIT" // PMPCIRC: the driven circuit/feature objnam
 	keyUSE     = "USE"
 	keyLISTORD = "LISTORD"
 	keySTATIC  = "STATIC"
 	keyFREEZE  = "FREEZE"
+	keyBODY    = "BODY" // HEATER: space-separated objnams of the bodies it serves
+	keyCOOL    = "COOL"
+	keyVACFLO  = "VACFLO" // system object: vacation mode ON/OFF
+	keyMODE    = "MODE"   // system object: display units ENGLISH/METRIC
+
+	// Sensor STATUS value when the probe is reporting normally.
+	sensorStatusOK = "OK"
+
+	// heater_active source label values.
+	sourceHeatPump = "heatpump"
+	sourceHeater   = "heater"
 )
 
 // IntelliCenter API structures are aliased to the intellicenter package, which
@@ -142,111 +185,377 @@ type (
 	ObjectData            = intellicenter.ObjectData
 )
 
-// Prometheus metrics.
+// Process-wide Prometheus metrics, shared by every controller. Each controller's
+// own metrics live on its PoolMonitor (see controllerMetrics).
 var (
-	poolTemperature = prometheus.NewGaugeVec(
+	buildInfo = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Name: "water_temperature_fahrenheit",
-			Help: "Current water temperature in Fahrenheit",
+			Name: "intellicenter_build_info",
+			Help: "Always 1, labeled with the pentameter release and the Go version it was built with",
 		},
-		[]string{logFieldBody, fieldName},
+		[]string{"version", "goversion"},
 	)
 
-	airTemperature = prometheus.NewGaugeVec(
+	discoveryDuration = prometheus.NewGauge(
 		prometheus.GaugeOpts{
-			Name: "air_temperature_fahrenheit",
-			Help: "Current outdoor air temperature in Fahrenheit",
+			Name: "intellicenter_discovery_duration_seconds",
+			Help: "Duration of the most recent mDNS discovery of the IntelliCenter",
 		},
-		[]string{"sensor", fieldName},
 	)
 
-	connectionFailure = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "intellicenter_connection_failure",
-			Help: "1 if there was a connection failure in the last refresh, 0 if successful",
+	discoveryAttempts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "intellicenter_discovery_attempts_total",
+			Help: "mDNS discovery attempts (startup and rediscovery) by result (success or failure)",
 		},
+		[]string{"result"},
 	)
+)
 
-	lastRefreshTimestamp = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "intellicenter_last_refresh_timestamp_seconds",
-			Help: "Unix timestamp of the last successful data refresh",
-		},
-	)
+// coreMetrics are a controller's equipment and connection metrics. Temperature
+// metrics live in temperature_units.go.
+type coreMetrics struct {
+	airSensorConnected    *prometheus.GaugeVec
+	connectionFailure     prometheus.Gauge
+	queryFailure          prometheus.Gauge
+	lastRefreshTimestamp  prometheus.Gauge
+	targetInfo            *prometheus.GaugeVec
+	lastPushTimestamp     *prometheus.GaugeVec
+	pumpRPM               *prometheus.GaugeVec
+	pumpWatts             *prometheus.GaugeVec
+	pumpGPM               *prometheus.GaugeVec
+	pumpTotalWatts        prometheus.Gauge
+	circuitStatus         *prometheus.GaugeVec
+	masterCircuitStatus   *prometheus.GaugeVec
+	thermalStatus         *prometheus.GaugeVec
+	thermalStatusMismatch *prometheus.GaugeVec
+	featureStatus         *prometheus.GaugeVec
+	heaterActive          *prometheus.GaugeVec
+	pushesSkipped         prometheus.Counter
+	pushesDropped         prometheus.Counter
+	queryResponseSeconds  *prometheus.HistogramVec
+	emptyResponses        *prometheus.CounterVec
+	reconnects            prometheus.Counter
+	panelReboots          prometheus.Counter
+	unitMismatch          prometheus.Gauge
+	vacationMode          prometheus.Gauge
+	poolTurnoversPerDay   *prometheus.GaugeVec
+	bodyFiltrationSeconds *prometheus.CounterVec
+	bodyHeatMode          *prometheus.GaugeVec
+	heaterHeatingSeconds  *prometheus.CounterVec
+	heaterCycles          *prometheus.CounterVec
+}
+
+// defineCoreMetrics builds coreMetrics.
+func defineCoreMetrics() coreMetrics {
+	return coreMetrics{
+		airSensorConnected: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "air_sensor_connected",
+				Help: "1 if the air sensor reports STATUS=OK, 0 otherwise. A 0 means a missing or zero air temperature is " +
+					"a sensor fault, not the weather.",
+			},
+			[]string{fieldObjnam, "sensor", fieldName},
+		),
 
-	pumpRPM = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "pump_rpm",
-			Help: "Current pump speed in revolutions per minute",
-		},
-		[]string{"pump", fieldName},
-	)
+		connectionFailure: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "intellicenter_connection_failure",
+				Help: "1 if the last refresh failed at the transport level (can't connect, socket error or timeout), 0 if successful",
+			},
+		),
 
-	circuitStatus = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "circuit_status",
-			Help: "Circuit status (0=off, 1=on, 2=freeze protection active). A circuit that drives a pump " +
-				"reads on only if it is commanded on AND that pump is actually running (RPM>0); a commanded-on " +
-				"circuit whose pump has no power reads off.",
-		},
-		[]string{logFieldCircuit, fieldName, fieldSubtyp},
-	)
+		queryFailure: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "intellicenter_query_failure",
+				Help: "1 if the last refresh reached the panel but a query was rejected or unanswered, 0 if successful",
+			},
+		),
 
-	thermalStatus = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "thermal_status",
-			Help: "Thermal equipment operational status derived from IntelliCenter HTMODE+HTSRC " +
-				"(0=off, 1=heating, 2=idle, 3=cooling). Note: 'idle' is pentameter's interpretation " +
-				"of HTMODE=0+assigned heater, not an IntelliCenter native status.",
-		},
-		[]string{logFieldHeater, fieldName, fieldSubtyp},
-	)
+		lastRefreshTimestamp: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "intellicenter_last_refresh_timestamp_seconds",
+				Help: "Unix timestamp of the last successful data refresh",
+			},
+		),
 
-	thermalLowSetpoint = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "thermal_low_setpoint_fahrenheit",
-			Help: "Heating target temperature in Fahrenheit (turn on heating when temp drops below this)",
-		},
-		[]string{logFieldHeater, fieldName, fieldSubtyp},
-	)
+		targetInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "intellicenter_target_info",
+				Help: "Always 1, labeled with the panel address this exporter talks to; changes when rediscovery finds a new IP",
+			},
+			[]string{"ip", "port"},
+		),
+
+		// A vector with no labels, so nothing is exported until a push arrives
+		// (only listen mode processes pushes itself; see processRawPushNotification).
+		lastPushTimestamp: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "intellicenter_last_push_timestamp_seconds",
+				Help: "Unix timestamp of the last push notification carrying an object list (listen mode)",
+			},
+			nil,
+		),
 
-	thermalHighSetpoint = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "thermal_high_setpoint_fahrenheit",
-			Help: "Cooling target temperature in Fahrenheit (turn on cooling when temp rises above this)",
-		},
-		[]string{logFieldHeater, fieldName, fieldSubtyp},
-	)
+		pumpRPM: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "pump_rpm",
+				Help: "Current pump speed in revolutions per minute",
+			},
+			[]string{"pump", fieldName},
+		),
 
-	featureStatus = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "feature_status",
-			Help: "Feature status (0=off, 1=on, 2=freeze protection active). A feature that drives a pump " +
-				"reads on only if it is commanded on AND that pump is actually running (RPM>0); a commanded-on " +
-				"feature whose pump has no power reads off.",
-		},
-		[]string{"feature", fieldName, fieldSubtyp},
-	)
-)
+		pumpWatts: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "pump_watts",
+				Help: "Current pump power draw in watts (PWR, or WATTS on firmwares that populate it instead)",
+			},
+			[]string{"pump", fieldName},
+		),
+
+		pumpGPM: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "pump_gpm",
+				Help: "Current pump flow in gallons per minute (estimated on pumps without flow sensing, MAXF 0). " +
+					"Absent while the pump reports no flow.",
+			},
+			[]string{"pump", fieldName},
+		),
+
+		pumpTotalWatts: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "pump_total_watts",
+				Help: "Combined real power draw of all pumps in watts (pumps reporting no power are skipped)",
+			},
+		),
+
+		circuitStatus: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "circuit_status",
+				Help: "Circuit status (0=off, 1=on, 2=freeze protection active; with --status-encoding=boolean, freeze " +
+					"protection reads 1 and is reported by circuit_freeze_protected). A circuit that drives a pump " +
+					"reads on only if it is commanded on AND that pump is actually running (RPM>0); a commanded-on " +
+					"circuit whose pump has no power reads off. group lists the circuit groups it belongs to.",
+			},
+			[]string{logFieldCircuit, fieldName, fieldSubtyp, fieldGroup},
+		),
+
+		masterCircuitStatus: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "master_circuit_status",
+				Help: "Status of each body's master circuit (SUBTYP POOL or SPA, or those named by --master-circuits), " +
+					"with circuit_status's values and pump gating: the top-level \"is the pool running\" signal.",
+			},
+			[]string{logFieldCircuit, fieldName, fieldSubtyp, fieldGroup},
+		),
+
+		thermalStatus: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "thermal_status",
+				Help: "Thermal equipment operational status derived from IntelliCenter HTMODE+HTSRC " +
+					"(0=off, 1=heating, 2=idle, 3=cooling). Note: 'idle' is pentameter's interpretation " +
+					"of HTMODE=0+assigned heater, not an IntelliCenter native status.",
+			},
+			[]string{logFieldHeater, fieldName, fieldSubtyp},
+		),
+
+		thermalStatusMismatch: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "thermal_status_mismatch",
+				Help: "1 if a body's assigned heater reports STATUS=OFF while thermal_status (derived from the body's HTMODE) " +
+					"says it is heating or cooling, 0 otherwise. A 1 means pentameter's inference disagrees with the panel.",
+			},
+			[]string{logFieldHeater, fieldName},
+		),
+
+		featureStatus: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "feature_status",
+				Help: "Feature status (0=off, 1=on, 2=freeze protection active; with --status-encoding=boolean, freeze " +
+					"protection reads 1 and is reported by feature_freeze_protected). A feature that drives a pump " +
+					"reads on only if it is commanded on AND that pump is actually running (RPM>0); a commanded-on " +
+					"feature whose pump has no power reads off. group lists the circuit groups it belongs to.",
+			},
+			[]string{"feature", fieldName, fieldSubtyp, fieldGroup},
+		),
+
+		heaterActive: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "heater_active",
+				Help: "1 if this heater is the source currently doing the work (heating, or a heat pump cooling) " +
+					"for a body it serves, 0 otherwise. source is heatpump or heater.",
+			},
+			[]string{logFieldHeater, fieldName, "source"},
+		),
+
+		pushesSkipped: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "intellicenter_pushes_skipped_total",
+				Help: "Unsolicited pushes skipped on the request connection while awaiting a poll/control response",
+			},
+		),
+
+		pushesDropped: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "intellicenter_push_dropped_total",
+				Help: "Pushes dropped because processing fell too far behind the push connection (the next poll reconciles them)",
+			},
+		),
+
+		queryResponseSeconds: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "intellicenter_response_seconds",
+				Help:    "Round-trip time of answered panel requests, by command (body, air, pump, circuit, heater, ...). A rising tail is an early sign of an overloaded controller.",
+				Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+			},
+			[]string{"command"},
+		),
+
+		emptyResponses: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "intellicenter_empty_response_total",
+				Help: "Required queries (circuit, body) answered with no objects. Every panel has both, so this is a panel fault or misconfiguration; the previous values are kept meanwhile",
+			},
+			[]string{"query"},
+		),
+
+		reconnects: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "intellicenter_reconnects_total",
+				Help: "Reconnections to the panel after the first successful connect, however brief the outage",
+			},
+		),
+
+		panelReboots: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "intellicenter_reboots_total",
+				Help: "Likely panel reboots: reconnects after the connection was down 60s or more (a long network outage counts too)",
+			},
+		),
+
+		unitMismatch: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "pentameter_unit_mismatch",
+				Help: "1 if the panel reports metric units (system MODE=METRIC) while pentameter exports Fahrenheit, " +
+					"so temperature metrics hold Celsius values under _fahrenheit names; 0 otherwise (including a metric panel with --units=c)",
+			},
+		),
+
+		vacationMode: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "intellicenter_vacation_mode",
+				Help: "1 if the panel is in vacation (away) mode, which changes how schedules and heating run, 0 otherwise",
+			},
+		),
+
+		poolTurnoversPerDay: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "pool_turnovers_per_day",
+				Help: "Turnovers per day at the current flow: GPM of the running pumps associated with the body, " +
+					"times 1440, over its --pool-gallons volume. 0 while the body isn't circulating.",
+			},
+			[]string{fieldObjnam, logFieldBody, fieldName},
+		),
+
+		bodyFiltrationSeconds: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "body_filtration_seconds_total",
+				Help: "Cumulative seconds a body has been circulating (STATUS=ON, and at least one pump it drives " +
+					"running when it has a pump association). The basis for turnovers-per-day.",
+			},
+			[]string{fieldObjnam, logFieldBody, fieldName},
+		),
+
+		bodyHeatMode: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "body_heat_mode",
+				Help: "Raw HTMODE of a body: 0=not heating, 1=heater heating, 4=heat pump heating, 9=heat pump cooling. " +
+					"thermal_status is derived from it; this exposes the panel's value for debugging.",
+			},
+			[]string{fieldObjnam, logFieldBody, fieldName},
+		),
+
+		heaterHeatingSeconds: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "heater_heating_seconds_total",
+				Help: "Cumulative seconds a heater has been working (thermal_status 1 heating or 3 cooling). " +
+					"The key driver of gas/electricity cost and heater wear.",
+			},
+			[]string{logFieldHeater, fieldName},
+		),
+
+		heaterCycles: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "heater_cycles_total",
+				Help: "Times a heater has started heating (thermal_status changed to 1 from off or idle). A high rate against heater_heating_seconds_total means short-cycling.",
+			},
+			[]string{logFieldHeater, fieldName},
+		),
+	}
+}
 
 type PoolMonitor struct {
 	lastRefresh            time.Time
-	ic                     *intellicenter.Client     // IntelliCenter transport + protocol
-	bodyHeatingStatus      map[string]bool           // Track which bodies are actively heating
-	referencedHeaters      map[string]BodyHeaterInfo // Track body-to-heater assignments
-	featureConfig          map[string]string         // Track feature objnam -> SHOMNU for visibility
-	circuitFreezeConfig    map[string]bool           // Track circuit objnam -> freeze protection enabled
-	circuitNames           map[string]string         // Track circuit/group objnam -> SNAME for display
-	activeCircuitKeys      map[string]bool           // Track active circuit metric keys for stale cleanup
-	activeFeatureKeys      map[string]bool           // Track active feature metric keys for stale cleanup
-	previousState          *EquipmentState           // Previous state for change detection
-	mu                     sync.Mutex                // Protects concurrent access in listen mode
-	lastLogged             map[string]string         // Last "Updated ..." line logged per object key; gates change-only logging
-	listenMode             bool                      // Enable live event logging mode (includes raw JSON output)
-	initialPollDone        bool                      // Track if initial poll completed (suppresses "detected" logs after first poll)
-	freezeProtectionActive bool                      // Track if freeze protection is currently active
-	pumpRunning            map[string]bool           // pump objnam -> actually running (RPM>0); rebuilt each refresh
-	circuitToPumps         map[string][]string       // driven circuit/feature objnam -> pump objnams (from PMPCIRC); rebuilt each refresh
+	ic                     *intellicenter.Client                 // IntelliCenter transport + protocol
+	bodyHeatingStatus      map[string]bool                       // Track which bodies are actively heating
+	referencedHeaters      map[string]BodyHeaterInfo             // Track body-to-heater assignments
+	featureConfig          map[string]string                     // Track feature objnam -> SHOMNU for visibility
+	configObjects          map[string]intellicenter.ConfigObject // GetConfiguration objnam -> type/subtype/name (authoritative identity)
+	circuitFreezeConfig    map[string]bool                       // Track circuit objnam -> freeze protection enabled
+	circuitNames           map[string]string                     // Track circuit/group objnam -> SNAME for display
+	circuitGroups          map[string]string                     // member circuit/feature objnam -> comma-joined group names (from CIRCGRP); rebuilt each refresh
+	activeCircuitKeys      map[string]bool                       // Track active circuit metric keys for stale cleanup
+	activeFeatureKeys      map[string]bool                       // Track active feature metric keys for stale cleanup
+	activeMasterKeys       map[string]bool                       // Track active master circuit metric keys for stale cleanup
+	activePumps            map[string]string                     // pump objnam -> name of its series this refresh, for stale cleanup
+	activeBodies           map[string]objectSeries               // body objnam -> labels of its series this refresh, for stale cleanup
+	activeHeaters          map[string]objectSeries               // heater objnam -> labels of its series this refresh, for stale cleanup
+	activeNextRunKeys      map[string]bool                       // circuit|name keys with a circuit_next_run_seconds series
+	activeSetpointKeys     map[string]bool                       // circuit|name keys with a circuit_scheduled_setpoint_fahrenheit series
+	activeAlertKeys        map[string]bool                       // alert|code|message keys with an intellicenter_alert_info series
+	masterCircuits         map[string]bool                       // --master-circuits: objnams that replace SUBTYP detection; nil = detect
+	boardTempKey           string                                // --board-temp-key: system object param holding the board temperature; "" = off
+	panelMetric            bool                                  // system MODE=METRIC: readings are already Celsius
+	circuitTimerKey        string                                // --circuit-timer-key: circuit param holding the remaining run time; "" = off
+	chemFlowKey            string                                // --chem-flow-key: CHEM param holding the flow switch state; "" = off
+	freezeObject           string                                // --freeze-object: objnam reporting freeze protection; "" = detect
+	previousState          *EquipmentState                       // Previous state for change detection
+	mu                     sync.Mutex                            // Protects concurrent access in listen mode
+	lastLogged             map[string]string                     // Last "Updated ..." line logged per object key; gates change-only logging
+	listenMode             bool                                  // Enable live event logging mode (includes raw JSON output)
+	initialPollDone        bool                                  // Track if initial poll completed (suppresses "detected" logs after first poll)
+	freezeProtectionActive bool                                  // Track if freeze protection is currently active
+	pumpRunning            map[string]bool                       // pump objnam -> actually running (RPM>0); rebuilt each refresh
+	pumpFlow               map[string]float64                    // pump objnam -> reported GPM; rebuilt each refresh
+	bodyGallons            map[string]float64                    // --pool-gallons: body objnam -> volume in gallons
+	circuitToPumps         map[string][]string                   // driven circuit/feature objnam -> pump objnams (from PMPCIRC); rebuilt each refresh
+	filtrationSamples      map[string]runtimeSample              // body objnam -> last filtration sample, for runtime accumulation
+	heatingSamples         map[string]runtimeSample              // heater objnam -> last heating sample, for runtime accumulation
+	circuitRunSamples      map[string]runtimeSample              // circuit/feature objnam -> last running sample, for runtime accumulation
+	equipmentLabels        map[string][4]string                  // objnam -> current intellicenter_equipment_status labels (--equipment-status)
+	readingSamples         map[string]readingSample              // objnam -> last sensor reading and polls since it changed
+	now                    func() time.Time                      // Injectable clock (tests); defaults to time.Now
+	reconnectGrace         time.Duration                         // Listen mode: keep the baseline across reconnects shorter than this (0 = always reset)
+	lastListenPoll         time.Time                             // Listen mode: when the last successful poll completed
+	quietDetection         bool                                  // Listen mode: suppress "detected" inventory lines (change lines still log)
+	declaredBodies         map[string]string                     // --bodies: body objnam -> bodyHeatingStatus key; consulted before pool/spa name matching
+	pumpAnomaly            *pumpAnomalyDetector                  // --pump-anomaly: GPM-per-watt baselines; nil when disabled
+	watchdog               *failureWatchdog                      // --watchdog-timeout: exits on prolonged failure; nil when disabled
+	pushgateway            *gatewayPusher                        // --pushgateway-url: pushes after each successful scan; nil when disabled
+	stateEngine            *intellicenter.Engine                 // --debug-endpoint: served on /debug/state; nil when disabled
+	metrics                *controllerMetrics                    // this controller's own metric set
+	equipmentStatus        bool                                  // --equipment-status: export intellicenter_equipment_status
+	booleanStatus          bool                                  // --status-encoding=boolean: 0/1 status plus *_freeze_protected gauges
+	celsius                bool                                  // --units=c: temperatures exported in Celsius
+	enumMap                enumMap                               // --enum-map: OBJTYP.KEY value tables exported as enum_value
+}
+
+// runtimeSample is the last observed on/off state of an object and when it was
+// observed, so the time until the next observation can be credited to a runtime
+// counter when the object was running.
+type runtimeSample struct {
+	at      time.Time
+	running bool
 }
 
 // CircGrpState tracks the state of a circuit group member.
@@ -268,36 +577,66 @@ type EquipmentState struct {
 	UnknownEquip    map[string]string       // objnam -> "OBJTYP:STATUS" for equipment not otherwise tracked
 	ParseErrors     map[string]bool         // Track parse errors we've already logged
 	SkippedFeatures map[string]bool         // Track skipped features we've already logged
-	AirTemp         float64
-	PollChangeCount int // Count changes detected during current poll
+	AirTemps        map[string]float64      // air sensor objnam -> temperature
+	PollChangeCount int                     // Count changes detected during current poll
+}
+
+// objectSeries is the subtype and name labels an object's series were last
+// exported under, so they can be deleted once it is removed or renamed.
+type objectSeries struct {
+	subtype string
+	name    string
 }
 
+// BodyHeaterInfo is a body's heater assignment, keyed by the heater (HTSRC)
+// in referencedHeaters. Every field is copied from the body's own params.
 type BodyHeaterInfo struct {
 	BodyName  string
 	BodyObj   string
 	HeaterObj string
 	HTMode    int
-	Temp      float64
+	Temp      float64 // the body's TEMP, not a heater reading: heaters report no temperature
 	LoTemp    float64
 	HiTemp    float64
 }
 
 func NewPoolMonitor(intelliCenterIP, intelliCenterPort string, listenMode bool) *PoolMonitor {
+	return newPoolMonitor(intelliCenterIP, intelliCenterPort, listenMode, false)
+}
+
+// newPoolMonitor is NewPoolMonitor with the output units: celsius (--units=c)
+// exports temperatures in Celsius under *_celsius names.
+func newPoolMonitor(intelliCenterIP, intelliCenterPort string, listenMode, celsius bool) *PoolMonitor {
 	return &PoolMonitor{
 		ic:                     intellicenter.New(intelliCenterIP, intelliCenterPort),
+		metrics:                defineControllerMetrics(celsius),
+		celsius:                celsius,
 		bodyHeatingStatus:      make(map[string]bool),
 		referencedHeaters:      make(map[string]BodyHeaterInfo),
 		featureConfig:          make(map[string]string),
+		configObjects:          make(map[string]intellicenter.ConfigObject),
 		circuitFreezeConfig:    make(map[string]bool),
 		circuitNames:           make(map[string]string),
+		circuitGroups:          make(map[string]string),
 		activeCircuitKeys:      make(map[string]bool),
 		activeFeatureKeys:      make(map[string]bool),
+		activeMasterKeys:       make(map[string]bool),
+		activePumps:            make(map[string]string),
+		activeBodies:           make(map[string]objectSeries),
+		activeHeaters:          make(map[string]objectSeries),
 		previousState:          nil,
 		lastLogged:             make(map[string]string),
 		listenMode:             listenMode,
 		freezeProtectionActive: false,
 		pumpRunning:            make(map[string]bool),
+		pumpFlow:               make(map[string]float64),
 		circuitToPumps:         make(map[string][]string),
+		filtrationSamples:      make(map[string]runtimeSample),
+		heatingSamples:         make(map[string]runtimeSample),
+		circuitRunSamples:      make(map[string]runtimeSample),
+		equipmentLabels:        make(map[string][4]string),
+		readingSamples:         make(map[string]readingSample),
+		now:                    time.Now,
 	}
 }
 
@@ -323,20 +662,57 @@ func (pm *PoolMonitor) outputRawObjectData(obj ObjectData) {
 }
 
 // processRawPushNotification handles raw JSON push notifications.
-// Logs everything received, then processes known types.
+// Logs everything received, then processes known types. Frames are decoded into
+// the typed intellicenter.PushFrame; anything that doesn't fit it is walked as a
+// generic map by processRawPushMap.
 func (pm *PoolMonitor) processRawPushNotification(msg map[string]interface{}) {
+	frame, err := intellicenter.PushFrameFromMap(msg)
+	if err != nil {
+		pm.processRawPushMap(msg)
+		return
+	}
+	if len(frame.ObjectList) == 0 {
+		pm.logRawPushMessage(msg)
+		return
+	}
+	pm.markPushReceived()
+
+	for _, obj := range frame.ObjectList {
+		if obj.Changes == nil {
+			pm.logRawPushMessage(obj)
+			continue
+		}
+		for _, change := range obj.Changes {
+			if change.Params == nil {
+				continue
+			}
+			pm.processPushObject(ObjectData{ObjName: change.ObjName, Params: change.Params.Text()})
+		}
+	}
+}
+
+// processRawPushMap is the generic-map fallback for push frames the typed model
+// rejects: it salvages each well-formed objectList item on its own.
+func (pm *PoolMonitor) processRawPushMap(msg map[string]interface{}) {
 	objectList, ok := msg["objectList"].([]interface{})
 	if !ok || len(objectList) == 0 {
 		pm.logRawPushMessage(msg)
 		return
 	}
+	pm.markPushReceived()
 
 	for _, item := range objectList {
 		pm.processObjectListItem(item)
 	}
 }
 
-func (pm *PoolMonitor) logRawPushMessage(msg map[string]interface{}) {
+// markPushReceived records that the push stream delivered an object list, so a
+// stream that goes quiet while the connection stays open can be alerted on.
+func (pm *PoolMonitor) markPushReceived() {
+	pm.metrics.lastPushTimestamp.WithLabelValues().Set(float64(pm.now().Unix()))
+}
+
+func (pm *PoolMonitor) logRawPushMessage(msg any) {
 	jsonBytes, err := json.Marshal(msg)
 	if err != nil {
 		log.Printf("PUSH: [marshal error: %v]", err)
@@ -403,49 +779,63 @@ func (pm *PoolMonitor) processPushObject(obj ObjectData) {
 	}
 
 	// Use the same processing functions as polling mode, then log the change.
-	switch objType {
-	case objTypeBody:
-		pm.handleBodyPush(obj, name)
-	case objTypePump:
-		pm.handlePumpPush(obj, name)
-	case objTypeCircuit:
-		pm.handleCircuitPush(obj, name)
-	case objTypeHeater:
-		pm.handleHeaterPush(obj, name)
-	case objTypeCircGrp:
-		pm.handleCircGrpPush(obj)
-	default:
-		pm.handleUnknownPush(obj)
+	if handle, ok := objTypeHandlers[objType]; ok {
+		handle(pm, obj, name)
+		return
 	}
+	pm.handleUnknownPush(obj)
+}
+
+// objTypeHandlers maps each OBJTYP with a dedicated push handler to it. It is
+// the single list of handled types: processPushObject routes by it, and
+// trackUnknownEquipment skips its types, so adding a handler here also stops
+// that type being reported as unknown equipment.
+var objTypeHandlers = map[string]func(pm *PoolMonitor, obj ObjectData, name string){
+	objTypeBody:    (*PoolMonitor).handleBodyPush,
+	objTypePump:    (*PoolMonitor).handlePumpPush,
+	objTypeCircuit: (*PoolMonitor).handleCircuitPush,
+	objTypeHeater:  (*PoolMonitor).handleHeaterPush,
+	objTypeCircGrp: func(pm *PoolMonitor, obj ObjectData, _ string) { pm.handleCircGrpPush(obj) },
 }
 
 func (pm *PoolMonitor) handleBodyPush(obj ObjectData, name string) {
 	referencedHeaters := make(map[string]BodyHeaterInfo)
 	pm.processBodyObject(obj, referencedHeaters)
+	// Drop this body's previous assignment first: a body whose heater was
+	// unassigned must not keep the old heater referenced with stale setpoints.
+	for k, v := range pm.referencedHeaters {
+		if v.BodyObj == obj.ObjName {
+			delete(pm.referencedHeaters, k)
+		}
+	}
 	for k, v := range referencedHeaters {
 		pm.referencedHeaters[k] = v
 	}
-	log.Printf("PUSH: %s temp=%s°F setpoint=%s°F htmode=%s status=%s",
+	logEventf(pushFields(obj, name, keyTEMP, "SETPT", keyHTMODE, keySTATUS),
+		"PUSH: %s temp=%s°F setpoint=%s°F htmode=%s status=%s",
 		name, obj.Params[keyTEMP], obj.Params["SETPT"], obj.Params[keyHTMODE], obj.Params[keySTATUS])
 }
 
 func (pm *PoolMonitor) handlePumpPush(obj ObjectData, name string) {
 	if err := pm.processPumpObject(obj, 0); err != nil {
-		log.Printf("PUSH: %s pump error: %v", name, err)
+		logEventf(append(pushFields(obj, name), "error", err.Error()), "PUSH: %s pump error: %v", name, err)
 	} else {
-		log.Printf("PUSH: %s rpm=%s watts=%s status=%s",
-			name, obj.Params[keyRPM], obj.Params[keyPWR], obj.Params[keySTATUS])
+		watts, _ := intellicenter.PumpWatts(obj.Params)
+		logEventf(append(pushFields(obj, name, keyRPM, keySTATUS), "watts", watts),
+			"PUSH: %s rpm=%s watts=%.0f status=%s",
+			name, obj.Params[keyRPM], watts, obj.Params[keySTATUS])
 	}
 }
 
 func (pm *PoolMonitor) handleCircuitPush(obj ObjectData, name string) {
 	pm.processCircuitObject(obj)
-	log.Printf("PUSH: %s status=%s", name, obj.Params[keySTATUS])
+	logEventf(pushFields(obj, name, keySTATUS), "PUSH: %s status=%s", name, obj.Params[keySTATUS])
 }
 
 func (pm *PoolMonitor) handleHeaterPush(obj ObjectData, name string) {
 	pm.processHeaterObject(obj)
-	log.Printf("PUSH: %s status=%s mode=%s", name, obj.Params[keySTATUS], obj.Params["MODE"])
+	logEventf(pushFields(obj, name, keySTATUS, "MODE"),
+		"PUSH: %s status=%s mode=%s", name, obj.Params[keySTATUS], obj.Params["MODE"])
 }
 
 func (pm *PoolMonitor) handleCircGrpPush(obj ObjectData) {
@@ -455,8 +845,8 @@ func (pm *PoolMonitor) handleCircGrpPush(obj ObjectData) {
 	circuitName := pm.resolveCircuitName(obj.Params[objTypeCircuit])
 	act := obj.Params[keyACT]
 	use := obj.Params[keyUSE]
-	log.Printf("PUSH: CircGrp %s/%s act=%s use=%s",
-		groupName, circuitName, act, use)
+	logEventf(append(pushFields(obj, "", keyACT, keyUSE), fieldGroup, groupName, logFieldCircuit, circuitName),
+		"PUSH: CircGrp %s/%s act=%s use=%s", groupName, circuitName, act, use)
 }
 
 func (pm *PoolMonitor) handleUnknownPush(obj ObjectData) {
@@ -465,20 +855,65 @@ func (pm *PoolMonitor) handleUnknownPush(obj ObjectData) {
 		log.Printf("PUSH: unknown %s: [marshal error: %v]", obj.ObjName, err)
 		return
 	}
-	log.Printf("PUSH: unknown %s: %s", obj.ObjName, string(jsonBytes))
+	logEventf([]any{fieldObjnam, obj.ObjName, "objtyp", obj.Params[keyOBJTYP]},
+		"PUSH: unknown %s: %s", obj.ObjName, string(jsonBytes))
+}
+
+// pushFields returns the structured log fields for a PUSH line: the objnam,
+// the display name when given, and each listed param under its lowercased key.
+func pushFields(obj ObjectData, name string, keys ...string) []any {
+	fields := []any{fieldObjnam, obj.ObjName}
+	if name != "" {
+		fields = append(fields, fieldName, name)
+	}
+	for _, key := range keys {
+		fields = append(fields, strings.ToLower(key), obj.Params[key])
+	}
+	return fields
 }
 
 // applyBodyTemperatures updates body metrics and collects heater assignments from
 // a set of body objects (sourced either from a live query or the engine snapshot).
 func (pm *PoolMonitor) applyBodyTemperatures(objs []ObjectData) {
 	referencedHeaters := make(map[string]BodyHeaterInfo)
+	previousBodies := pm.activeBodies
+	pm.activeBodies = make(map[string]objectSeries, len(objs))
 	for _, obj := range objs {
 		pm.processBodyObject(obj, referencedHeaters)
+		if name := obj.Params[keySNAME]; name != "" {
+			pm.activeBodies[obj.ObjName] = objectSeries{subtype: obj.Params[keySUBTYP], name: name}
+		}
 	}
 	// Store referenced heaters for heater status processing
 	pm.referencedHeaters = referencedHeaters
+	pm.cleanupStaleBodies(previousBodies)
 }
 
+// cleanupStaleBodies deletes the series of bodies that were removed or renamed
+// since the previous refresh, and forgets a removed body's heating status so
+// heater circuits stop following it.
+func (pm *PoolMonitor) cleanupStaleBodies(previous map[string]objectSeries) {
+	for objName, old := range previous {
+		current, ok := pm.activeBodies[objName]
+		if current == old {
+			continue
+		}
+		pm.metrics.poolTemperature.DeleteLabelValues(objName, old.subtype, old.name)
+		pm.metrics.bodyHeatMode.DeleteLabelValues(objName, old.subtype, old.name)
+		pm.metrics.bodyTemperatureError.DeleteLabelValues(objName, old.subtype, old.name)
+		pm.metrics.bodyFiltrationSeconds.DeleteLabelValues(objName, old.subtype, old.name)
+		pm.metrics.poolTurnoversPerDay.DeleteLabelValues(objName, old.subtype, old.name)
+		if !ok {
+			delete(pm.bodyHeatingStatus, pm.bodyKey(objName, old.name))
+		}
+		log.Printf("Cleaned up stale body metric: %s (%s)", old.name, objName)
+	}
+}
+
+// processBodyObject updates one body's metrics. Temperature and heating status
+// never depend on a heater: a body with no HTSRC (or HTSRC "00000") exports them
+// like any other. Only the heater assignment and the setpoint-derived
+// temperature error need an assigned heater.
 func (pm *PoolMonitor) processBodyObject(obj ObjectData, referencedHeaters map[string]BodyHeaterInfo) {
 	name := obj.Params[keySNAME]
 	tempStr := obj.Params[keyTEMP]
@@ -490,8 +925,28 @@ func (pm *PoolMonitor) processBodyObject(obj ObjectData, referencedHeaters map[s
 	hitmpStr := obj.Params[keyHITMP]
 
 	pm.processBodyTemperature(name, tempStr, subtype, status, obj)
-	pm.processBodyHeatingStatus(name, htmodeStr, obj.ObjName)
+	pm.processBodyHeatingStatus(name, htmodeStr, obj.ObjName, subtype)
 	pm.processHeaterAssignment(name, tempStr, htmodeStr, htsrc, lotmpStr, hitmpStr, obj.ObjName, referencedHeaters)
+	pm.processBodyTemperatureError(obj.ObjName, name, subtype, tempStr, lotmpStr, htsrc)
+	if name != "" && status != "" {
+		pm.setEquipmentStatus(obj.ObjName, objTypeBody, subtype, name, equipmentOnOff(status == statusOn))
+	}
+}
+
+// processBodyTemperatureError exports how far a body's water is from its heating
+// setpoint. Only bodies with an assigned heater have a meaningful target, so the
+// series is removed when the heater is unassigned or either value is unusable.
+func (pm *PoolMonitor) processBodyTemperatureError(objName, name, subtype, tempStr, lotmpStr, htsrc string) {
+	if name == "" {
+		return
+	}
+	temp, tempErr := strconv.ParseFloat(tempStr, 64)
+	lotmp, lotmpErr := strconv.ParseFloat(lotmpStr, 64)
+	if htsrc == "" || htsrc == intellicenter.HeatSourceNone || tempErr != nil || lotmpErr != nil {
+		pm.metrics.bodyTemperatureError.DeleteLabelValues(objName, subtype, name)
+		return
+	}
+	pm.metrics.bodyTemperatureError.WithLabelValues(objName, subtype, name).Set(pm.displayTempDelta(temp - lotmp))
 }
 
 func (pm *PoolMonitor) processBodyTemperature(name, tempStr, subtype, status string, obj ObjectData) {
@@ -515,12 +970,12 @@ func (pm *PoolMonitor) processBodyTemperature(name, tempStr, subtype, status str
 	}
 
 	// Store temperature in Fahrenheit as per project standard
-	poolTemperature.WithLabelValues(subtype, name).Set(tempFahrenheit)
+	pm.metrics.poolTemperature.WithLabelValues(obj.ObjName, subtype, name).Set(pm.displayTemp(tempFahrenheit))
 	pm.trackWaterTemp(name, tempFahrenheit, obj)
 	pm.logChangedf("watertemp:"+obj.ObjName, "Updated temperature: %s (%s) = %.1f°F (Status: %s)", name, subtype, tempFahrenheit, status)
 }
 
-func (pm *PoolMonitor) processBodyHeatingStatus(name, htmodeStr, objName string) {
+func (pm *PoolMonitor) processBodyHeatingStatus(name, htmodeStr, objName, subtype string) {
 	if htmodeStr == "" || name == "" {
 		return
 	}
@@ -531,8 +986,11 @@ func (pm *PoolMonitor) processBodyHeatingStatus(name, htmodeStr, objName string)
 		return
 	}
 
+	// The raw mode, for telling gas heating (1) from heat-pump heating (4) and cooling (9)
+	pm.metrics.bodyHeatMode.WithLabelValues(objName, subtype, name).Set(float64(htmode))
+
 	// HTMODE >= 1 means heater is on (1=actively heating, 2=on but not heating)
-	pm.bodyHeatingStatus[strings.ToLower(name)] = htmode >= 1
+	pm.bodyHeatingStatus[pm.bodyKey(objName, name)] = htmode >= 1
 	pm.logChangedf("bodyheat:"+objName, "Updated body heating status: %s (%s) HTMODE=%d [%v]", name, objName, htmode, htmode >= 1)
 }
 
@@ -540,7 +998,7 @@ func (pm *PoolMonitor) processHeaterAssignment(
 	name, tempStr, htmodeStr, htsrc, lotmpStr, hitmpStr, objName string,
 	referencedHeaters map[string]BodyHeaterInfo,
 ) {
-	if htsrc == "" || htsrc == "00000" || name == "" {
+	if htsrc == "" || htsrc == intellicenter.HeatSourceNone || name == "" {
 		return
 	}
 
@@ -569,6 +1027,15 @@ func (pm *PoolMonitor) applyAirTemperature(objs []ObjectData) {
 		subtype := obj.Params[keySUBTYP]
 		status := obj.Params[keySTATUS]
 
+		// Health first: a disconnected probe may send no usable PROBE at all.
+		if name != "" && status != "" && subtype != subtypSolar {
+			connected := 0.0
+			if status == sensorStatusOK {
+				connected = 1
+			}
+			pm.metrics.airSensorConnected.WithLabelValues(obj.ObjName, subtype, name).Set(connected)
+		}
+
 		if tempStr != "" && name != "" {
 			tempFahrenheit, err := strconv.ParseFloat(tempStr, 64)
 			if err != nil {
@@ -576,25 +1043,127 @@ func (pm *PoolMonitor) applyAirTemperature(objs []ObjectData) {
 				continue
 			}
 
+			// Solar probes read the collector, not ambient air: they get their
+			// own gauge so they never overwrite or masquerade as air temperature.
+			if subtype == subtypSolar {
+				pm.metrics.solarTemperature.WithLabelValues(obj.ObjName, subtype, name).Set(pm.displayTemp(tempFahrenheit))
+				pm.logChangedf("solartemp:"+obj.ObjName, "Updated solar temperature: %s (%s) = %.1f°F (Status: %s)", name, subtype, tempFahrenheit, status)
+				continue
+			}
+
 			// Store temperature in Fahrenheit as per project standard
-			airTemperature.WithLabelValues(subtype, name).Set(tempFahrenheit)
+			pm.metrics.airTemperature.WithLabelValues(obj.ObjName, subtype, name).Set(pm.displayTemp(tempFahrenheit))
 			pm.trackAirTemp(tempFahrenheit, obj)
 			pm.logChangedf("airtemp:"+obj.ObjName, "Updated air temperature: %s (%s) = %.1f°F (Status: %s)", name, subtype, tempFahrenheit, status)
 		}
 	}
 }
 
+// applySystemInfo sets intellicenter_vacation_mode from the system object's
+// VACFLO. Any other value (a panel without vacation mode echoes the key name)
+// leaves the gauge at 0.
+func (pm *PoolMonitor) applySystemInfo(objs []ObjectData) {
+	for _, obj := range objs {
+		pm.applyBoardTemperature(obj)
+		pm.applyUnitMismatch(obj)
+		switch obj.Params[keyVACFLO] {
+		case statusOn:
+			pm.metrics.vacationMode.Set(1)
+		case statusDescOff:
+			pm.metrics.vacationMode.Set(0)
+		default:
+			continue
+		}
+		pm.logChangedf("vacation:"+obj.ObjName, "Updated vacation mode: %s", obj.Params[keyVACFLO])
+	}
+}
+
+// applyUnitMismatch tracks the panel's display units. A metric panel already
+// reports Celsius: with --units=c its readings are exported unconverted, but
+// Fahrenheit output would publish Celsius values under _fahrenheit names, so
+// that combination sets the gauge and warns once per occurrence. A panel that
+// doesn't report MODE leaves both alone.
+func (pm *PoolMonitor) applyUnitMismatch(obj ObjectData) {
+	logKey := "unitmismatch:" + obj.ObjName
+	switch obj.Params[keyMODE] {
+	case unitsMetric:
+		pm.panelMetric = true
+		if pm.celsius {
+			pm.metrics.unitMismatch.Set(0)
+			return
+		}
+		pm.metrics.unitMismatch.Set(1)
+		pm.logChangedf(logKey, "Warning: panel reports metric units (MODE=%s) but pentameter exports Fahrenheit; "+
+			"set the panel to English units or use --units=c", unitsMetric)
+	case unitsEnglish:
+		pm.panelMetric = false
+		pm.metrics.unitMismatch.Set(0)
+		delete(pm.lastLogged, logKey) // warn again if it recurs
+	}
+}
+
+// applyBoardTemperature exports the controller's board temperature from the
+// system object param named by --board-temp-key. No documented key carries it
+// and firmwares differ, so it is off unless configured, and a value that isn't
+// a number (a key echo, an empty reading) is skipped rather than exported.
+func (pm *PoolMonitor) applyBoardTemperature(obj ObjectData) {
+	if pm.boardTempKey == "" {
+		return
+	}
+	temp, err := strconv.ParseFloat(obj.Params[pm.boardTempKey], 64)
+	if err != nil {
+		return
+	}
+	pm.metrics.boardTemperature.WithLabelValues().Set(pm.displayTemp(temp))
+	pm.logChangedf("boardtemp:"+obj.ObjName, "Updated board temperature: %.1f°F", temp)
+}
+
 // applyPumpData updates pump metrics from a set of pump objects. responseTime is
 // for logging only (0 when sourced from the engine snapshot rather than a query).
 func (pm *PoolMonitor) applyPumpData(objs []ObjectData, responseTime time.Duration) {
 	// Rebuilt each refresh so circuit status can be gated on whether the pump a
 	// circuit drives is physically running (RPM>0), not just commanded on.
 	pm.pumpRunning = make(map[string]bool, len(objs))
+	pm.pumpFlow = make(map[string]float64, len(objs))
+	previousPumps := pm.activePumps
+	pm.activePumps = make(map[string]string, len(objs))
 	for _, obj := range objs {
+		// A stopped pump can come back without an RPM; it is known, so it still
+		// reads 0 instead of leaving a gap until it first runs.
+		obj = withDefaultParam(obj, keyRPM, "0")
 		if err := pm.processPumpObject(obj, responseTime); err != nil {
 			log.Printf("Failed to process pump object %s: %v", obj.ObjName, err)
 		}
 	}
+	pm.metrics.pumpTotalWatts.Set(totalPumpWatts(objs))
+	pm.cleanupStalePumps(previousPumps)
+}
+
+// cleanupStalePumps deletes the series of pumps that were removed or renamed
+// since the previous refresh, so they don't keep their last reading forever.
+func (pm *PoolMonitor) cleanupStalePumps(previous map[string]string) {
+	for objName, name := range previous {
+		if pm.activePumps[objName] == name {
+			continue
+		}
+		pm.metrics.pumpRPM.DeleteLabelValues(objName, name)
+		pm.metrics.pumpWatts.DeleteLabelValues(objName, name)
+		pm.metrics.pumpGPM.DeleteLabelValues(objName, name)
+		pm.metrics.pumpEfficiencyAnomaly.DeleteLabelValues(objName, name)
+		log.Printf("Cleaned up stale pump metric: %s (%s)", name, objName)
+	}
+}
+
+// totalPumpWatts sums the pumps' power draw, recomputed from scratch each
+// refresh. intellicenter.PumpWatts reads PWR or WATTS, whichever the firmware
+// populates; pumps with neither contribute nothing.
+func totalPumpWatts(objs []ObjectData) float64 {
+	total := 0.0
+	for _, obj := range objs {
+		watts, _ := intellicenter.PumpWatts(obj.Params)
+		total += watts
+	}
+	return total
 }
 
 // applyPumpAssociations rebuilds circuitToPumps from PMPCIRC speed-assignment
@@ -618,6 +1187,42 @@ func (pm *PoolMonitor) applyPumpAssociations(objs []ObjectData) {
 	pm.circuitToPumps = assoc
 }
 
+// cacheCircuitNames records each circuit/group SNAME up front so group names
+// resolve on the first refresh, before applyCircuitStatus caches them itself.
+func (pm *PoolMonitor) cacheCircuitNames(objs []ObjectData) {
+	for _, obj := range objs {
+		if name := obj.Params[keySNAME]; name != "" {
+			pm.circuitNames[obj.ObjName] = name
+		}
+	}
+}
+
+// applyCircuitGroups rebuilds circuitGroups from CIRCGRP member objects: each
+// maps a member circuit/feature (CIRCUIT) to its parent group (PARENT). The
+// group's SNAME becomes the member's "group" metric label so dashboards can
+// aggregate by group/zone. A circuit in several groups gets their sorted names
+// comma-joined, keeping the label deterministic.
+func (pm *PoolMonitor) applyCircuitGroups(objs []ObjectData) {
+	members := make(map[string][]string, len(objs))
+	for _, obj := range objs {
+		circuit := obj.Params[keyCIRCUIT]
+		parent := obj.Params[keyPARENT]
+		if circuit == "" || parent == "" {
+			continue
+		}
+		group := pm.resolveCircuitName(parent)
+		if !slices.Contains(members[circuit], group) {
+			members[circuit] = append(members[circuit], group)
+		}
+	}
+	groups := make(map[string]string, len(members))
+	for circuit, names := range members {
+		slices.Sort(names)
+		groups[circuit] = strings.Join(names, ",")
+	}
+	pm.circuitGroups = groups
+}
+
 // applyPumpDeliveryGate floors a circuit/feature's status to OFF when it drives
 // one or more pumps but none are actually running (RPM>0) — i.e. it was
 // commanded on yet isn't physically delivering (e.g. a pump lost power). A
@@ -638,13 +1243,75 @@ func (pm *PoolMonitor) applyPumpDeliveryGate(objName string, statusValue float64
 	return circuitStatusOff
 }
 
-// applyFreezeProtection sets freezeProtectionActive from the _FEA2 feature's status.
-// objs may be the dedicated _FEA2 query result or the full circuit set (the engine
-// path passes all circuits; only _FEA2 is inspected).
+// applyBodyFiltration accumulates body_filtration_seconds_total for each body.
+// A body is circulating while its STATUS is ON and, when PMPCIRC associates it
+// with pumps, at least one of them is actually running (the same delivery gate
+// circuits use). The interval since the previous sample is credited only if the
+// body was circulating at that sample, so the counter stays monotonic and never
+// over-counts across a stop. Must run after applyPumpData/applyPumpAssociations.
+func (pm *PoolMonitor) applyBodyFiltration(objs []ObjectData) {
+	now := pm.now()
+	for _, obj := range objs {
+		name := obj.Params[keySNAME]
+		if name == "" {
+			continue
+		}
+		running := obj.Params[keySTATUS] == statusOn
+		if running {
+			running = pm.applyPumpDeliveryGate(obj.ObjName, circuitStatusOn) == circuitStatusOn
+		}
+
+		counter := pm.metrics.bodyFiltrationSeconds.WithLabelValues(obj.ObjName, obj.Params[keySUBTYP], name)
+		if prev, ok := pm.filtrationSamples[obj.ObjName]; ok && prev.running {
+			if elapsed := now.Sub(prev.at).Seconds(); elapsed > 0 {
+				counter.Add(elapsed)
+			}
+		}
+		pm.filtrationSamples[obj.ObjName] = runtimeSample{at: now, running: running}
+	}
+}
+
+// applyTurnovers sets pool_turnovers_per_day for each body with a --pool-gallons
+// volume. Flow is the summed GPM of the running pumps PMPCIRC associates with the
+// body. A pump shared by several bodies counts fully toward each one that is
+// circulating, since shared-equipment setups run one body at a time. A body
+// with no pump association has no flow to measure and exports no series. Must
+// run after applyPumpData/applyPumpAssociations.
+func (pm *PoolMonitor) applyTurnovers(objs []ObjectData) {
+	for _, obj := range objs {
+		gallons := pm.bodyGallons[obj.ObjName]
+		name := obj.Params[keySNAME]
+		if gallons <= 0 || name == "" {
+			continue
+		}
+		subtype := obj.Params[keySUBTYP]
+		pumps := pm.circuitToPumps[obj.ObjName]
+		if len(pumps) == 0 {
+			pm.metrics.poolTurnoversPerDay.DeleteLabelValues(obj.ObjName, subtype, name)
+			continue
+		}
+		gpm := 0.0
+		if obj.Params[keySTATUS] == statusOn {
+			for _, pump := range pumps {
+				if pm.pumpRunning[pump] {
+					gpm += pm.pumpFlow[pump]
+				}
+			}
+		}
+		turnovers := gpm * minutesPerDay / gallons
+		pm.metrics.poolTurnoversPerDay.WithLabelValues(obj.ObjName, subtype, name).Set(turnovers)
+		pm.logChangedf("turnovers:"+obj.ObjName, "Updated turnovers: %s (%s) = %.2f/day at %.0f GPM", name, obj.ObjName, turnovers, gpm)
+	}
+}
+
+// applyFreezeProtection sets freezeProtectionActive from the freeze-protection
+// feature's status. objs may be the dedicated query result or the full circuit
+// set (the engine path passes all circuits; only the freeze object is inspected).
 func (pm *PoolMonitor) applyFreezeProtection(objs []ObjectData) {
+	freezeObject := pm.freezeObjectName(objs)
 	pm.freezeProtectionActive = false
 	for _, obj := range objs {
-		if obj.ObjName == objnamFreezeFeat && obj.Params[keySTATUS] == statusOn {
+		if obj.ObjName == freezeObject && obj.Params[keySTATUS] == statusOn {
 			pm.freezeProtectionActive = true
 			pm.logChangedf("freeze", "Freeze protection is ACTIVE")
 			break
@@ -656,34 +1323,86 @@ func (pm *PoolMonitor) applyFreezeProtection(objs []ObjectData) {
 	}
 }
 
+// freezeObjectName picks the object whose STATUS reports freeze protection,
+// which differs across firmware and installations: --freeze-object when set,
+// else the circuit IntelliCenter types as freeze protection (SUBTYP FRZ), and
+// _FEA2 when none is found.
+func (pm *PoolMonitor) freezeObjectName(objs []ObjectData) string {
+	if pm.freezeObject != "" {
+		return pm.freezeObject
+	}
+	detected := ""
+	for _, obj := range objs {
+		if obj.Params[keySUBTYP] == subtypFreeze {
+			detected = obj.ObjName
+			break
+		}
+	}
+	if detected == "" {
+		return objnamFreezeFeat
+	}
+	if detected != objnamFreezeFeat {
+		pm.logChangedf("freezeobject", "Freeze protection read from %s (%s) instead of %s",
+			pm.resolveCircuitName(detected), detected, objnamFreezeFeat)
+	}
+	return detected
+}
+
 // applyCircuitStatus updates circuit + feature metrics from a set of circuit
 // objects, then prunes metric series no longer present (stale cleanup).
 func (pm *PoolMonitor) applyCircuitStatus(objs []ObjectData) {
 	// Save previous keys for stale metric cleanup
 	previousCircuitKeys := pm.activeCircuitKeys
 	previousFeatureKeys := pm.activeFeatureKeys
+	previousMasterKeys := pm.activeMasterKeys
 	pm.activeCircuitKeys = make(map[string]bool)
 	pm.activeFeatureKeys = make(map[string]bool)
+	pm.activeMasterKeys = make(map[string]bool)
 
-	// Update Prometheus metrics
+	// Update Prometheus metrics. A known circuit without a STATUS reads off
+	// rather than going missing until it is first seen on.
 	for _, obj := range objs {
-		pm.processCircuitObject(obj)
+		pm.processCircuitObject(withDefaultParam(obj, keySTATUS, statusDescOff))
 	}
 
 	// Cleanup stale circuit metrics
-	pm.cleanupStaleMetrics(previousCircuitKeys, pm.activeCircuitKeys, circuitStatus, logFieldCircuit)
+	pm.cleanupStaleMetrics(previousCircuitKeys, pm.activeCircuitKeys, pm.metrics.circuitStatus, logFieldCircuit)
+	pm.cleanupStaleMetrics(previousCircuitKeys, pm.activeCircuitKeys, pm.metrics.circuitFreezeProtected, "circuit freeze")
 
 	// Cleanup stale feature metrics
-	pm.cleanupStaleMetrics(previousFeatureKeys, pm.activeFeatureKeys, featureStatus, "feature")
+	pm.cleanupStaleMetrics(previousFeatureKeys, pm.activeFeatureKeys, pm.metrics.featureStatus, "feature")
+	pm.cleanupStaleMetrics(previousFeatureKeys, pm.activeFeatureKeys, pm.metrics.featureFreezeProtected, "feature freeze")
+
+	// Cleanup stale master circuit metrics
+	pm.cleanupStaleMetrics(previousMasterKeys, pm.activeMasterKeys, pm.metrics.masterCircuitStatus, "master circuit")
+}
+
+// withDefaultParam returns obj with key set to value when the panel left it
+// empty. The params are copied, so the caller's map is never mutated.
+func withDefaultParam(obj ObjectData, key, value string) ObjectData {
+	if obj.Params[key] != "" {
+		return obj
+	}
+	params := maps.Clone(obj.Params)
+	if params == nil {
+		params = make(map[string]string, 1)
+	}
+	params[key] = value
+	return ObjectData{ObjName: obj.ObjName, Params: params}
+}
+
+// metricKey joins a series' label values into the key used for stale cleanup.
+func metricKey(labels ...string) string {
+	return strings.Join(labels, "|")
 }
 
 func (pm *PoolMonitor) cleanupStaleMetrics(previous, current map[string]bool, metric *prometheus.GaugeVec, metricType string) {
 	for key := range previous {
 		if !current[key] {
-			// Parse the key back into label values (format: "objnam|name|subtype")
+			// Parse the key back into label values (format: "objnam|name|subtype|group")
 			parts := strings.SplitN(key, "|", metricKeyPartsCount)
 			if len(parts) == metricKeyPartsCount {
-				deleted := metric.DeleteLabelValues(parts[0], parts[1], parts[2])
+				deleted := metric.DeleteLabelValues(parts...)
 				if deleted {
 					log.Printf("Cleaned up stale %s metric: %s (%s)", metricType, parts[1], parts[0])
 				}
@@ -709,13 +1428,35 @@ func (pm *PoolMonitor) processCircuitObject(obj ObjectData) {
 	if strings.HasPrefix(obj.ObjName, "FTR") {
 		pm.processFeatureObject(obj, name, status, subtype, freezeEnabled)
 	} else if pm.isValidCircuit(obj.ObjName, name, subtype) {
-		statusValue := pm.calculateCircuitStatusValue(name, status, obj.ObjName, freezeEnabled)
-		circuitStatus.WithLabelValues(obj.ObjName, name, subtype).Set(statusValue)
-		pm.activeCircuitKeys[obj.ObjName+"|"+name+"|"+subtype] = true
+		rawStatus := pm.calculateCircuitStatusValue(name, status, obj.ObjName, freezeEnabled)
+		statusValue, freeze := pm.encodeStatus(rawStatus)
+		group := pm.circuitGroups[obj.ObjName]
+		pm.metrics.circuitStatus.WithLabelValues(obj.ObjName, name, subtype, group).Set(statusValue)
+		if pm.booleanStatus {
+			pm.metrics.circuitFreezeProtected.WithLabelValues(obj.ObjName, name, subtype, group).Set(freeze)
+		}
+		pm.activeCircuitKeys[metricKey(obj.ObjName, name, subtype, group)] = true
+		if pm.isMasterCircuit(obj.ObjName, subtype) {
+			pm.metrics.masterCircuitStatus.WithLabelValues(obj.ObjName, name, subtype, group).Set(statusValue)
+			pm.activeMasterKeys[metricKey(obj.ObjName, name, subtype, group)] = true
+		}
+		pm.accumulateRuntime(pm.circuitRunSamples, obj.ObjName,
+			pm.metrics.circuitRuntimeSeconds.WithLabelValues(obj.ObjName, name), rawStatus != circuitStatusOff)
+		pm.setEquipmentStatus(obj.ObjName, objTypeCircuit, subtype, name, circuitEquipmentStatus(rawStatus))
 		pm.trackCircuit(name, status, obj)
 	}
 }
 
+// isMasterCircuit reports whether a circuit is a body's master on/off circuit.
+// IntelliCenter classifies those by SUBTYP (POOL/SPA); --master-circuits
+// replaces the detection with an explicit objnam list.
+func (pm *PoolMonitor) isMasterCircuit(objName, subtype string) bool {
+	if pm.masterCircuits != nil {
+		return pm.masterCircuits[objName]
+	}
+	return subtype == subtypPool || subtype == subtypSpa
+}
+
 func (pm *PoolMonitor) isValidCircuit(objName, name, subtype string) bool {
 	// Accept regular circuits (C prefix) and circuit groups (GRP prefix)
 	hasValidPrefix := strings.HasPrefix(objName, "C") || strings.HasPrefix(objName, "GRP")
@@ -752,6 +1493,30 @@ func (pm *PoolMonitor) logSkippedFeature(name, objName, shomnu string) {
 }
 
 func (pm *PoolMonitor) processVisibleFeature(obj ObjectData, name, status, subtype string, freezeEnabled bool) {
+	// A feature backing a heater follows its body's heating like a heater circuit.
+	// Features never had the name heuristic, so only the configuration says so.
+	var statusValue float64
+	if pm.configuredHeaterControl(obj.ObjName) {
+		statusValue = pm.getHeaterCircuitStatus(name, obj.ObjName, freezeEnabled)
+	} else {
+		statusValue = pm.getFeatureStatus(name, status, obj.ObjName, freezeEnabled)
+	}
+
+	// Update Prometheus metric using IntelliCenter's SUBTYP
+	group := pm.circuitGroups[obj.ObjName]
+	exported, freeze := pm.encodeStatus(statusValue)
+	pm.metrics.featureStatus.WithLabelValues(obj.ObjName, name, subtype, group).Set(exported)
+	if pm.booleanStatus {
+		pm.metrics.featureFreezeProtected.WithLabelValues(obj.ObjName, name, subtype, group).Set(freeze)
+	}
+	pm.activeFeatureKeys[metricKey(obj.ObjName, name, subtype, group)] = true
+	pm.accumulateRuntime(pm.circuitRunSamples, obj.ObjName,
+		pm.metrics.featureRuntimeSeconds.WithLabelValues(obj.ObjName, name), statusValue != circuitStatusOff)
+	pm.setEquipmentStatus(obj.ObjName, objTypeCircuit, subtype, name, circuitEquipmentStatus(statusValue))
+	pm.trackFeature(name, status)
+}
+
+func (pm *PoolMonitor) getFeatureStatus(name, status, objName string, freezeEnabled bool) float64 {
 	// Calculate feature status value with freeze protection support
 	statusValue := circuitStatusOff
 	statusDesc := statusDescOff
@@ -768,31 +1533,62 @@ func (pm *PoolMonitor) processVisibleFeature(obj ObjectData, name, status, subty
 	}
 
 	// Floor to OFF if commanded on but the pump(s) this feature drives aren't running.
-	if gated := pm.applyPumpDeliveryGate(obj.ObjName, statusValue); gated != statusValue {
+	if gated := pm.applyPumpDeliveryGate(objName, statusValue); gated != statusValue {
 		statusValue = gated
 		statusDesc = statusDescPumpIdle
 	}
 
-	// Update Prometheus metric using IntelliCenter's SUBTYP
-	featureStatus.WithLabelValues(obj.ObjName, name, subtype).Set(statusValue)
-	pm.activeFeatureKeys[obj.ObjName+"|"+name+"|"+subtype] = true
-	pm.trackFeature(name, status)
+	pm.logChangedf("feature:"+objName, "Updated feature status: %s (%s) = %s [%.0f]", name, objName, statusDesc, statusValue)
 
-	pm.logChangedf("feature:"+obj.ObjName, "Updated feature status: %s (%s) = %s [%.0f]", name, obj.ObjName, statusDesc, statusValue)
+	return statusValue
 }
 
 func (pm *PoolMonitor) calculateCircuitStatusValue(name, status, objName string, freezeEnabled bool) float64 {
-	isHeaterCircuit := strings.Contains(strings.ToLower(name), "heat")
-
-	if isHeaterCircuit {
+	if pm.isHeaterControl(objName, name) {
 		return pm.getHeaterCircuitStatus(name, objName, freezeEnabled)
 	}
 
 	return pm.getRegularCircuitStatus(name, status, objName, freezeEnabled)
 }
 
+// isHeaterControl reports whether a circuit drives a heater, so its status
+// follows its body's heating rather than its own STATUS. Once the configuration
+// graph lists the circuit, only the graph decides (see configuredHeaterControl);
+// until then, a name containing "heat" stands in.
+func (pm *PoolMonitor) isHeaterControl(objName, name string) bool {
+	if _, ok := pm.configObjects[objName]; ok {
+		return pm.configuredHeaterControl(objName)
+	}
+	return strings.Contains(strings.ToLower(name), "heat")
+}
+
+// configuredHeaterControl reports whether the configuration graph nests
+// objName under a HEATER object, which is how IntelliCenter ties a circuit or
+// feature to the heater it controls.
+func (pm *PoolMonitor) configuredHeaterControl(objName string) bool {
+	parent := pm.configObjects[objName].Parent
+	return parent != "" && pm.configObjects[parent].ObjType == objTypeHeater
+}
+
+// configuredHeaterBody returns the bodyHeatingStatus key of the body whose
+// HTSRC names the heater objName is nested under, if the configuration graph
+// and the bodies say so.
+func (pm *PoolMonitor) configuredHeaterBody(objName string) (string, bool) {
+	if !pm.configuredHeaterControl(objName) {
+		return "", false
+	}
+	info, ok := pm.referencedHeaters[pm.configObjects[objName].Parent]
+	if !ok {
+		return "", false
+	}
+	return pm.bodyKey(info.BodyObj, info.BodyName), true
+}
+
 func (pm *PoolMonitor) getHeaterCircuitStatus(name, objName string, freezeEnabled bool) float64 {
-	bodyName := pm.getBodyNameFromCircuit(name)
+	bodyName, ok := pm.configuredHeaterBody(objName)
+	if !ok {
+		bodyName = pm.getBodyNameFromCircuit(name)
+	}
 	statusValue := circuitStatusOff
 	statusDesc := statusDescOff
 
@@ -819,8 +1615,29 @@ func (pm *PoolMonitor) getHeaterCircuitStatus(name, objName string, freezeEnable
 	return statusValue
 }
 
+// bodyKey is the bodyHeatingStatus key for a body: its declared key when
+// --bodies names its objnam, otherwise its lowercased SNAME.
+func (pm *PoolMonitor) bodyKey(objName, name string) string {
+	if key, ok := pm.declaredBodies[objName]; ok {
+		return key
+	}
+	return strings.ToLower(name)
+}
+
+// getBodyNameFromCircuit maps a heater circuit's name to the body it heats.
+// Declared body keys are tried first (longest match wins, so "therapy pool"
+// beats "pool"); the pool/spa name inference is the fallback.
 func (pm *PoolMonitor) getBodyNameFromCircuit(name string) string {
 	lowerName := strings.ToLower(name)
+	best := ""
+	for _, key := range pm.declaredBodies {
+		if len(key) > len(best) && strings.Contains(lowerName, key) {
+			best = key
+		}
+	}
+	if best != "" {
+		return best
+	}
 	if strings.Contains(lowerName, bodyNameSpa) {
 		return bodyNameSpa
 	}
@@ -857,8 +1674,41 @@ func (pm *PoolMonitor) getRegularCircuitStatus(name, status, objName string, fre
 
 // applyThermalStatus updates thermal (heater) metrics from a set of heater objects.
 func (pm *PoolMonitor) applyThermalStatus(objs []ObjectData) {
+	previousHeaters := pm.activeHeaters
+	pm.activeHeaters = make(map[string]objectSeries, len(objs))
 	for _, obj := range objs {
 		pm.processHeaterObject(obj)
+		if name, subtype := obj.Params[keySNAME], obj.Params[keySUBTYP]; name != "" && subtype != "" {
+			pm.activeHeaters[obj.ObjName] = objectSeries{subtype: subtype, name: name}
+		}
+	}
+	pm.cleanupStaleHeaters(previousHeaters)
+}
+
+// cleanupStaleHeaters deletes the thermal series of heaters that were removed
+// or renamed since the previous refresh, so they don't keep their last reading
+// forever. heater_active is keyed by source rather than subtype, so both of
+// its series go.
+func (pm *PoolMonitor) cleanupStaleHeaters(previous map[string]objectSeries) {
+	for objName, old := range previous {
+		current, ok := pm.activeHeaters[objName]
+		if current == old {
+			continue
+		}
+		pm.metrics.thermalStatus.DeleteLabelValues(objName, old.name, old.subtype)
+		pm.metrics.thermalLowSetpoint.DeleteLabelValues(objName, old.name, old.subtype)
+		pm.metrics.thermalHighSetpoint.DeleteLabelValues(objName, old.name, old.subtype)
+		if current.name != old.name { // a subtype change alone keeps these
+			pm.metrics.thermalStatusMismatch.DeleteLabelValues(objName, old.name)
+			pm.metrics.heaterActive.DeleteLabelValues(objName, old.name, sourceHeater)
+			pm.metrics.heaterActive.DeleteLabelValues(objName, old.name, sourceHeatPump)
+			pm.metrics.heaterHeatingSeconds.DeleteLabelValues(objName, old.name)
+			pm.metrics.heaterCycles.DeleteLabelValues(objName, old.name)
+		}
+		if !ok {
+			delete(pm.heatingSamples, objName)
+		}
+		log.Printf("Cleaned up stale heater metric: %s (%s)", old.name, objName)
 	}
 }
 
@@ -914,8 +1764,11 @@ func (pm *PoolMonitor) processHeaterObject(obj ObjectData) {
 	}
 
 	// Update Prometheus metric
-	thermalStatus.WithLabelValues(obj.ObjName, name, subtype).Set(float64(heaterStatusValue))
+	pm.metrics.thermalStatus.WithLabelValues(obj.ObjName, name, subtype).Set(float64(heaterStatusValue))
+	pm.accumulateHeating(obj.ObjName, name, heaterStatusValue)
+	pm.setEquipmentStatus(obj.ObjName, objTypeHeater, subtype, name, thermalEquipmentStatus(heaterStatusValue))
 	pm.trackThermal(name, heaterStatusValue, obj)
+	pm.updateThermalMismatch(obj.ObjName, name, status, isReferenced, heaterStatusValue)
 
 	// Handle temperature setpoints
 	pm.updateThermalSetpoints(obj.ObjName, name, subtype, isReferenced, &bodyInfo, heaterStatusValue)
@@ -924,21 +1777,101 @@ func (pm *PoolMonitor) processHeaterObject(obj ObjectData) {
 		name, obj.ObjName, heaterStatusValue, statusDescription)
 }
 
+// accumulateHeating credits heater_heating_seconds_total with the interval
+// since the heater's previous sample when it was heating or cooling at that
+// sample, the same scheme as body filtration, so the counter stays monotonic and
+// a stop is never over-counted. A sample that finds the heater heating after one
+// that found it off or idle counts a heater_cycles_total start; the first sample
+// doesn't, since when that run began is unknown.
+func (pm *PoolMonitor) accumulateHeating(objName, name string, status int) {
+	cycles := pm.metrics.heaterCycles.WithLabelValues(objName, name)
+	if prev, ok := pm.heatingSamples[objName]; ok && !prev.running && status == thermalStatusHeating {
+		cycles.Inc()
+	}
+	working := status == thermalStatusHeating || status == thermalStatusCooling
+	pm.accumulateRuntime(pm.heatingSamples, objName, pm.metrics.heaterHeatingSeconds.WithLabelValues(objName, name), working)
+}
+
+// updateThermalMismatch flags a referenced heater whose own STATUS contradicts
+// the thermal status derived from its body's HTMODE. A heater's STATUS is its
+// installed/available flag and reads ON whether or not it is firing, so ON with
+// HTMODE 0 is normal; the disagreement worth reporting is the body's assigned
+// heater reading STATUS=OFF while HTMODE says it is doing work. Non-referenced
+// heaters have no HTMODE to compare and read 0; combo objects echo the key name
+// as STATUS and are skipped. Each mismatch is logged once, when it starts.
+func (pm *PoolMonitor) updateThermalMismatch(objName, name, status string, isReferenced bool, heaterStatusValue int) {
+	if status != statusOn && status != statusDescOff {
+		return
+	}
+	working := heaterStatusValue == thermalStatusHeating || heaterStatusValue == thermalStatusCooling
+	logKey := "thermalmismatch:" + objName
+	if !isReferenced || status != statusDescOff || !working {
+		pm.metrics.thermalStatusMismatch.WithLabelValues(objName, name).Set(0)
+		delete(pm.lastLogged, logKey) // log again if it recurs
+		return
+	}
+	pm.metrics.thermalStatusMismatch.WithLabelValues(objName, name).Set(1)
+	pm.logChangedf(logKey, "Thermal status mismatch: %s (%s) STATUS=OFF but derived status is %s; please report this",
+		name, objName, pm.getStatusDescription(heaterStatusValue))
+}
+
+// applyHeaterActive publishes which heat source is actually firing. A body's
+// HTSRC may name a combo ("Preferred") object, so the assignment alone can't
+// say whether the heat pump or the gas heater is doing the work; HTMODE can:
+// 4 (heating) and 9 (cooling) are the heat pump, 1 is a conventional heater.
+// Each real heater gets a series: 1 when a body it serves (its BODY list, or
+// the body's HTSRC) is firing with that heater's kind of source, else 0.
+func (pm *PoolMonitor) applyHeaterActive(objs []ObjectData) {
+	firing := make(map[string]string) // body objnam -> source doing the work
+	assigned := make(map[string][]string)
+	for heater, info := range pm.referencedHeaters {
+		assigned[heater] = append(assigned[heater], info.BodyObj)
+		switch info.HTMode {
+		case htModeHeatPumpHeating, htModeHeatPumpCooling:
+			firing[info.BodyObj] = sourceHeatPump
+		case htModeHeating:
+			firing[info.BodyObj] = sourceHeater
+		}
+	}
+
+	for _, obj := range objs {
+		name := obj.Params[keySNAME]
+		status := obj.Params[keySTATUS]
+		// Combo objects echo the key name as STATUS; only real heaters fire.
+		if name == "" || (status != statusOn && status != statusDescOff) {
+			continue
+		}
+		source := sourceHeater
+		if obj.Params[keySUBTYP] == subtypUltra || obj.Params[keyCOOL] == statusOn {
+			source = sourceHeatPump
+		}
+
+		active := 0.0
+		for _, body := range append(strings.Fields(obj.Params[keyBODY]), assigned[obj.ObjName]...) {
+			if firing[body] == source {
+				active = 1
+				break
+			}
+		}
+		pm.metrics.heaterActive.WithLabelValues(obj.ObjName, name, source).Set(active)
+	}
+}
+
 func (pm *PoolMonitor) updateThermalSetpoints(objName, name, subtype string, isReferenced bool, bodyInfo *BodyHeaterInfo, heaterStatusValue int) {
 	// Always show heatpoint for referenced heaters
 	if isReferenced {
-		thermalLowSetpoint.WithLabelValues(objName, name, subtype).Set(bodyInfo.LoTemp)
+		pm.metrics.thermalLowSetpoint.WithLabelValues(objName, name, subtype).Set(pm.displayTemp(bodyInfo.LoTemp))
 	} else {
 		// Remove low setpoint metric when not referenced
-		thermalLowSetpoint.DeleteLabelValues(objName, name, subtype)
+		pm.metrics.thermalLowSetpoint.DeleteLabelValues(objName, name, subtype)
 	}
 
 	// Only show coolpoint if realistic temperature (< 100°F) and relevant state
 	if isReferenced && bodyInfo.HiTemp < 100 && (heaterStatusValue == 3 || heaterStatusValue == 2) { // Cooling or Idle with realistic setpoint
-		thermalHighSetpoint.WithLabelValues(objName, name, subtype).Set(bodyInfo.HiTemp)
+		pm.metrics.thermalHighSetpoint.WithLabelValues(objName, name, subtype).Set(pm.displayTemp(bodyInfo.HiTemp))
 	} else {
 		// Remove high setpoint metric when >= 100°F, not cooling/idle, or not referenced
-		thermalHighSetpoint.DeleteLabelValues(objName, name, subtype)
+		pm.metrics.thermalHighSetpoint.DeleteLabelValues(objName, name, subtype)
 	}
 }
 
@@ -1014,20 +1947,52 @@ func (pm *PoolMonitor) processPumpObject(obj ObjectData, responseTime time.Durat
 		return fmt.Errorf("failed to parse RPM %s for pump %s: %w", rpmStr, name, err)
 	}
 
-	pumpRPM.WithLabelValues(obj.ObjName, name).Set(rpm)
+	pm.metrics.pumpRPM.WithLabelValues(obj.ObjName, name).Set(rpm)
+	pm.activePumps[obj.ObjName] = name
+	// Polls and pushes share this path, so pump_watts reads the same whichever
+	// key the firmware sends. A push without either key leaves the last value.
+	if watts, ok := intellicenter.PumpWatts(obj.Params); ok {
+		pm.metrics.pumpWatts.WithLabelValues(obj.ObjName, name).Set(watts)
+	}
 	pm.pumpRunning[obj.ObjName] = rpm > 0
+	pm.setEquipmentStatus(obj.ObjName, objTypePump, obj.Params[keySUBTYP], name, equipmentOnOff(rpm > 0))
+	pm.processPumpFlow(obj, name)
 	pm.trackPumpRPM(name, rpm, obj)
 	pm.logPumpUpdate(name, obj.ObjName, rpm, status, responseTime)
 	return nil
 }
 
+// processPumpFlow records a pump's GPM for turnovers and exports pump_gpm.
+// A GPM of 0, empty or unparseable (a stopped pump, or one with no flow
+// reading) deletes the series so a stale flow doesn't linger. A push without
+// GPM leaves both untouched.
+func (pm *PoolMonitor) processPumpFlow(obj ObjectData, name string) {
+	raw, ok := obj.Params[keyGPM]
+	if !ok {
+		return
+	}
+	gpm, err := strconv.ParseFloat(raw, 64)
+	if err != nil || gpm <= 0 {
+		pm.pumpFlow[obj.ObjName] = 0
+		pm.metrics.pumpGPM.DeleteLabelValues(obj.ObjName, name)
+		return
+	}
+	pm.pumpFlow[obj.ObjName] = gpm
+	pm.metrics.pumpGPM.WithLabelValues(obj.ObjName, name).Set(gpm)
+}
+
 func (pm *PoolMonitor) logPumpUpdate(name, objName string, rpm float64, status string, responseTime time.Duration) {
 	pm.logChangedf("pump:"+objName, "Updated pump RPM: %s (%s) = %.0f RPM (Status: %s) [ResponseTime: %v]", name, objName, rpm, status, responseTime)
 }
 
+// updateRefreshTimestamp records a successful refresh. lastRefresh is read at
+// scrape time by dataAgeCollector, so it is written under pm.mu.
 func (pm *PoolMonitor) updateRefreshTimestamp() {
-	pm.lastRefresh = time.Now()
-	lastRefreshTimestamp.Set(float64(pm.lastRefresh.Unix()))
+	now := pm.now()
+	pm.mu.Lock()
+	pm.lastRefresh = now
+	pm.mu.Unlock()
+	pm.metrics.lastRefreshTimestamp.Set(float64(now.Unix()))
 }
 
 func getEnvOrDefault(envVar, defaultValue string) string {
@@ -1055,12 +2020,13 @@ func (pm *PoolMonitor) logChangedf(key, format string, v ...interface{}) {
 		return
 	}
 	pm.lastLogged[key] = msg
-	log.Print(msg)
+	logEventf(changeFields(key), "%s", msg)
 }
 
 func (pm *PoolMonitor) initializeState() {
 	pm.previousState = &EquipmentState{
 		WaterTemps:      make(map[string]float64),
+		AirTemps:        make(map[string]float64),
 		PumpRPMs:        make(map[string]float64),
 		Circuits:        make(map[string]string),
 		Thermals:        make(map[string]int),
@@ -1072,12 +2038,25 @@ func (pm *PoolMonitor) initializeState() {
 	}
 }
 
-// logPollChangef logs a change and increments the change counter.
-func (pm *PoolMonitor) logPollChangef(format string, args ...interface{}) {
-	log.Printf("POLL: "+format, args...)
+// shouldLogDetection reports whether a first-seen object's "detected" line
+// should be logged: only on the initial poll of a baseline, and never when
+// quietDetection is set (long-running operators who only want change lines).
+func (pm *PoolMonitor) shouldLogDetection() bool {
+	return !pm.initialPollDone && !pm.quietDetection
+}
+
+// logPollChangef logs a change, with fields as structured log fields, and
+// increments the change counter.
+func (pm *PoolMonitor) logPollChangef(fields []any, format string, args ...interface{}) {
+	logEventf(fields, "POLL: "+format, args...)
 	pm.previousState.PollChangeCount++
 }
 
+// pollChangeFields returns the structured log fields for a POLL change line.
+func pollChangeFields(objnam, name string, prev, value any) []any {
+	return []any{fieldObjnam, objnam, fieldName, name, "previous", prev, "value", value}
+}
+
 // trackNumericValue is a generic helper for tracking numeric values (temps, RPM).
 // It handles the common pattern of detect/change logging with raw JSON output.
 func (pm *PoolMonitor) trackNumericValue(
@@ -1090,12 +2069,12 @@ func (pm *PoolMonitor) trackNumericValue(
 ) {
 	prev, exists := valueMap[name]
 	if !exists {
-		if !pm.initialPollDone {
+		if pm.shouldLogDetection() {
 			log.Printf(detectFmt, name, value)
 			pm.outputRawObjectData(obj)
 		}
 	} else if prev != value {
-		pm.logPollChangef(changeFmt, name, prev, value)
+		pm.logPollChangef(pollChangeFields(obj.ObjName, name, prev, value), changeFmt, name, prev, value)
 		pm.outputRawObjectData(obj)
 	}
 	valueMap[name] = value
@@ -1121,17 +2100,25 @@ func (pm *PoolMonitor) trackAirTemp(temp float64, obj ObjectData) {
 		pm.initializeState()
 	}
 
-	if pm.previousState.AirTemp == 0 {
-		// First time seeing air temp - only log on initial poll
-		if !pm.initialPollDone {
-			log.Printf("POLL: Air temperature detected: %.1f°F", temp)
+	// Keyed by objnam, like the air_temperature gauge, so panels with several
+	// air sensors track each one instead of whichever updated last.
+	name := obj.Params[keySNAME]
+	if name == "" {
+		name = obj.ObjName
+	}
+	prev, exists := pm.previousState.AirTemps[obj.ObjName]
+	if !exists {
+		// First time seeing this sensor - only log on initial poll
+		if pm.shouldLogDetection() {
+			log.Printf("POLL: Air temperature detected: %s %.1f°F", name, temp)
 			pm.outputRawObjectData(obj)
 		}
-	} else if pm.previousState.AirTemp != temp {
-		pm.logPollChangef("Air temperature changed: %.1f°F → %.1f°F", pm.previousState.AirTemp, temp)
+	} else if prev != temp {
+		pm.logPollChangef(pollChangeFields(obj.ObjName, name, prev, temp),
+			"Air temperature changed: %s %.1f°F → %.1f°F", name, prev, temp)
 		pm.outputRawObjectData(obj)
 	}
-	pm.previousState.AirTemp = temp
+	pm.previousState.AirTemps[obj.ObjName] = temp
 }
 
 func (pm *PoolMonitor) trackPumpRPM(name string, rpm float64, obj ObjectData) {
@@ -1157,12 +2144,12 @@ func (pm *PoolMonitor) trackCircuit(name, status string, obj ObjectData) {
 	prevStatus, exists := pm.previousState.Circuits[name]
 	if !exists {
 		// First time seeing this circuit - only log on initial poll
-		if !pm.initialPollDone {
+		if pm.shouldLogDetection() {
 			log.Printf("POLL: %s detected: %s", name, status)
 			pm.outputRawObjectData(obj)
 		}
 	} else if prevStatus != status {
-		pm.logPollChangef("%s turned %s", name, status)
+		pm.logPollChangef(pollChangeFields(obj.ObjName, name, prevStatus, status), "%s turned %s", name, status)
 		pm.outputRawObjectData(obj)
 	}
 	pm.previousState.Circuits[name] = status
@@ -1179,12 +2166,12 @@ func (pm *PoolMonitor) trackThermal(name string, status int, obj ObjectData) {
 	prevStatus, exists := pm.previousState.Thermals[name]
 	if !exists {
 		// First time seeing this thermal equipment - only log on initial poll
-		if !pm.initialPollDone {
+		if pm.shouldLogDetection() {
 			log.Printf("POLL: %s detected: %s", name, pm.getStatusDescription(status))
 			pm.outputRawObjectData(obj)
 		}
 	} else if prevStatus != status {
-		pm.logPollChangef("%s status changed: %s → %s", name,
+		pm.logPollChangef(pollChangeFields(obj.ObjName, name, prevStatus, status), "%s status changed: %s → %s", name,
 			pm.getStatusDescription(prevStatus), pm.getStatusDescription(status))
 		pm.outputRawObjectData(obj)
 	}
@@ -1202,11 +2189,11 @@ func (pm *PoolMonitor) trackFeature(name, status string) {
 	prevStatus, exists := pm.previousState.Features[name]
 	if !exists {
 		// First time seeing this feature - only log on initial poll
-		if !pm.initialPollDone {
+		if pm.shouldLogDetection() {
 			log.Printf("POLL: %s detected: %s", name, status)
 		}
 	} else if prevStatus != status {
-		pm.logPollChangef("%s turned %s", name, status)
+		pm.logPollChangef([]any{fieldName, name, "previous", prevStatus, "value", status}, "%s turned %s", name, status)
 	}
 	pm.previousState.Features[name] = status
 }
@@ -1236,7 +2223,7 @@ func (pm *PoolMonitor) trackCircGrp(obj ObjectData) {
 
 	if !exists {
 		// First time seeing this circuit group member - only log on initial poll
-		if !pm.initialPollDone {
+		if pm.shouldLogDetection() {
 			log.Printf("POLL: CircGrp %s/%s detected: act=%s use=%s",
 				groupName, circuitName, newState.Active, newState.Use)
 		}
@@ -1250,16 +2237,21 @@ func (pm *PoolMonitor) trackCircGrp(obj ObjectData) {
 	// Log what changed
 	changes := pm.buildCircGrpChanges(prevState, newState)
 	if len(changes) > 0 {
-		pm.logPollChangef("CircGrp %s/%s changed: %s",
+		pm.logPollChangef([]any{fieldObjnam, objName, fieldGroup, groupName, logFieldCircuit, circuitName},
+			"CircGrp %s/%s changed: %s",
 			groupName, circuitName, strings.Join(changes, " "))
 	}
 }
 
-// resolveCircuitName returns the SNAME for a circuit/group ID, or the ID itself if not found.
+// resolveCircuitName returns the SNAME for a circuit/group ID, falling back to
+// the GetConfiguration name, or the ID itself if neither knows it.
 func (pm *PoolMonitor) resolveCircuitName(objID string) string {
 	if name, ok := pm.circuitNames[objID]; ok && name != "" {
 		return name
 	}
+	if name := pm.configObjects[objID].Name; name != "" {
+		return name
+	}
 	return objID
 }
 
@@ -1309,12 +2301,9 @@ func (pm *PoolMonitor) trackUnknownEquipment(obj ObjectData) {
 	status := obj.Params[keySTATUS]
 	subtype := obj.Params[keySUBTYP]
 
-	// Skip if already handled by specific equipment types
-	switch objType {
-	case objTypeBody, objTypePump, objTypeCircuit, objTypeHeater, objTypeCircGrp:
-		return // Already tracked by specific handlers
-	case "":
-		return // No object type, skip
+	// Skip types with dedicated handlers, and objects with no type
+	if _, handled := objTypeHandlers[objType]; handled || objType == "" {
+		return
 	}
 
 	// Skip internal/system objects
@@ -1333,7 +2322,7 @@ func (pm *PoolMonitor) trackUnknownEquipment(obj ObjectData) {
 	// Log equipment changes with appropriate format
 	if !exists {
 		// Only log on initial poll
-		if !pm.initialPollDone {
+		if pm.shouldLogDetection() {
 			pm.logUnknownEquipmentDetected(name, obj.ObjName, objType, status)
 		}
 	} else if prevValue != trackingValue {
@@ -1343,7 +2332,18 @@ func (pm *PoolMonitor) trackUnknownEquipment(obj ObjectData) {
 	pm.previousState.UnknownEquip[obj.ObjName] = trackingValue
 }
 
+// unknownEquipmentName returns the live SNAME for an unknown object, falling
+// back to its GetConfiguration name so reports about unsupported equipment
+// (e.g. a VALVE) carry a human name even when the poll didn't return one.
+func (pm *PoolMonitor) unknownEquipmentName(name, objName string) string {
+	if name != "" {
+		return name
+	}
+	return pm.configObjects[objName].Name
+}
+
 func (pm *PoolMonitor) logUnknownEquipmentDetected(name, objName, objType, status string) {
+	name = pm.unknownEquipmentName(name, objName)
 	if name != "" {
 		log.Printf("POLL: Unknown equipment detected - %s (%s) type=%s status=%s", name, objName, objType, status)
 		return
@@ -1352,6 +2352,7 @@ func (pm *PoolMonitor) logUnknownEquipmentDetected(name, objName, objType, statu
 }
 
 func (pm *PoolMonitor) logUnknownEquipmentChanged(name, objName, prevValue, trackingValue string) {
+	name = pm.unknownEquipmentName(name, objName)
 	if name != "" {
 		log.Printf("POLL: Unknown equipment changed - %s (%s) %s → %s", name, objName, prevValue, trackingValue)
 		return
@@ -1359,28 +2360,117 @@ func (pm *PoolMonitor) logUnknownEquipmentChanged(name, objName, prevValue, trac
 	log.Printf("POLL: Unknown equipment changed - %s %s → %s", objName, prevValue, trackingValue)
 }
 
-func createMetricsHandler(registry *prometheus.Registry, _ *PoolMonitor) http.Handler {
-	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+// createMetricsHandler serves the registry. promhttp negotiates compression
+// from the scraper's Accept-Encoding (gzip for Prometheus), which --no-compression
+// turns off so responses can be read raw while debugging.
+func createMetricsHandler(registry *prometheus.Registry, _ *PoolMonitor, cfg *appConfig) http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{DisableCompression: cfg.noCompression})
 }
 
 type appConfig struct {
 	intelliCenterIP   string
+	controllers       []string // every --ic-ip address when several are given (metrics mode); nil for one
 	intelliCenterPort string
-	httpPort          string // port the HTTP /metrics server binds, in every mode
+	httpPort          string // port the HTTP /metrics server binds, in every mode unless --no-metrics
+	metricsPath       string // HTTP path of the Prometheus endpoint (--metrics-path)
+	healthPath        string // HTTP path of the health check (--health-path)
+	noCompression     bool   // serve metrics uncompressed even when gzip is accepted (--no-compression)
+	metricPrefix      string // prepended to every metric name (--metric-prefix)
 	listenMode        bool
+	noMetrics         bool // listen mode: skip the /metrics server (--no-metrics)
 	homebridge        bool
+	once              bool // scrape once, write metrics to stdout and exit (--once)
+	dryRun            bool // scan once, list the equipment found and exit (--dry-run)
 	autoDiscover      bool // no static IP given → (re)discover via mDNS
 	pollInterval      time.Duration
+	pollIntervals     map[intellicenter.Kind]time.Duration
+	reconnectGrace    time.Duration      // listen mode: keep the baseline across reconnects shorter than this
+	quietDetection    bool               // listen mode: suppress "detected" inventory lines
+	lockTiming        bool               // debug: record monitor-lock waits
+	initialTimeout    time.Duration      // per-response timeout during a session's baseline
+	bodies            map[string]string  // declared body objnam -> heating-status key (--bodies)
+	queryPacing       time.Duration      // delay between a scan's sub-queries (--query-pacing)
+	maxUnsolicited    int                // messages read awaiting a response before failing; 0 = read timeout only (--max-unsolicited)
+	pingFailures      int                // consecutive failed push-connection pings before reconnecting (--ping-failures)
+	batchQueries      bool               // read every equipment type in one GetParamList (--batch-queries)
+	pumpAnomaly       int                // pump efficiency anomaly threshold percent; 0 = disabled
+	queryTimeout      time.Duration      // steady-state per-response timeout; 0 = engine default (--profile only)
+	pushQueue         int                // pushes buffered before dropping; 0 = engine default (--profile only)
+	enumMap           enumMap            // OBJTYP.KEY value tables exported as enum_value (--enum-map)
+	masterCircuits    map[string]bool    // master circuit objnams (--master-circuits); nil = detect by SUBTYP
+	boardTempKey      string             // system object param holding the board temperature (--board-temp-key)
+	circuitTimerKey   string             // circuit param holding the remaining run time (--circuit-timer-key)
+	chemFlowKey       string             // CHEM param holding the flow switch state (--chem-flow-key)
+	freezeObject      string             // objnam reporting freeze protection (--freeze-object); "" = detect
+	discoverHostname  string             // mDNS name queried for the panel (--discover-hostname)
+	rediscoverAfter   int                // consecutive connect/session failures before rediscovering (--rediscovery-threshold)
+	bodyGallons       map[string]float64 // body objnam -> volume in gallons (--pool-gallons)
+	watchdogTimeout   time.Duration      // continuous scan failure before exiting; 0 = disabled (--watchdog-timeout)
+	startupTimeout    time.Duration      // wait for the first successful scan before exiting; 0 = keep trying (--startup-timeout)
+	shutdownGrace     time.Duration      // let a poll in flight at shutdown finish for this long (--shutdown-grace)
+	debugAddr         string             // host:port for the /debug/pprof listener; "" = disabled (--debug-addr)
+	debugEndpoint     bool               // serve /debug/state on the metrics port (--debug-endpoint)
+	pushgatewayURL    string             // Pushgateway to push to after each successful scan; "" = disabled (--pushgateway-url)
+	pushgatewayJob    string             // job grouping label for pushes (--pushgateway-job)
+	pushgatewayInst   string             // instance grouping label for pushes (--pushgateway-instance)
+	logFormat         string             // log output: text or json (--log-format)
+	statusEncoding    string             // circuit/feature status scheme: tristate or boolean (--status-encoding)
+	tempUnits         string             // temperature output units: f or c (--units)
+	schedules         bool               // fetch SCHED objects for circuit_next_run_seconds (--schedules)
+	alerts            bool               // fetch ALERT objects for the alert metrics (--alerts)
+	equipmentStatus   bool               // export intellicenter_equipment_status (--equipment-status)
 }
 
 type commandLineFlags struct {
 	intelliCenterIP   *string
 	intelliCenterPort *string
 	httpPort          *string
+	metricsPath       *string
+	healthPath        *string
+	noCompression     *bool
+	metricPrefix      *string
 	metrics           *bool
 	listenMode        *bool
+	noMetrics         *bool
 	homebridge        *bool
+	once              *bool
+	dryRun            *bool
 	pollInterval      *int
+	pollIntervals     *string
+	reconnectGrace    *int
+	quietDetection    *bool
+	lockTiming        *bool
+	initialTimeout    *int
+	bodies            *string
+	queryPacing       *int
+	maxUnsolicited    *int
+	pingFailures      *int
+	batchQueries      *bool
+	pumpAnomaly       *int
+	profile           *string
+	enumMap           *string
+	masterCircuits    *string
+	boardTempKey      *string
+	circuitTimerKey   *string
+	chemFlowKey       *string
+	freezeObject      *string
+	discoverHostname  *string
+	rediscoverAfter   *int
+	poolGallons       *string
+	watchdogTimeout   *int
+	startupTimeout    *int
+	shutdownGrace     *int
+	debugAddr         *string
+	debugEndpoint     *bool
+	pushgatewayURL    *string
+	pushgatewayJob    *string
+	pushgatewayInst   *string
+	logFormat         *string
+	statusEncoding    *string
+	tempUnits         *string
+	schedules         *bool
+	alerts            *bool
+	equipmentStatus   *bool
 	showVersion       *bool
 	discoverOnly      *bool
 }
@@ -1392,19 +2482,104 @@ func defineFlags() *commandLineFlags {
 		metrics: flag.Bool("metrics", getEnvOrDefault("PENTAMETER_METRICS", "false") == trueString,
 			"Run as the Prometheus metrics exporter — the default if no function or other mode is given (env: PENTAMETER_METRICS)"),
 		intelliCenterIP: flag.String("ic-ip", getEnvOrDefault("PENTAMETER_IC_IP", ""),
-			"IntelliCenter IP address (env: PENTAMETER_IC_IP) (default mDNS auto-discovery)"),
+			"IntelliCenter IP address, or a comma-separated list to export several controllers, each under a controller "+
+				"label (metrics mode only) (env: PENTAMETER_IC_IP) (default mDNS auto-discovery)"),
 		intelliCenterPort: flag.String("ic-port", getEnvOrDefault("PENTAMETER_IC_PORT", "6680"),
 			"IntelliCenter WebSocket port (env: PENTAMETER_IC_PORT)"),
 		httpPort: flag.String("http-port", getEnvOrDefault("PENTAMETER_HTTP_PORT", "8080"),
 			"HTTP server port for metrics (env: PENTAMETER_HTTP_PORT)"),
+		metricsPath: flag.String("metrics-path", getEnvOrDefault("PENTAMETER_METRICS_PATH", defaultMetricsPath),
+			"HTTP path of the Prometheus metrics endpoint, e.g. /pentameter/metrics behind a shared ingress (env: PENTAMETER_METRICS_PATH)"),
+		healthPath: flag.String("health-path", getEnvOrDefault("PENTAMETER_HEALTH_PATH", defaultHealthPath),
+			"HTTP path of the health check endpoint (env: PENTAMETER_HEALTH_PATH)"),
+		noCompression: flag.Bool("no-compression", getEnvOrDefault("PENTAMETER_NO_COMPRESSION", "false") == trueString,
+			"Serve metrics uncompressed even when the scraper accepts gzip, for debugging (env: PENTAMETER_NO_COMPRESSION)"),
+		metricPrefix: flag.String("metric-prefix", getEnvOrDefault("PENTAMETER_METRIC_PREFIX", ""),
+			"Prepend this to every metric name, e.g. pentameter_, to avoid collisions with other exporters (env: PENTAMETER_METRIC_PREFIX)"),
 		listenMode: flag.Bool("listen", getEnvOrDefault("PENTAMETER_LISTEN", "false") == trueString,
 			"Run as a live event logger with raw JSON output (env: PENTAMETER_LISTEN)"),
+		noMetrics: flag.Bool("no-metrics", getEnvOrDefault("PENTAMETER_NO_METRICS", "false") == trueString,
+			"Listen mode: do not serve the Prometheus metrics endpoint (env: PENTAMETER_NO_METRICS)"),
 		homebridge: flag.Bool("homebridge", getEnvOrDefault("PENTAMETER_HOMEBRIDGE", "false") == trueString,
 			"Run as a Homebridge sidecar — stdio JSON IPC (env: PENTAMETER_HOMEBRIDGE)"),
 		pollInterval: flag.Int("interval", getEnvIntOrDefault("PENTAMETER_INTERVAL", 0),
 			"Polling interval in seconds (env: PENTAMETER_INTERVAL) (default 60, or 10 in listen mode)"),
+		pollIntervals: flag.String("poll-intervals", getEnvOrDefault("PENTAMETER_POLL_INTERVALS", ""),
+			"Per-type polling intervals in seconds as type=seconds pairs, e.g. pump=10,body=120; types are circuit, body, pump, heater, chem, sensor and alert (env: PENTAMETER_POLL_INTERVALS) (default --interval for every type)"),
+		reconnectGrace: flag.Int("reconnect-grace", getEnvIntOrDefault("PENTAMETER_RECONNECT_GRACE", defaultReconnectGrace),
+			"Listen mode: seconds a reconnect may take and still keep the change baseline; 0 always re-detects (env: PENTAMETER_RECONNECT_GRACE)"),
+		quietDetection: flag.Bool("quiet-detection", getEnvOrDefault("PENTAMETER_QUIET_DETECTION", "false") == trueString,
+			"Listen mode: suppress \"detected\" inventory lines, logging only changes (env: PENTAMETER_QUIET_DETECTION)"),
+		initialTimeout: flag.Int("initial-timeout", getEnvIntOrDefault("PENTAMETER_INITIAL_TIMEOUT", defaultInitialTimeout),
+			"Seconds to wait for each response while (re)connecting, before steady-state polling (env: PENTAMETER_INITIAL_TIMEOUT)"),
+		maxUnsolicited: flag.Int("max-unsolicited", getEnvIntOrDefault("PENTAMETER_MAX_UNSOLICITED", intellicenter.DefaultMaxUnsolicited),
+			"Messages to read while awaiting a response, unsolicited pushes included, before the request fails; 0 leaves only the read timeout (env: PENTAMETER_MAX_UNSOLICITED)"),
+		pingFailures: flag.Int("ping-failures", getEnvIntOrDefault("PENTAMETER_PING_FAILURES", intellicenter.DefaultPingFailures),
+			"Consecutive failed health pings on the push connection (one every 30s) before reconnecting; values below 1 behave as 1 (env: PENTAMETER_PING_FAILURES)"),
+		lockTiming: flag.Bool("lock-timing", getEnvOrDefault("PENTAMETER_LOCK_TIMING", "false") == trueString,
+			"Debug: record monitor-lock wait times as pentameter_lock_wait_seconds and log long waits (env: PENTAMETER_LOCK_TIMING)"),
+		queryPacing: flag.Int("query-pacing", getEnvIntOrDefault("PENTAMETER_QUERY_PACING", 0),
+			"Milliseconds to wait between the queries of each poll, for slow panels that drop back-to-back requests (env: PENTAMETER_QUERY_PACING)"),
+		batchQueries: flag.Bool("batch-queries", getEnvOrDefault("PENTAMETER_BATCH_QUERIES", "false") == trueString,
+			"Read every equipment type in one GetParamList per poll instead of one per type, for slow panels (env: PENTAMETER_BATCH_QUERIES)"),
+		profile: flag.String("profile", getEnvOrDefault("PENTAMETER_PROFILE", profileDefault),
+			"Timing preset: conservative (slow/older panels) or fast; explicitly set timing flags still win (env: PENTAMETER_PROFILE)"),
+		pumpAnomaly: flag.Int("pump-anomaly", getEnvIntOrDefault("PENTAMETER_PUMP_ANOMALY", 0),
+			"Heuristic: flag a flow-capable pump whose GPM-per-watt drops more than this percent below its learned baseline; 0 disables (env: PENTAMETER_PUMP_ANOMALY)"),
+		enumMap: flag.String("enum-map", getEnvOrDefault("PENTAMETER_ENUM_MAP", ""),
+			"Export enum params as numbers: OBJTYP.KEY=VALUE:NUMBER,... tables separated by ';', e.g. CIRCGRP.USE=White:1,Blue:2 (env: PENTAMETER_ENUM_MAP)"),
+		masterCircuits: flag.String("master-circuits", getEnvOrDefault("PENTAMETER_MASTER_CIRCUITS", ""),
+			"Comma-separated circuit objnams to export as master_circuit_status, e.g. C0001,C0006 (env: PENTAMETER_MASTER_CIRCUITS) (default SUBTYP POOL/SPA)"),
+		boardTempKey: flag.String("board-temp-key", getEnvOrDefault("PENTAMETER_BOARD_TEMP_KEY", ""),
+			"System object (_5451) param holding the controller board temperature, for firmwares that expose one (env: PENTAMETER_BOARD_TEMP_KEY) (default off)"),
+		circuitTimerKey: flag.String("circuit-timer-key", getEnvOrDefault("PENTAMETER_CIRCUIT_TIMER_KEY", ""),
+			"Circuit param holding the remaining egg-timer/freeze run time, as seconds or HH,MM,SS, for firmwares that expose one (env: PENTAMETER_CIRCUIT_TIMER_KEY) (default off)"),
+		chemFlowKey: flag.String("chem-flow-key", getEnvOrDefault("PENTAMETER_CHEM_FLOW_KEY", ""),
+			"IntelliChem (CHEM) param holding the flow switch state, ON/1 with flow, for firmwares that expose one (env: PENTAMETER_CHEM_FLOW_KEY) (default off)"),
+		freezeObject: flag.String("freeze-object", getEnvOrDefault("PENTAMETER_FREEZE_OBJECT", ""),
+			"Objnam of the circuit whose STATUS reports freeze protection (env: PENTAMETER_FREEZE_OBJECT) (default: SUBTYP FRZ, else _FEA2)"),
+		discoverHostname: flag.String("discover-hostname", getEnvOrDefault("PENTAMETER_DISCOVER_HOSTNAME", defaultDiscoverHostname),
+			"mDNS hostname queried to discover the IntelliCenter, for renamed or OEM-branded panels; answers must contain its first label (env: PENTAMETER_DISCOVER_HOSTNAME)"),
+		rediscoverAfter: flag.Int("rediscovery-threshold", getEnvIntOrDefault("PENTAMETER_REDISCOVERY_THRESHOLD", defaultRediscoveryThreshold),
+			"Consecutive connect or session failures before an auto-discovered panel is looked up again via mDNS on reconnect; 0 looks it up on every reconnect (env: PENTAMETER_REDISCOVERY_THRESHOLD)"),
+		poolGallons: flag.String("pool-gallons", getEnvOrDefault("PENTAMETER_POOL_GALLONS", ""),
+			"Body volumes as objnam=gallons pairs, e.g. B1101=20000,B1202=500, for pool_turnovers_per_day (env: PENTAMETER_POOL_GALLONS)"),
+		watchdogTimeout: flag.Int("watchdog-timeout", getEnvIntOrDefault("PENTAMETER_WATCHDOG_TIMEOUT", 0),
+			"Exit with status 3 after this many seconds without a successful scan, for a supervisor restart; 0 disables (env: PENTAMETER_WATCHDOG_TIMEOUT)"),
+		startupTimeout: flag.Int("startup-timeout", getEnvIntOrDefault("PENTAMETER_STARTUP_TIMEOUT", 0),
+			"Exit with status 3 if no scan succeeds within this many seconds of startup, for a supervisor restart; 0 keeps retrying while serving failure metrics (env: PENTAMETER_STARTUP_TIMEOUT)"),
+		shutdownGrace: flag.Int("shutdown-grace", getEnvIntOrDefault("PENTAMETER_SHUTDOWN_GRACE", int(intellicenter.DefaultShutdownGrace/time.Second)),
+			"Seconds a poll in flight at shutdown may take to finish before the panel connections are closed; 0 closes them at once (env: PENTAMETER_SHUTDOWN_GRACE)"),
+		pushgatewayURL: flag.String("pushgateway-url", getEnvOrDefault("PENTAMETER_PUSHGATEWAY_URL", ""),
+			"Also push metrics to this Prometheus Pushgateway after each successful scan, e.g. http://gateway:9091, for deployments that can't be scraped (env: PENTAMETER_PUSHGATEWAY_URL) (default off)"),
+		pushgatewayJob: flag.String("pushgateway-job", getEnvOrDefault("PENTAMETER_PUSHGATEWAY_JOB", defaultPushgatewayJob),
+			"Job grouping label for --pushgateway-url pushes (env: PENTAMETER_PUSHGATEWAY_JOB)"),
+		pushgatewayInst: flag.String("pushgateway-instance", getEnvOrDefault("PENTAMETER_PUSHGATEWAY_INSTANCE", ""),
+			"Instance grouping label for --pushgateway-url pushes (env: PENTAMETER_PUSHGATEWAY_INSTANCE) (default this host's name)"),
+		debugEndpoint: flag.Bool("debug-endpoint", getEnvOrDefault("PENTAMETER_DEBUG_ENDPOINT", "false") == trueString,
+			"Serve /debug/state on the metrics port: JSON of the connection status, last error, consecutive failures, panel address and equipment; exposes the pool's topology (env: PENTAMETER_DEBUG_ENDPOINT)"),
+		debugAddr: flag.String("debug-addr", getEnvOrDefault("PENTAMETER_DEBUG_ADDR", ""),
+			"Serve /debug/pprof on this separate host:port, e.g. localhost:6060, kept apart from /metrics (env: PENTAMETER_DEBUG_ADDR) (default off)"),
+		logFormat: flag.String("log-format", getEnvOrDefault("PENTAMETER_LOG_FORMAT", logFormatText),
+			"Log output format: text, or json for structured entries (level, msg, and fields such as objnam) for Loki or CloudWatch (env: PENTAMETER_LOG_FORMAT)"),
+		statusEncoding: flag.String("status-encoding", getEnvOrDefault("PENTAMETER_STATUS_ENCODING", statusEncodingTristate),
+			"Circuit/feature status encoding: tristate (0=off, 1=on, 2=freeze protection) or boolean (0/1 plus separate *_freeze_protected gauges) (env: PENTAMETER_STATUS_ENCODING)"),
+		tempUnits: flag.String("units", getEnvOrDefault("PENTAMETER_UNITS", tempUnitsFahrenheit),
+			"Temperature output units: f (*_fahrenheit metrics) or c (converted, *_celsius metrics) (env: PENTAMETER_UNITS)"),
+		schedules: flag.Bool("schedules", getEnvOrDefault("PENTAMETER_SCHEDULES", "false") == trueString,
+			"Fetch circuit schedules and export circuit_next_run_seconds countdowns (env: PENTAMETER_SCHEDULES)"),
+		alerts: flag.Bool("alerts", getEnvOrDefault("PENTAMETER_ALERTS", "false") == trueString,
+			"Fetch panel alerts and export intellicenter_active_alerts and intellicenter_alert_info (env: PENTAMETER_ALERTS)"),
+		equipmentStatus: flag.Bool("equipment-status", getEnvOrDefault("PENTAMETER_EQUIPMENT_STATUS", "false") == trueString,
+			"Export intellicenter_equipment_status, a normalized status for every equipment object in one metric (env: PENTAMETER_EQUIPMENT_STATUS)"),
+		bodies: flag.String("bodies", getEnvOrDefault("PENTAMETER_BODIES", ""),
+			"Declared bodies as objnam=key pairs, e.g. B1101=lake,B1202=therapy; a heater circuit whose name contains a key tracks that body's heating (env: PENTAMETER_BODIES) (default pool/spa name matching)"),
 		showVersion:  flag.Bool("version", false, "Show version information"),
 		discoverOnly: flag.Bool("discover", false, "Discover the IntelliCenter IP address via mDNS and exit"),
+		once: flag.Bool("once", false,
+			"Scrape the IntelliCenter once, write the metrics to stdout in Prometheus text format, and exit (nonzero on failure)"),
+		dryRun: flag.Bool("dry-run", false,
+			"Connect once, list the equipment found (bodies, pumps, circuits, features, heaters, sensors) with objnams, and exit (nonzero on failure)"),
 	}
 }
 
@@ -1426,7 +2601,12 @@ func handleEarlyExitFlags(flags *commandLineFlags) {
 	if *flags.discoverOnly {
 		log.Println("Discovering IntelliCenter...")
 		log.Println("Searching for IntelliCenter on network (up to 60 seconds). Press Ctrl-C to cancel.")
-		ip, err := DiscoverIntelliCenter(true)
+		hostname, err := parseDiscoverHostname(*flags.discoverHostname)
+		if err != nil {
+			fmt.Fprintf(flag.CommandLine.Output(), "error: --discover-hostname: %v\n", err)
+			os.Exit(exitUsageError)
+		}
+		ip, err := DiscoverIntelliCenter(hostname, true)
 		if err != nil {
 			log.Fatalf("Discovery failed: %v", err)
 		}
@@ -1450,15 +2630,79 @@ func determinePollInterval(pollIntervalSeconds int, listenMode bool) time.Durati
 	return defaultPollInterval * time.Second
 }
 
+// newEngine builds the intellicenter.Engine every mode runs, configured from cfg.
+func newEngine(cfg *appConfig, m *controllerMetrics) *intellicenter.Engine {
+	engine := intellicenter.NewEngine(cfg.intelliCenterIP, cfg.intelliCenterPort, cfg.pollInterval)
+	engine.Logf = log.Printf
+	engine.Resolve = newDiscoveryResolver(cfg, m, DiscoverIntelliCenter)
+	engine.ResolveAfter = cfg.rediscoverAfter
+	if cfg.intelliCenterIP != "" {
+		m.setTargetInfo(cfg.intelliCenterIP, cfg.intelliCenterPort)
+	}
+	if cfg.initialTimeout > 0 {
+		engine.BaselineTimeout = cfg.initialTimeout
+	}
+	engine.QueryPacing = cfg.queryPacing
+	engine.MaxUnsolicited = cfg.maxUnsolicited
+	engine.PingFailures = cfg.pingFailures
+	engine.PollEvery = cfg.pollIntervals
+	engine.ShutdownGrace = cfg.shutdownGrace
+	engine.BatchQueries = cfg.batchQueries
+	engine.Schedules = cfg.schedules
+	engine.Alerts = cfg.alerts
+	engine.ExtraKeys = cfg.enumMap.extraKeys()
+	if cfg.boardTempKey != "" {
+		if engine.ExtraKeys == nil {
+			engine.ExtraKeys = make(map[intellicenter.Kind][]string)
+		}
+		engine.ExtraKeys[intellicenter.KindSystem] = append(engine.ExtraKeys[intellicenter.KindSystem], cfg.boardTempKey)
+	}
+	if cfg.circuitTimerKey != "" {
+		if engine.ExtraKeys == nil {
+			engine.ExtraKeys = make(map[intellicenter.Kind][]string)
+		}
+		engine.ExtraKeys[intellicenter.KindCircuit] = append(engine.ExtraKeys[intellicenter.KindCircuit], cfg.circuitTimerKey)
+	}
+	if cfg.chemFlowKey != "" {
+		if engine.ExtraKeys == nil {
+			engine.ExtraKeys = make(map[intellicenter.Kind][]string)
+		}
+		engine.ExtraKeys[intellicenter.KindChem] = append(engine.ExtraKeys[intellicenter.KindChem], cfg.chemFlowKey)
+	}
+	if cfg.queryTimeout > 0 {
+		engine.QueryTimeout = cfg.queryTimeout
+	}
+	if cfg.pushQueue > 0 {
+		engine.PushQueue = cfg.pushQueue
+	}
+	return engine
+}
+
 // newDiscoveryResolver returns an engine Resolve hook that rediscovers the
-// IntelliCenter via mDNS before each (re)connect, or nil when a static IP was
-// configured (no rediscovery needed). This lets the engine-driven modes follow a
-// controller whose IP changes, matching the legacy paths' attemptRediscovery.
-func newDiscoveryResolver(cfg *appConfig) func() (string, error) {
+// IntelliCenter with discover (DiscoverIntelliCenter; tests inject a fake)
+// before each (re)connect, or nil when a static IP was configured (no
+// rediscovery needed). This lets the engine-driven modes follow a controller
+// whose IP changes, matching the legacy paths' attemptRediscovery.
+func newDiscoveryResolver(cfg *appConfig, m *controllerMetrics,
+	discover func(hostname string, verbose bool) (string, error),
+) func() (string, error) {
 	if !cfg.autoDiscover {
 		return nil
 	}
-	return func() (string, error) { return DiscoverIntelliCenter(true) }
+	return func() (string, error) {
+		ip, err := discover(cfg.discoverHostname, true)
+		if err == nil {
+			m.setTargetInfo(ip, cfg.intelliCenterPort)
+		}
+		return ip, err
+	}
+}
+
+// setTargetInfo points intellicenter_target_info at the panel address, dropping
+// the previous one so only the current address is exported.
+func (m *controllerMetrics) setTargetInfo(ip, port string) {
+	m.targetInfo.Reset()
+	m.targetInfo.WithLabelValues(ip, port).Set(1)
 }
 
 func resolveIntelliCenterIP(ip string) string {
@@ -1468,7 +2712,7 @@ func resolveIntelliCenterIP(ip string) string {
 	log.Println("No IP address provided, attempting auto-discovery...")
 	log.Println("Tip: Specify with --ic-ip flag or export PENTAMETER_IC_IP environment variable to skip discovery")
 	log.Println("Searching for IntelliCenter on network (up to 60 seconds). Press Ctrl-C to cancel.")
-	discoveredIP, err := DiscoverIntelliCenter(true)
+	discoveredIP, err := DiscoverIntelliCenter(defaultDiscoverHostname, true)
 	if err != nil {
 		log.Fatalf("Auto-discovery failed: %v\nPlease provide IP address using --ic-ip flag or PENTAMETER_IC_IP environment variable", err)
 	}
@@ -1487,9 +2731,9 @@ func doubleDashUsage() {
 		title string
 		names []string
 	}{
-		{"Functions (run once and exit)", []string{"discover", "version"}},
+		{"Functions (run once and exit)", []string{"discover", "dry-run", "once", "version"}},
 		{"Modes", []string{"metrics", "homebridge", "listen"}},
-		{"Configuration", []string{"ic-ip", "ic-port", "http-port", "interval"}},
+		{"Configuration", []string{"ic-ip", "ic-port", "http-port", "metrics-path", "health-path", "no-compression", "metric-prefix", "profile", "interval", "poll-intervals", "initial-timeout", "max-unsolicited", "ping-failures", "reconnect-grace", "quiet-detection", "no-metrics", "lock-timing", "query-pacing", "batch-queries", "pump-anomaly", "enum-map", "master-circuits", "board-temp-key", "circuit-timer-key", "chem-flow-key", "freeze-object", "discover-hostname", "rediscovery-threshold", "pool-gallons", "watchdog-timeout", "startup-timeout", "shutdown-grace", "pushgateway-url", "pushgateway-job", "pushgateway-instance", "debug-addr", "debug-endpoint", "log-format", "status-encoding", "units", "schedules", "alerts", "equipment-status", "bodies"}},
 	}
 	for _, grp := range groups {
 		fmt.Fprintf(out, "\n%s:\n", grp.title)
@@ -1524,11 +2768,11 @@ func isZeroFlagValue(v string) bool {
 }
 
 // validateExclusiveFlags enforces that at most one function or mode is selected.
-// The functions (--version, --discover) and modes (--metrics, --homebridge,
+// The functions (--version, --discover, --once, --dry-run) and modes (--metrics, --homebridge,
 // --listen) are all mutually exclusive — with each other and across categories.
 func validateExclusiveFlags(flags *commandLineFlags) {
 	exclusive := []bool{
-		*flags.showVersion, *flags.discoverOnly,
+		*flags.showVersion, *flags.discoverOnly, *flags.once, *flags.dryRun,
 		*flags.metrics, *flags.homebridge, *flags.listenMode,
 	}
 	selected := 0
@@ -1539,7 +2783,7 @@ func validateExclusiveFlags(flags *commandLineFlags) {
 	}
 	if selected > 1 {
 		fmt.Fprintln(flag.CommandLine.Output(),
-			"error: --version, --discover, --metrics, --homebridge, and --listen "+
+			"error: --version, --discover, --once, --dry-run, --metrics, --homebridge, and --listen "+
 				"are mutually exclusive; pick at most one")
 		os.Exit(exitUsageError)
 	}
@@ -1551,16 +2795,166 @@ func parseCommandLineFlags() *appConfig {
 	flag.Parse()
 
 	validateExclusiveFlags(flags)
+	logFormat := strings.ToLower(strings.TrimSpace(*flags.logFormat))
+	if logFormat != logFormatText && logFormat != logFormatJSON {
+		fmt.Fprintf(flag.CommandLine.Output(), "error: --log-format: unknown format %q, want text or json\n", *flags.logFormat)
+		os.Exit(exitUsageError)
+	}
+	setupLogging(logFormat)
 	handleEarlyExitFlags(flags)
 
+	bodies, err := parseBodies(*flags.bodies)
+	if err != nil {
+		fmt.Fprintf(flag.CommandLine.Output(), "error: --bodies: %v\n", err)
+		os.Exit(exitUsageError)
+	}
+	gallons, err := parseGallons(*flags.poolGallons)
+	if err != nil {
+		fmt.Fprintf(flag.CommandLine.Output(), "error: --pool-gallons: %v\n", err)
+		os.Exit(exitUsageError)
+	}
+	pollIntervals, err := parsePollIntervals(*flags.pollIntervals)
+	if err != nil {
+		fmt.Fprintf(flag.CommandLine.Output(), "error: --poll-intervals: %v\n", err)
+		os.Exit(exitUsageError)
+	}
+	enums, err := parseEnumMap(*flags.enumMap)
+	if err != nil {
+		fmt.Fprintf(flag.CommandLine.Output(), "error: --enum-map: %v\n", err)
+		os.Exit(exitUsageError)
+	}
+	encoding := strings.ToLower(strings.TrimSpace(*flags.statusEncoding))
+	if encoding != statusEncodingTristate && encoding != statusEncodingBoolean {
+		fmt.Fprintf(flag.CommandLine.Output(), "error: --status-encoding: unknown encoding %q, want tristate or boolean\n", *flags.statusEncoding)
+		os.Exit(exitUsageError)
+	}
+	units := strings.ToLower(strings.TrimSpace(*flags.tempUnits))
+	if units != tempUnitsFahrenheit && units != tempUnitsCelsius {
+		fmt.Fprintf(flag.CommandLine.Output(), "error: --units: unknown units %q, want f or c\n", *flags.tempUnits)
+		os.Exit(exitUsageError)
+	}
+	metricsPathFlag := strings.TrimSpace(*flags.metricsPath)
+	healthPathFlag := strings.TrimSpace(*flags.healthPath)
+	if err := validateHTTPPath(metricsPathFlag); err != nil {
+		fmt.Fprintf(flag.CommandLine.Output(), "error: --metrics-path: %v\n", err)
+		os.Exit(exitUsageError)
+	}
+	if err := validateHTTPPath(healthPathFlag); err != nil {
+		fmt.Fprintf(flag.CommandLine.Output(), "error: --health-path: %v\n", err)
+		os.Exit(exitUsageError)
+	}
+	metricPrefixFlag := strings.TrimSpace(*flags.metricPrefix)
+	if err := validateMetricPrefix(metricPrefixFlag); err != nil {
+		fmt.Fprintf(flag.CommandLine.Output(), "error: --metric-prefix: %v\n", err)
+		os.Exit(exitUsageError)
+	}
+	if metricsPathFlag == healthPathFlag {
+		fmt.Fprintf(flag.CommandLine.Output(), "error: --health-path: %q is also the metrics path\n", healthPathFlag)
+		os.Exit(exitUsageError)
+	}
+	if *flags.noMetrics && !*flags.listenMode {
+		fmt.Fprintln(flag.CommandLine.Output(), "error: --no-metrics: only applies to --listen")
+		os.Exit(exitUsageError)
+	}
+	icIPs, err := parseControllerIPs(*flags.intelliCenterIP)
+	if err != nil {
+		fmt.Fprintf(flag.CommandLine.Output(), "error: --ic-ip: %v\n", err)
+		os.Exit(exitUsageError)
+	}
+	var icIP string
+	var controllers []string
+	switch len(icIPs) {
+	case 0:
+	case 1:
+		icIP = icIPs[0]
+	default:
+		if flagName := singleControllerFlag(flags); flagName != "" {
+			fmt.Fprintf(flag.CommandLine.Output(), "error: --ic-ip: %d controllers given, but %s serves one; "+
+				"only metrics mode exports several\n", len(icIPs), flagName)
+			os.Exit(exitUsageError)
+		}
+		icIP, controllers = icIPs[0], icIPs
+	}
+	discoverHostname, err := parseDiscoverHostname(*flags.discoverHostname)
+	if err != nil {
+		fmt.Fprintf(flag.CommandLine.Output(), "error: --discover-hostname: %v\n", err)
+		os.Exit(exitUsageError)
+	}
+	pushgatewayURL := strings.TrimSpace(*flags.pushgatewayURL)
+	if pushgatewayURL != "" {
+		if err := validatePushgatewayURL(pushgatewayURL); err != nil {
+			fmt.Fprintf(flag.CommandLine.Output(), "error: --pushgateway-url: %v\n", err)
+			os.Exit(exitUsageError)
+		}
+	}
+	pushgatewayJob := strings.TrimSpace(*flags.pushgatewayJob)
+	if pushgatewayJob == "" {
+		fmt.Fprintln(flag.CommandLine.Output(), "error: --pushgateway-job: must not be empty")
+		os.Exit(exitUsageError)
+	}
+	pushgatewayInst := strings.TrimSpace(*flags.pushgatewayInst)
+	if pushgatewayInst == "" {
+		pushgatewayInst, _ = os.Hostname() // unknown → no instance grouping
+	}
+	profile, ok := timingProfiles[*flags.profile]
+	if !ok {
+		fmt.Fprintf(flag.CommandLine.Output(), "error: --profile: unknown profile %q, want conservative, fast, or default\n", *flags.profile)
+		os.Exit(exitUsageError)
+	}
+
 	cfg := &appConfig{
-		intelliCenterIP:   *flags.intelliCenterIP,
+		bodies:            bodies,
+		enumMap:           enums,
+		bodyGallons:       gallons,
+		masterCircuits:    parseObjnamList(*flags.masterCircuits),
+		boardTempKey:      strings.ToUpper(strings.TrimSpace(*flags.boardTempKey)),
+		circuitTimerKey:   strings.ToUpper(strings.TrimSpace(*flags.circuitTimerKey)),
+		chemFlowKey:       strings.ToUpper(strings.TrimSpace(*flags.chemFlowKey)),
+		freezeObject:      strings.TrimSpace(*flags.freezeObject),
+		discoverHostname:  discoverHostname,
+		rediscoverAfter:   max(*flags.rediscoverAfter, 0),
+		intelliCenterIP:   icIP,
+		controllers:       controllers,
 		intelliCenterPort: *flags.intelliCenterPort,
 		httpPort:          *flags.httpPort,
+		metricsPath:       metricsPathFlag,
+		metricPrefix:      metricPrefixFlag,
+		healthPath:        healthPathFlag,
+		noCompression:     *flags.noCompression,
 		listenMode:        *flags.listenMode,
+		noMetrics:         *flags.noMetrics,
 		homebridge:        *flags.homebridge,
+		once:              *flags.once,
+		dryRun:            *flags.dryRun,
 		pollInterval:      determinePollInterval(*flags.pollInterval, *flags.listenMode),
-	}
+		pollIntervals:     pollIntervals,
+		reconnectGrace:    time.Duration(max(*flags.reconnectGrace, 0)) * time.Second,
+		quietDetection:    *flags.quietDetection,
+		lockTiming:        *flags.lockTiming,
+		initialTimeout:    time.Duration(*flags.initialTimeout) * time.Second,
+		queryPacing:       time.Duration(max(*flags.queryPacing, 0)) * time.Millisecond,
+		maxUnsolicited:    max(*flags.maxUnsolicited, 0),
+		pingFailures:      *flags.pingFailures,
+		batchQueries:      *flags.batchQueries,
+		pumpAnomaly:       max(*flags.pumpAnomaly, 0),
+		watchdogTimeout:   time.Duration(max(*flags.watchdogTimeout, 0)) * time.Second,
+		startupTimeout:    time.Duration(max(*flags.startupTimeout, 0)) * time.Second,
+		shutdownGrace:     time.Duration(max(*flags.shutdownGrace, 0)) * time.Second,
+		debugAddr:         strings.TrimSpace(*flags.debugAddr),
+		debugEndpoint:     *flags.debugEndpoint,
+		pushgatewayURL:    pushgatewayURL,
+		pushgatewayJob:    pushgatewayJob,
+		pushgatewayInst:   pushgatewayInst,
+		logFormat:         logFormat,
+		statusEncoding:    encoding,
+		tempUnits:         units,
+		schedules:         *flags.schedules,
+		alerts:            *flags.alerts,
+		equipmentStatus:   *flags.equipmentStatus,
+	}
+	applyProfile(cfg, profile, explicitlySet)
+	lockTiming = cfg.lockTiming
+	metricsPath, healthPath = cfg.metricsPath, cfg.healthPath
 	cfg.autoDiscover = cfg.intelliCenterIP == ""
 	// All modes now run an intellicenter.Engine, which rediscovers via its Resolve
 	// hook; up-front discovery would only block and Fatal. So resolve here only
@@ -1571,47 +2965,278 @@ func parseCommandLineFlags() *appConfig {
 	return cfg
 }
 
+// parseBodies parses --bodies ("B1101=lake,B1202=therapy pool") into a body
+// objnam -> key map. Keys are lowercased to match circuit names case-insensitively.
+func parseBodies(spec string) (map[string]string, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	bodies := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		objName, key, ok := strings.Cut(pair, "=")
+		objName = strings.TrimSpace(objName)
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || objName == "" || key == "" {
+			return nil, fmt.Errorf("invalid entry %q, want objnam=key", pair)
+		}
+		bodies[objName] = key
+	}
+	return bodies, nil
+}
+
+// parseGallons parses --pool-gallons ("B1101=20000,B1202=500") into a body
+// objnam -> gallons map.
+func parseGallons(spec string) (map[string]float64, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	gallons := make(map[string]float64)
+	for _, pair := range strings.Split(spec, ",") {
+		objName, volStr, ok := strings.Cut(pair, "=")
+		objName = strings.TrimSpace(objName)
+		vol, err := strconv.ParseFloat(strings.TrimSpace(volStr), 64)
+		if !ok || objName == "" || err != nil || vol <= 0 {
+			return nil, fmt.Errorf("invalid entry %q, want objnam=gallons", pair)
+		}
+		gallons[objName] = vol
+	}
+	return gallons, nil
+}
+
+// pollIntervalKinds is the engine kinds --poll-intervals accepts: every kind a
+// poll scans.
+var pollIntervalKinds = []intellicenter.Kind{
+	intellicenter.KindCircuit, intellicenter.KindBody, intellicenter.KindPump,
+	intellicenter.KindHeater, intellicenter.KindChem, intellicenter.KindSensor, intellicenter.KindAlert,
+}
+
+// parsePollIntervals parses --poll-intervals ("pump=10,body=120") into
+// per-kind poll intervals. Each is held to the same minimum as --interval.
+func parsePollIntervals(spec string) (map[intellicenter.Kind]time.Duration, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	intervals := make(map[intellicenter.Kind]time.Duration)
+	for _, pair := range strings.Split(spec, ",") {
+		kindStr, secStr, ok := strings.Cut(pair, "=")
+		kind := intellicenter.Kind(strings.ToLower(strings.TrimSpace(kindStr)))
+		seconds, err := strconv.Atoi(strings.TrimSpace(secStr))
+		if !ok || err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid entry %q, want type=seconds", pair)
+		}
+		if !slices.Contains(pollIntervalKinds, kind) {
+			return nil, fmt.Errorf("unknown type %q, want circuit, body, pump, heater, chem, sensor or alert", kindStr)
+		}
+		if seconds < minPollInterval {
+			return nil, fmt.Errorf("%s interval %ds is below the minimum (%ds)", kind, seconds, minPollInterval)
+		}
+		intervals[kind] = time.Duration(seconds) * time.Second
+	}
+	return intervals, nil
+}
+
+// parseObjnamList parses a comma-separated objnam list into a set, or nil when
+// the list is empty.
+func parseObjnamList(spec string) map[string]bool {
+	var set map[string]bool
+	for _, objName := range strings.Split(spec, ",") {
+		if objName = strings.TrimSpace(objName); objName != "" {
+			if set == nil {
+				set = make(map[string]bool)
+			}
+			set[objName] = true
+		}
+	}
+	return set
+}
+
 func logStartupMessage(cfg *appConfig) {
-	log.Printf("Starting pool monitor for IntelliCenter at %s:%s", cfg.intelliCenterIP, cfg.intelliCenterPort)
+	if len(cfg.controllers) > 1 {
+		log.Printf("Starting pool monitor for %d IntelliCenters at %s (port %s)",
+			len(cfg.controllers), strings.Join(cfg.controllers, ", "), cfg.intelliCenterPort)
+	} else {
+		log.Printf("Starting pool monitor for IntelliCenter at %s:%s", cfg.intelliCenterIP, cfg.intelliCenterPort)
+	}
 	if cfg.listenMode {
 		log.Printf("Listen mode enabled - real-time push + polling every %v", cfg.pollInterval)
+		if !cfg.noMetrics {
+			log.Printf("HTTP server will run on port %s", cfg.httpPort)
+		}
 	} else {
 		log.Printf("HTTP server will run on port %s", cfg.httpPort)
 		log.Printf("Polling interval: %v", cfg.pollInterval)
 	}
+	for _, kind := range pollIntervalKinds {
+		if every, ok := cfg.pollIntervals[kind]; ok {
+			log.Printf("Polling interval for %s: %v", kind, every)
+		}
+	}
+}
+
+// createPrometheusRegistry builds a registry holding the process-wide metrics
+// and one controller's metrics m, for modes that serve one panel.
+func createPrometheusRegistry(cfg *appConfig, m *controllerMetrics) *prometheus.Registry {
+	registry := createProcessRegistry(cfg)
+	m.register(metricsRegisterer(registry, cfg), cfg)
+	return registry
 }
 
-func createPrometheusRegistry() *prometheus.Registry {
+// createProcessRegistry builds a registry holding only the process-wide
+// metrics. Metrics mode adds each controller's set itself, labeled when there
+// are several.
+func createProcessRegistry(cfg *appConfig) *prometheus.Registry {
 	registry := prometheus.NewRegistry()
-	registry.MustRegister(poolTemperature)
-	registry.MustRegister(airTemperature)
-	registry.MustRegister(connectionFailure)
-	registry.MustRegister(lastRefreshTimestamp)
-	registry.MustRegister(pumpRPM)
-	registry.MustRegister(circuitStatus)
-	registry.MustRegister(thermalStatus)
-	registry.MustRegister(thermalLowSetpoint)
-	registry.MustRegister(thermalHighSetpoint)
-	registry.MustRegister(featureStatus)
+	r := metricsRegisterer(registry, cfg)
+	buildInfo.WithLabelValues(version, runtime.Version()).Set(1)
+	r.MustRegister(buildInfo)
+	r.MustRegister(discoveryDuration)
+	r.MustRegister(discoveryAttempts)
+	if lockTiming {
+		r.MustRegister(lockWaitSeconds)
+	}
 	return registry
 }
 
-// bindMetricsServer registers the Prometheus /metrics + /health handlers and
-// binds the listener synchronously, so the caller learns immediately — before
-// logging or advertising the endpoint — whether the bind succeeded. metrics mode
-// treats a bind failure as fatal (serving metrics is the whole job); homebridge
-// mode logs it and carries on, so a port conflict on the secondary metrics
-// endpoint never takes down HomeKit.
-func bindMetricsServer(registry *prometheus.Registry, monitor *PoolMonitor, httpPort string) (net.Listener, error) {
-	http.Handle("/metrics", createMetricsHandler(registry, monitor))
-	http.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
+// boundServer is a bound listener plus the handler it serves. Each server gets
+// its own mux, never http.DefaultServeMux, so routes registered for one (or by
+// a package init, like net/http/pprof's) can't leak onto another.
+type boundServer struct {
+	net.Listener
+	handler http.Handler
+}
+
+// Default HTTP endpoint paths, relocatable with --metrics-path/--health-path.
+const (
+	defaultMetricsPath = "/metrics"
+	defaultHealthPath  = "/health"
+)
+
+// metricsPath and healthPath are the HTTP endpoint paths. Set once at startup,
+// before any server binds or the endpoint is advertised.
+var (
+	metricsPath = defaultMetricsPath
+	healthPath  = defaultHealthPath
+)
+
+// metricsRegisterer registers collectors into registry with cfg.metricPrefix
+// (--metric-prefix) prepended to their metric names.
+func metricsRegisterer(registry *prometheus.Registry, cfg *appConfig) prometheus.Registerer {
+	return prometheus.WrapRegistererWithPrefix(cfg.metricPrefix, registry)
+}
+
+// parseControllerIPs returns the controller addresses in an --ic-ip value:
+// none (auto-discovery), one, or a comma-separated list. A repeated address is
+// rejected, since its two metric sets would carry the same controller label.
+func parseControllerIPs(value string) ([]string, error) {
+	var addrs []string
+	for addr := range strings.SplitSeq(value, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if slices.Contains(addrs, addr) {
+			return nil, fmt.Errorf("controller %s is listed twice", addr)
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// singleControllerFlag names the first flag set in flags that serves a single
+// controller, or "" when none is. Listen and homebridge modes, --once and
+// --dry-run drive one panel's state; --debug-endpoint dumps one engine.
+func singleControllerFlag(flags *commandLineFlags) string {
+	switch {
+	case *flags.listenMode:
+		return "--listen"
+	case *flags.homebridge:
+		return "--homebridge"
+	case *flags.once:
+		return "--once"
+	case *flags.dryRun:
+		return "--dry-run"
+	case *flags.debugEndpoint:
+		return "--debug-endpoint"
+	}
+	return ""
+}
+
+// validateHTTPPath checks an endpoint path flag: it must be absolute and a
+// plain path, since ServeMux reads spaces as a method and braces as wildcards.
+func validateHTTPPath(p string) error {
+	if !strings.HasPrefix(p, "/") {
+		return fmt.Errorf("path %q must start with /", p)
+	}
+	if strings.ContainsAny(p, " \t{}") {
+		return fmt.Errorf("path %q must not contain spaces or braces", p)
+	}
+	return nil
+}
+
+// validateMetricPrefix checks --metric-prefix can start a metric name: letters,
+// digits, underscores and colons, not beginning with a digit. Empty is no prefix.
+func validateMetricPrefix(prefix string) error {
+	for i, c := range prefix {
+		switch {
+		case c == '_' || c == ':' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z'):
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return fmt.Errorf("prefix %q may only contain letters, digits, _ and :, and must not start with a digit", prefix)
+		}
+	}
+	return nil
+}
+
+// newMetricsMux serves the Prometheus metrics and health endpoints, at
+// /metrics and /health unless relocated by flag.
+func newMetricsMux(registry *prometheus.Registry, monitor *PoolMonitor, cfg *appConfig) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, createMetricsHandler(registry, monitor, cfg))
+	if monitor.stateEngine != nil {
+		mux.Handle(debugStatePath, newDebugStateHandler(monitor.stateEngine))
+	}
+	mux.HandleFunc(healthPath, func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		if _, err := w.Write([]byte("OK")); err != nil {
 			log.Printf("Failed to write health check response: %v", err)
 		}
 	})
+	return mux
+}
+
+// newDebugMux serves the Go runtime profiles under /debug/pprof/. They expose
+// process internals, so they live on their own --debug-addr listener, which
+// can be kept on localhost while /metrics is reachable from the network.
+func newDebugMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
 
-	return net.Listen("tcp", ":"+httpPort)
+// bindMetricsServer binds the /metrics + /health listener on cfg.httpPort
+// synchronously, so the caller learns immediately — before logging or
+// advertising the endpoint — whether the bind succeeded. metrics mode treats a bind failure as fatal
+// (serving metrics is the whole job); homebridge mode logs it and carries on,
+// so a port conflict on the secondary metrics endpoint never takes down HomeKit.
+func bindMetricsServer(registry *prometheus.Registry, monitor *PoolMonitor, cfg *appConfig) (boundServer, error) {
+	return bindServer(":"+cfg.httpPort, newMetricsMux(registry, monitor, cfg))
+}
+
+// bindDebugServer binds the --debug-addr listener (host:port) for the debug mux.
+func bindDebugServer(addr string) (boundServer, error) {
+	return bindServer(addr, newDebugMux())
+}
+
+func bindServer(addr string, handler http.Handler) (boundServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return boundServer{}, err
+	}
+	return boundServer{Listener: ln, handler: handler}, nil
 }
 
 func main() {
@@ -1621,36 +3246,55 @@ func main() {
 		runHomebridge(cfg)
 		return
 	}
+	if cfg.once {
+		os.Exit(runOnce(cfg, os.Stdout))
+	}
+	if cfg.dryRun {
+		os.Exit(runDryRun(cfg, os.Stdout))
+	}
 
 	logStartupMessage(cfg)
 
-	registry := createPrometheusRegistry()
-
 	// Metrics and listen modes are both driven by the push-based
 	// intellicenter.Engine (real-time gauges / events, with the poll as a safety
 	// net). The engine owns connection, reconnect, and mDNS rediscovery.
 	if cfg.listenMode {
 		runListenEngine(cfg)
 	} else {
-		runMetricsEngine(cfg, registry)
+		runMetricsEngine(cfg, createProcessRegistry(cfg))
 	}
 }
 
-// serveMetrics serves on an already-bound listener (from bindMetricsServer) and
-// blocks until the server stops. http.ErrServerClosed (graceful shutdown) is
+// serveHTTP serves on an already-bound listener (from bindMetricsServer or
+// bindDebugServer) and blocks until the server stops. Canceling ctx shuts the
+// server down gracefully: in-flight requests get up to httpShutdownTimeout to
+// finish before serveHTTP returns. http.ErrServerClosed (graceful shutdown) is
 // folded into a nil return.
-func serveMetrics(ln net.Listener) error {
+func serveHTTP(ctx context.Context, ln boundServer) error {
 	server := &http.Server{
-		Handler:      nil,
+		Handler:      ln.handler,
 		ReadTimeout:  httpReadTimeout,
 		WriteTimeout: httpWriteTimeout,
 		IdleTimeout:  httpIdleTimeout,
 	}
 
+	drained := make(chan struct{})
+	stopShutdown := context.AfterFunc(ctx, func() {
+		defer close(drained)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("HTTP server shutdown: %v", err)
+		}
+	})
+
 	// Serve returns ErrServerClosed on Server.Close/Shutdown and net.ErrClosed
 	// when the listener itself is closed; both are graceful stops, not failures.
-	if err := server.Serve(ln); err != nil &&
-		!errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+	err := server.Serve(ln)
+	if !stopShutdown() {
+		<-drained // Shutdown is running: let in-flight requests finish
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
 		return err
 	}
 	return nil
@@ -1,13 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"io"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/astrostl/pentameter/intellicenter"
 	"github.com/gorilla/websocket"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -26,6 +35,68 @@ const (
 
 // Test helper to create a mock WebSocket server.
 func createMockWebSocketServer(t *testing.T, responses map[string]IntelliCenterResponse) *httptest.Server {
+	t.Helper()
+	return createMockWebSocketServerWithPushes(t, responses, nil)
+}
+
+// createMockWebSocketServerWithPushes is createMockWebSocketServer that also
+// writes an unsolicited push ahead of the response for any request whose
+// "command:condition" key appears in pushes, as IntelliCenter does when state
+// changes mid-request.
+func createMockWebSocketServerWithPushes(
+	t *testing.T, responses map[string]IntelliCenterResponse, pushes map[string]map[string]any,
+) *httptest.Server {
+	t.Helper()
+	return newScriptedMock(responses, pushes).serve(t)
+}
+
+// scriptedMock is a mock IntelliCenter whose responses can be changed while it
+// is serving, so tests can script equipment changes between polls. Responses
+// and pushes are keyed by "command:condition"; unknown keys get an empty 200.
+type scriptedMock struct {
+	mu        sync.Mutex
+	responses map[string]IntelliCenterResponse
+	pushes    map[string]map[string]any
+	down      bool
+}
+
+func newScriptedMock(responses map[string]IntelliCenterResponse, pushes map[string]map[string]any) *scriptedMock {
+	m := &scriptedMock{
+		responses: map[string]IntelliCenterResponse{},
+		pushes:    pushes,
+	}
+	for k, v := range responses {
+		m.responses[k] = v
+	}
+	return m
+}
+
+// set replaces the response for key; subsequent requests see the new value.
+func (m *scriptedMock) set(key string, resp IntelliCenterResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses[key] = resp
+}
+
+// setDown makes the mock drop the connection on every request (true), as an
+// unreachable panel would, or answer normally again (false).
+func (m *scriptedMock) setDown(down bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.down = down
+}
+
+func (m *scriptedMock) lookup(key string) (IntelliCenterResponse, map[string]any, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	resp, ok := m.responses[key]
+	if !ok {
+		resp = IntelliCenterResponse{Response: "200"}
+	}
+	return resp, m.pushes[key], !m.down
+}
+
+func (m *scriptedMock) serve(t *testing.T) *httptest.Server {
 	t.Helper()
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(_ *http.Request) bool { return true },
@@ -34,7 +105,8 @@ func createMockWebSocketServer(t *testing.T, responses map[string]IntelliCenterR
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
-			t.Fatalf("Failed to upgrade connection: %v", err)
+			t.Errorf("Failed to upgrade connection: %v", err)
+			return
 		}
 		defer conn.Close()
 
@@ -44,19 +116,20 @@ func createMockWebSocketServer(t *testing.T, responses map[string]IntelliCenterR
 				return
 			}
 
-			// Determine response based on command and condition
-			var resp IntelliCenterResponse
-			if response, exists := responses[req.Command+":"+req.Condition]; exists {
-				resp = response
-				resp.MessageID = req.MessageID
-			} else {
-				resp = IntelliCenterResponse{
-					Command:   req.Command,
-					MessageID: req.MessageID,
-					Response:  "200",
+			resp, push, up := m.lookup(req.Command + ":" + req.Condition)
+			if !up {
+				return
+			}
+			if push != nil {
+				if err := conn.WriteJSON(push); err != nil {
+					return
 				}
 			}
 
+			if resp.Command == "" {
+				resp.Command = req.Command
+			}
+			resp.MessageID = req.MessageID
 			if err := conn.WriteJSON(resp); err != nil {
 				return
 			}
@@ -151,6 +224,47 @@ func TestGetAirTemperature(t *testing.T) {
 	testAirTemperature(t, "75.2")
 }
 
+func TestAirTemperatureMultipleSensors(t *testing.T) {
+	objs := []ObjectData{
+		{ObjName: "_A135", Params: map[string]string{"SNAME": "Multi Air", "PROBE": "71", "SUBTYP": "AIR", "STATUS": "ON"}},
+		{ObjName: "SSS11", Params: map[string]string{"SNAME": "Multi Solar", "PROBE": "112", "SUBTYP": "SOLAR", "STATUS": "ON"}},
+		{ObjName: "_A136", Params: map[string]string{"SNAME": "Multi Air 2", "PROBE": "69", "SUBTYP": "AIR", "STATUS": "ON"}},
+	}
+	pm := NewPoolMonitor("test", "6680", false)
+	pm.applyAirTemperature(objs)
+
+	if got := gaugeVal(t, pm.metrics.airTemperature.WithLabelValues("_A135", "AIR", "Multi Air")); got != 71 {
+		t.Errorf("first air sensor = %v, want 71", got)
+	}
+	if got := gaugeVal(t, pm.metrics.airTemperature.WithLabelValues("_A136", "AIR", "Multi Air 2")); got != 69 {
+		t.Errorf("second air sensor = %v, want 69", got)
+	}
+	if got := gaugeVal(t, pm.metrics.solarTemperature.WithLabelValues("SSS11", "SOLAR", "Multi Solar")); got != 112 {
+		t.Errorf("solar sensor = %v, want 112", got)
+	}
+	if pm.metrics.airTemperature.DeleteLabelValues("SSS11", "SOLAR", "Multi Solar") {
+		t.Error("solar sensor should not be published as an air temperature")
+	}
+}
+
+func TestAirSensorConnected(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	pm.applyAirTemperature([]ObjectData{
+		{ObjName: "_A135", Params: map[string]string{"SNAME": "Health Air", "PROBE": "71", "SUBTYP": "AIR", "STATUS": "OK"}},
+	})
+	if got := gaugeVal(t, pm.metrics.airSensorConnected.WithLabelValues("_A135", "AIR", "Health Air")); got != 1 {
+		t.Errorf("STATUS=OK: air_sensor_connected = %v, want 1", got)
+	}
+
+	// A faulted probe still reports health even with no usable reading.
+	pm.applyAirTemperature([]ObjectData{
+		{ObjName: "_A135", Params: map[string]string{"SNAME": "Health Air", "SUBTYP": "AIR", "STATUS": "FAULT"}},
+	})
+	if got := gaugeVal(t, pm.metrics.airSensorConnected.WithLabelValues("_A135", "AIR", "Health Air")); got != 0 {
+		t.Errorf("STATUS=FAULT: air_sensor_connected = %v, want 0", got)
+	}
+}
+
 func TestGetPumpData(_ *testing.T) {
 	objs := []ObjectData{
 		{
@@ -247,6 +361,47 @@ func TestGetBodyNameFromCircuit(t *testing.T) {
 	}
 }
 
+func TestDeclaredBodies(t *testing.T) {
+	bodies, err := parseBodies("B1101=Lake, B1202=therapy pool")
+	if err != nil {
+		t.Fatalf("parseBodies: %v", err)
+	}
+	poolMonitor := NewPoolMonitor("test", "6680", false)
+	poolMonitor.declaredBodies = bodies
+
+	// Heating status is keyed by the declared key, not the SNAME.
+	poolMonitor.processBodyHeatingStatus("Big Water", "1", "B1101", "POOL")
+	poolMonitor.processBodyHeatingStatus("Hot Tub", "0", "B1202", "SPA")
+	if !poolMonitor.bodyHeatingStatus["lake"] || poolMonitor.bodyHeatingStatus["therapy pool"] {
+		t.Errorf("bodyHeatingStatus = %v, want lake heating and therapy pool not", poolMonitor.bodyHeatingStatus)
+	}
+
+	tests := []struct {
+		circuitName string
+		expected    string
+	}{
+		{"Lake Heater", "lake"},
+		{"Therapy Pool Heat", "therapy pool"}, // longest declared key wins
+		{"Spa Heat", "spa"},                   // undeclared names still fall back
+		{"Random Circuit", ""},
+	}
+	for _, test := range tests {
+		if got := poolMonitor.getBodyNameFromCircuit(test.circuitName); got != test.expected {
+			t.Errorf("getBodyNameFromCircuit(%s): expected %q, got %q", test.circuitName, test.expected, got)
+		}
+	}
+
+	if got := poolMonitor.calculateCircuitStatusValue("Lake Heater", "ON", "C0001", false); got != circuitStatusOn {
+		t.Errorf("Lake Heater status = %v, want %v", got, circuitStatusOn)
+	}
+
+	for _, bad := range []string{"B1101", "=lake", "B1101="} {
+		if _, err := parseBodies(bad); err == nil {
+			t.Errorf("parseBodies(%q): expected error", bad)
+		}
+	}
+}
+
 func TestCalculateCircuitStatusValue(t *testing.T) {
 	poolMonitor := NewPoolMonitor("test", "6680", false)
 	poolMonitor.bodyHeatingStatus["pool"] = true
@@ -274,6 +429,251 @@ func TestCalculateCircuitStatusValue(t *testing.T) {
 	}
 }
 
+// TestHeaterControlFromConfiguration checks that the configuration graph, not
+// the name, decides which circuits and features follow their body's heating,
+// and maps each to the body its heater is assigned to.
+func TestHeaterControlFromConfiguration(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	pm.bodyHeatingStatus["spa"] = true
+	pm.referencedHeaters["H0002"] = BodyHeaterInfo{BodyName: "Spa", BodyObj: "B1202", HeaterObj: "H0002"}
+	pm.configObjects = map[string]intellicenter.ConfigObject{
+		"H0002": {ID: "H0002", ObjType: objTypeHeater, SubType: "GENERIC", Name: "Spa Heater"},
+		"FTR07": {ID: "FTR07", ObjType: objTypeCircuit, SubType: "GENERIC", Name: "Spa Heat", Parent: "H0002"},
+		"C0010": {ID: "C0010", ObjType: objTypeCircuit, SubType: "GENERIC", Name: "Booster", Parent: "H0002"},
+		"C0008": {ID: "C0008", ObjType: objTypeCircuit, SubType: "GENERIC", Name: "Spa Heat Lamp"},
+	}
+
+	// Feature-backed heater: reports STATUS=OFF but its body is heating.
+	pm.processCircuitObject(ObjectData{ObjName: "FTR07", Params: map[string]string{
+		"SNAME": "Spa Heat", "STATUS": "OFF", "SUBTYP": "GENERIC",
+	}})
+	if got := gaugeVal(t, pm.metrics.featureStatus.WithLabelValues("FTR07", "Spa Heat", "GENERIC", "")); got != 1 {
+		t.Errorf("feature-backed heater status = %v, want 1 (spa heating)", got)
+	}
+
+	// Linked in the graph under another name: the body comes from the heater's
+	// HTSRC assignment, not the circuit name.
+	pm.processCircuitObject(ObjectData{ObjName: "C0010", Params: map[string]string{
+		"SNAME": "Booster", "STATUS": "OFF", "SUBTYP": "GENERIC",
+	}})
+	if got := gaugeVal(t, pm.metrics.circuitStatus.WithLabelValues("C0010", "Booster", "GENERIC", "")); got != 1 {
+		t.Errorf("configured heater circuit status = %v, want 1 (spa heating)", got)
+	}
+
+	// Named like a heater but configured as a plain circuit: its own STATUS counts.
+	pm.processCircuitObject(ObjectData{ObjName: "C0008", Params: map[string]string{
+		"SNAME": "Spa Heat Lamp", "STATUS": "OFF", "SUBTYP": "GENERIC",
+	}})
+	if got := gaugeVal(t, pm.metrics.circuitStatus.WithLabelValues("C0008", "Spa Heat Lamp", "GENERIC", "")); got != 0 {
+		t.Errorf("heater-named circuit status = %v, want 0 (its own STATUS)", got)
+	}
+
+	// Before the configuration is known, the name still marks a heater circuit.
+	if !pm.isHeaterControl("C0009", "Pool Heater") || pm.isHeaterControl("C0008", "Spa Heat Lamp") {
+		t.Error("isHeaterControl: want name fallback only for unconfigured circuits")
+	}
+}
+
+// TestApplyFreezeProtection checks the freeze object is --freeze-object, then
+// the FRZ-typed circuit, then _FEA2.
+func TestApplyFreezeProtection(t *testing.T) {
+	circuit := func(objName, name, subtype, status string) ObjectData {
+		return ObjectData{ObjName: objName, Params: map[string]string{
+			"SNAME": name, "SUBTYP": subtype, "STATUS": status,
+		}}
+	}
+	tests := []struct {
+		desc         string
+		freezeObject string
+		objs         []ObjectData
+		want         bool
+	}{
+		{"default _FEA2", "", []ObjectData{
+			circuit("_FEA2", "Feature 2", "GENERIC", "ON"),
+		}, true},
+		{"FRZ subtype wins over _FEA2", "", []ObjectData{
+			circuit("_FEA2", "Feature 2", "GENERIC", "ON"),
+			circuit("_FEA7", "Protect", "FRZ", "OFF"),
+		}, false},
+		{"--freeze-object", "C0099", []ObjectData{
+			circuit("X0034", "Freeze Protection", "FRZ", "OFF"),
+			circuit("C0099", "Antifreeze", "GENERIC", "ON"),
+		}, true},
+		{"--freeze-object off", "C0099", []ObjectData{
+			circuit("_FEA2", "Feature 2", "GENERIC", "ON"),
+			circuit("C0099", "Antifreeze", "GENERIC", "OFF"),
+		}, false},
+	}
+	for _, tt := range tests {
+		pm := NewPoolMonitor("test", "6680", false)
+		pm.freezeObject = tt.freezeObject
+		pm.applyFreezeProtection(tt.objs)
+		if pm.freezeProtectionActive != tt.want {
+			t.Errorf("%s: freezeProtectionActive = %v, want %v", tt.desc, pm.freezeProtectionActive, tt.want)
+		}
+	}
+}
+
+func TestApplySystemInfo(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	system := func(vacflo string) []ObjectData {
+		return []ObjectData{{ObjName: "_5451", Params: map[string]string{"VACFLO": vacflo}}}
+	}
+
+	pm.applySystemInfo(system("ON"))
+	if got := gaugeVal(t, pm.metrics.vacationMode); got != 1 {
+		t.Errorf("VACFLO=ON: vacation_mode = %v, want 1", got)
+	}
+	pm.applySystemInfo(system("VACFLO")) // unsupported key echo: unchanged
+	if got := gaugeVal(t, pm.metrics.vacationMode); got != 1 {
+		t.Errorf("key echo changed vacation_mode to %v", got)
+	}
+	pm.applySystemInfo(system("OFF"))
+	if got := gaugeVal(t, pm.metrics.vacationMode); got != 0 {
+		t.Errorf("VACFLO=OFF: vacation_mode = %v, want 0", got)
+	}
+}
+
+func TestPumpTotalWatts(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	pm.applyPumpData([]ObjectData{
+		{ObjName: "PMP01", Params: map[string]string{"SNAME": "VS", "RPM": "1800", "PWR": "215"}},
+		{ObjName: "PMP02", Params: map[string]string{"SNAME": "VSF", "RPM": "2450", "PWR": "760"}},
+		{ObjName: "PMP03", Params: map[string]string{"SNAME": "Old", "RPM": "1000", "WATTS": "100"}}, // WATTS fallback
+		{ObjName: "PMP04", Params: map[string]string{"SNAME": "Silent", "RPM": "0"}},                 // no power reported
+	}, 0)
+	if got := gaugeVal(t, pm.metrics.pumpTotalWatts); got != 1075 {
+		t.Errorf("pump_total_watts = %v, want 1075", got)
+	}
+
+	// Recomputed, not accumulated, on the next refresh.
+	pm.applyPumpData([]ObjectData{
+		{ObjName: "PMP01", Params: map[string]string{"SNAME": "VS", "RPM": "1800", "PWR": "215"}},
+	}, 0)
+	if got := gaugeVal(t, pm.metrics.pumpTotalWatts); got != 215 {
+		t.Errorf("pump_total_watts after refresh = %v, want 215", got)
+	}
+}
+
+// TestPumpWattsPollAndPush checks pump_watts reads the same from a poll or a
+// push, whether the firmware reports power under PWR or WATTS.
+func TestPumpWattsPollAndPush(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	for _, key := range []string{"PWR", "WATTS"} {
+		params := map[string]string{"SNAME": "Watts " + key, "RPM": "1800", key: "215", "OBJTYP": "PUMP"}
+		gauge := pm.metrics.pumpWatts.WithLabelValues("PMP"+key, "Watts "+key)
+
+		pm.applyPumpData([]ObjectData{{ObjName: "PMP" + key, Params: params}}, 0)
+		if got := gaugeVal(t, gauge); got != 215 {
+			t.Errorf("%s poll: pump_watts = %v, want 215", key, got)
+		}
+
+		params[key] = "760"
+		pm.processPushObject(ObjectData{ObjName: "PMP" + key, Params: params})
+		if got := gaugeVal(t, gauge); got != 760 {
+			t.Errorf("%s push: pump_watts = %v, want 760", key, got)
+		}
+
+		// A push without power keeps the last reading rather than zeroing it.
+		pm.processPushObject(ObjectData{ObjName: "PMP" + key, Params: map[string]string{
+			"SNAME": "Watts " + key, "RPM": "1900", "OBJTYP": "PUMP",
+		}})
+		if got := gaugeVal(t, gauge); got != 760 {
+			t.Errorf("%s push without power: pump_watts = %v, want 760", key, got)
+		}
+
+		// An unparseable reading is skipped; the rest of the pump still updates.
+		pm.processPushObject(ObjectData{ObjName: "PMP" + key, Params: map[string]string{
+			"SNAME": "Watts " + key, "RPM": "2000", key: "n/a", "OBJTYP": "PUMP",
+		}})
+		if got := gaugeVal(t, gauge); got != 760 {
+			t.Errorf("%s unparseable power: pump_watts = %v, want 760", key, got)
+		}
+		if got := gaugeVal(t, pm.metrics.pumpRPM.WithLabelValues("PMP"+key, "Watts "+key)); got != 2000 {
+			t.Errorf("%s unparseable power: pump_rpm = %v, want 2000", key, got)
+		}
+	}
+}
+
+func TestPumpGPM(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	pump := func(gpm string) ObjectData {
+		return ObjectData{ObjName: "PMP91", Params: map[string]string{
+			"SNAME": "Flow Pump", "RPM": "2400", "GPM": gpm, "OBJTYP": "PUMP",
+		}}
+	}
+
+	pm.applyPumpData([]ObjectData{pump("62")}, 0)
+	if got := gaugeVal(t, pm.metrics.pumpGPM.WithLabelValues("PMP91", "Flow Pump")); got != 62 {
+		t.Errorf("pump_gpm = %v, want 62", got)
+	}
+
+	// A push without GPM keeps the last reading.
+	pm.processPushObject(ObjectData{ObjName: "PMP91", Params: map[string]string{"SNAME": "Flow Pump", "RPM": "2500", "OBJTYP": "PUMP"}})
+	if got := gaugeVal(t, pm.metrics.pumpGPM.WithLabelValues("PMP91", "Flow Pump")); got != 62 {
+		t.Errorf("push without GPM: pump_gpm = %v, want 62", got)
+	}
+
+	for _, gpm := range []string{"0", "", "GPM"} {
+		pm.applyPumpData([]ObjectData{pump("62")}, 0)
+		pm.applyPumpData([]ObjectData{pump(gpm)}, 0)
+		if pm.metrics.pumpGPM.DeleteLabelValues("PMP91", "Flow Pump") {
+			t.Errorf("GPM %q: stale pump_gpm series not removed", gpm)
+		}
+		if pm.pumpFlow["PMP91"] != 0 {
+			t.Errorf("GPM %q: turnover flow = %v, want 0", gpm, pm.pumpFlow["PMP91"])
+		}
+	}
+}
+
+func TestApplyUnitMismatch(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	system := func(mode string) []ObjectData {
+		return []ObjectData{{ObjName: "_5451", Params: map[string]string{"VACFLO": "OFF", "MODE": mode}}}
+	}
+
+	pm.applySystemInfo(system("METRIC"))
+	if got := gaugeVal(t, pm.metrics.unitMismatch); got != 1 {
+		t.Errorf("MODE=METRIC: unit_mismatch = %v, want 1", got)
+	}
+	if _, ok := pm.lastLogged["unitmismatch:_5451"]; !ok {
+		t.Error("metric panel logged no warning")
+	}
+	pm.applySystemInfo(system("MODE")) // unsupported key echo: unchanged
+	if got := gaugeVal(t, pm.metrics.unitMismatch); got != 1 {
+		t.Errorf("MODE echo: unit_mismatch = %v, want unchanged 1", got)
+	}
+	pm.applySystemInfo(system("ENGLISH"))
+	if got := gaugeVal(t, pm.metrics.unitMismatch); got != 0 {
+		t.Errorf("MODE=ENGLISH: unit_mismatch = %v, want 0", got)
+	}
+	if _, ok := pm.lastLogged["unitmismatch:_5451"]; ok {
+		t.Error("warning not reset after the panel returned to English units")
+	}
+}
+
+func TestApplyBoardTemperature(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	system := []ObjectData{{ObjName: "_5451", Params: map[string]string{"VACFLO": "OFF", "BRDTMP": "104.5"}}}
+
+	pm.applySystemInfo(system) // no --board-temp-key: nothing exported
+	if pm.metrics.boardTemperature.DeleteLabelValues() {
+		t.Fatal("board temperature exported without --board-temp-key")
+	}
+
+	pm.boardTempKey = "BRDTMP"
+	pm.applySystemInfo(system)
+	if got := gaugeVal(t, pm.metrics.boardTemperature.WithLabelValues()); got != 104.5 {
+		t.Errorf("board temperature = %v, want 104.5", got)
+	}
+
+	// A key echo from a firmware without the param is skipped, not exported.
+	pm.applySystemInfo([]ObjectData{{ObjName: "_5451", Params: map[string]string{"BRDTMP": "BRDTMP"}}})
+	if got := gaugeVal(t, pm.metrics.boardTemperature.WithLabelValues()); got != 104.5 {
+		t.Errorf("key echo changed board temperature to %v", got)
+	}
+}
+
 func TestApplyPumpAssociations(t *testing.T) {
 	pm := NewPoolMonitor("test", "6680", false)
 	pm.applyPumpAssociations([]ObjectData{
@@ -297,6 +697,106 @@ func TestApplyPumpAssociations(t *testing.T) {
 	}
 }
 
+func TestBodyTemperatureError(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	body := func(temp, lotmp, htsrc string) ObjectData {
+		return ObjectData{ObjName: "B9201", Params: map[string]string{
+			"SNAME": "Error Spa", "SUBTYP": "SPA", "TEMP": temp, "LOTMP": lotmp, "HTSRC": htsrc,
+		}}
+	}
+	pm.processBodyObject(body("98", "102", "H0001"), map[string]BodyHeaterInfo{})
+	if got := gaugeVal(t, pm.metrics.bodyTemperatureError.WithLabelValues("B9201", "SPA", "Error Spa")); got != -4 {
+		t.Errorf("below target: got %v, want -4", got)
+	}
+	pm.processBodyObject(body("103", "102", "H0001"), map[string]BodyHeaterInfo{})
+	if got := gaugeVal(t, pm.metrics.bodyTemperatureError.WithLabelValues("B9201", "SPA", "Error Spa")); got != 1 {
+		t.Errorf("above target: got %v, want 1", got)
+	}
+
+	// Unassigning the heater removes the series.
+	pm.processBodyObject(body("103", "102", "00000"), map[string]BodyHeaterInfo{})
+	if pm.metrics.bodyTemperatureError.DeleteLabelValues("B9201", "SPA", "Error Spa") {
+		t.Error("series should be removed when no heater is assigned")
+	}
+}
+
+func TestHeaterlessBody(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", true)
+	pm.initializeState()
+	body := func(htsrc string) ObjectData {
+		return ObjectData{ObjName: "B9401", Params: map[string]string{
+			"OBJTYP": "BODY", "SNAME": "Plunge", "SUBTYP": "POOL", "STATUS": "ON",
+			"TEMP": "78", "HTMODE": "0", "HTSRC": htsrc, "LOTMP": "82", "HITMP": "104",
+		}}
+	}
+
+	// Never assigned a heater: temperature and heating status still export.
+	referenced := map[string]BodyHeaterInfo{}
+	pm.processBodyObject(body(""), referenced)
+	if got := gaugeVal(t, pm.metrics.poolTemperature.WithLabelValues("B9401", "POOL", "Plunge")); got != 78 {
+		t.Errorf("water temperature: got %v, want 78", got)
+	}
+	if heating, ok := pm.bodyHeatingStatus["plunge"]; !ok || heating {
+		t.Errorf("heating status: got %v (recorded %v), want false", heating, ok)
+	}
+	if len(referenced) != 0 {
+		t.Errorf("heaterless body should reference no heater, got %v", referenced)
+	}
+	if pm.metrics.bodyTemperatureError.DeleteLabelValues("B9401", "POOL", "Plunge") {
+		t.Error("heaterless body should have no temperature error series")
+	}
+
+	// Unassigning a heater by push drops the old assignment but keeps the body.
+	pm.handleBodyPush(body("H9401"), "Plunge")
+	if _, ok := pm.referencedHeaters["H9401"]; !ok {
+		t.Fatal("assigned heater should be referenced")
+	}
+	pm.handleBodyPush(body(intellicenter.HeatSourceNone), "Plunge")
+	if info, ok := pm.referencedHeaters["H9401"]; ok {
+		t.Errorf("unassigned heater still referenced: %+v", info)
+	}
+	if got := gaugeVal(t, pm.metrics.poolTemperature.WithLabelValues("B9401", "POOL", "Plunge")); got != 78 {
+		t.Errorf("water temperature after unassign: got %v, want 78", got)
+	}
+}
+
+func TestApplyCircuitGroups(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	pm.cacheCircuitNames([]ObjectData{
+		{ObjName: "GRP01", Params: map[string]string{"SNAME": "Backyard"}},
+		{ObjName: "GRP02", Params: map[string]string{"SNAME": "All Lights"}},
+	})
+	pm.applyCircuitGroups([]ObjectData{
+		{ObjName: "c0101", Params: map[string]string{"PARENT": "GRP01", "CIRCUIT": "C0003"}},
+		{ObjName: "c0102", Params: map[string]string{"PARENT": "GRP02", "CIRCUIT": "C0003"}},
+		{ObjName: "c0103", Params: map[string]string{"PARENT": "GRP01", "CIRCUIT": "FTR01"}},
+		{ObjName: "c0104", Params: map[string]string{"PARENT": "GRP09", "CIRCUIT": "C0004"}}, // unnamed group
+		{ObjName: "c0105", Params: map[string]string{"PARENT": "GRP01"}},                     // incomplete row
+	})
+
+	want := map[string]string{
+		"C0003": "All Lights,Backyard",
+		"FTR01": "Backyard",
+		"C0004": "GRP09",
+	}
+	if len(pm.circuitGroups) != len(want) {
+		t.Errorf("circuitGroups = %v, want %v", pm.circuitGroups, want)
+	}
+	for circuit, group := range want {
+		if got := pm.circuitGroups[circuit]; got != group {
+			t.Errorf("%s group = %q, want %q", circuit, got, group)
+		}
+	}
+
+	// The group label lands on the member's circuit_status series.
+	pm.processCircuitObject(ObjectData{ObjName: "C0003", Params: map[string]string{
+		"SNAME": "Pool Light", "STATUS": testStatusOn, "SUBTYP": "LIGHT",
+	}})
+	if !pm.activeCircuitKeys["C0003|Pool Light|LIGHT|All Lights,Backyard"] {
+		t.Errorf("circuit key missing group label: %v", pm.activeCircuitKeys)
+	}
+}
+
 func TestApplyPumpDeliveryGate(t *testing.T) {
 	pm := NewPoolMonitor("test", "6680", false)
 	pm.circuitToPumps = map[string][]string{
@@ -346,6 +846,262 @@ func TestCircuitStatusGatedByPump(t *testing.T) {
 	}
 }
 
+// TestApplyBodyFiltration advances an injected clock across samples and checks
+// that only intervals where the body was circulating (ON with its pump running)
+// are credited to body_filtration_seconds_total.
+func TestApplyBodyFiltration(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	clock := time.Unix(1_700_000_000, 0)
+	pm.now = func() time.Time { return clock }
+	pm.circuitToPumps = map[string][]string{"B9101": {"PMP01"}}
+
+	body := func(status string) []ObjectData {
+		return []ObjectData{{ObjName: "B9101", Params: map[string]string{
+			"SNAME": "Filtration Pool", "SUBTYP": "POOL", "STATUS": status,
+		}}}
+	}
+	counter := pm.metrics.bodyFiltrationSeconds.WithLabelValues("B9101", "POOL", "Filtration Pool")
+	start := counterVal(t, counter)
+
+	steps := []struct {
+		advance time.Duration
+		status  string
+		pumpOn  bool
+		want    float64 // cumulative seconds credited since start
+	}{
+		{0, statusOn, true, 0},                       // first sample: nothing to credit yet
+		{60 * time.Second, statusOn, true, 60},       // circulating for the full interval
+		{30 * time.Second, statusOn, false, 90},      // was circulating at last sample
+		{45 * time.Second, statusOn, true, 90},       // pump was stopped: not credited
+		{10 * time.Second, testStatusOff, true, 100}, // was circulating at last sample
+		{20 * time.Second, testStatusOff, true, 100}, // body off: not credited
+	}
+	for i, st := range steps {
+		clock = clock.Add(st.advance)
+		pm.pumpRunning = map[string]bool{"PMP01": st.pumpOn}
+		pm.applyBodyFiltration(body(st.status))
+		if got := counterVal(t, counter) - start; got != st.want {
+			t.Errorf("step %d: filtration seconds = %v, want %v", i, got, st.want)
+		}
+	}
+}
+
+// TestHeaterHeatingSeconds checks heater_heating_seconds_total credits only
+// intervals that began heating or cooling, across heating/idle/off/cooling
+// transitions, and heater_cycles_total counts each start of heating.
+func TestHeaterHeatingSeconds(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	clock := time.Unix(1_700_000_000, 0)
+	pm.now = func() time.Time { return clock }
+
+	heater := ObjectData{ObjName: "H9101", Params: map[string]string{
+		"SNAME": "Runtime Gas", "SUBTYP": "GENERIC", "STATUS": "ON",
+	}}
+	counter := pm.metrics.heaterHeatingSeconds.WithLabelValues("H9101", "Runtime Gas")
+	start := counterVal(t, counter)
+	cycles := pm.metrics.heaterCycles.WithLabelValues("H9101", "Runtime Gas")
+	startCycles := counterVal(t, cycles)
+
+	steps := []struct {
+		advance    time.Duration
+		htMode     int
+		temp       float64
+		want       float64 // cumulative seconds credited since start
+		wantCycles float64 // heating starts counted since start
+	}{
+		{0, htModeHeating, 80, 0, 0},                 // first sample: start time unknown, not a cycle
+		{60 * time.Second, htModeHeating, 81, 60, 0}, // heating for the full interval
+		{30 * time.Second, htModeOff, 84, 90, 0},     // was heating at last sample; now idle
+		{45 * time.Second, htModeOff, 84, 90, 0},     // idle: not credited
+		{10 * time.Second, htModeOff, 90, 90, 0},     // off (above setpoint): not credited
+		{20 * time.Second, htModeHeatPumpHeating, 82, 90, 1},
+		{15 * time.Second, htModeHeatPumpCooling, 90, 105, 1}, // was heating at last sample
+		{40 * time.Second, htModeOff, 84, 145, 1},             // was cooling at last sample
+		{5 * time.Second, htModeHeating, 80, 145, 2},          // idle to heating: a second cycle
+	}
+	for i, st := range steps {
+		clock = clock.Add(st.advance)
+		pm.referencedHeaters = map[string]BodyHeaterInfo{"H9101": {
+			BodyName: "Runtime Pool", HTMode: st.htMode, Temp: st.temp, LoTemp: 80, HiTemp: 86,
+		}}
+		pm.processHeaterObject(heater)
+		if got := counterVal(t, counter) - start; got != st.want {
+			t.Errorf("step %d: heating seconds = %v, want %v", i, got, st.want)
+		}
+		if got := counterVal(t, cycles) - startCycles; got != st.wantCycles {
+			t.Errorf("step %d: heater cycles = %v, want %v", i, got, st.wantCycles)
+		}
+	}
+}
+
+// TestApplyTurnovers checks pool_turnovers_per_day sums the running associated
+// pumps' GPM, reads 0 while the body is off, and skips unconfigured bodies.
+func TestApplyTurnovers(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	pm.bodyGallons = map[string]float64{"B9201": 14400}
+	pm.circuitToPumps = map[string][]string{"B9201": {"PMP01", "PMP02"}}
+	pm.pumpRunning = map[string]bool{"PMP01": true, "PMP02": false}
+	pm.pumpFlow = map[string]float64{"PMP01": 30, "PMP02": 50}
+
+	body := func(objName, status string) ObjectData {
+		return ObjectData{ObjName: objName, Params: map[string]string{
+			"SNAME": "Turnover " + objName, "SUBTYP": "POOL", "STATUS": status,
+		}}
+	}
+	gauge := pm.metrics.poolTurnoversPerDay.WithLabelValues("B9201", "POOL", "Turnover B9201")
+
+	pm.applyTurnovers([]ObjectData{body("B9201", statusOn), body("B9202", statusOn)})
+	if got := gaugeVal(t, gauge); got != 3 { // 30 GPM * 1440 / 14400
+		t.Errorf("turnovers = %v, want 3 (stopped pump excluded)", got)
+	}
+	if pm.metrics.poolTurnoversPerDay.DeleteLabelValues("B9202", "POOL", "Turnover B9202") {
+		t.Error("body without --pool-gallons exported a series")
+	}
+
+	pm.applyTurnovers([]ObjectData{body("B9201", testStatusOff)})
+	if got := gaugeVal(t, gauge); got != 0 {
+		t.Errorf("body off: turnovers = %v, want 0", got)
+	}
+}
+
+func TestParseGallons(t *testing.T) {
+	got, err := parseGallons("B1101=20000, B1202=500.5")
+	if err != nil || got["B1101"] != 20000 || got["B1202"] != 500.5 {
+		t.Errorf("parseGallons = %v, %v", got, err)
+	}
+	for _, bad := range []string{"B1101", "B1101=", "=500", "B1101=-5", "B1101=lots"} {
+		if _, err := parseGallons(bad); err == nil {
+			t.Errorf("parseGallons(%q) = nil error, want failure", bad)
+		}
+	}
+}
+
+func TestParsePollIntervals(t *testing.T) {
+	got, err := parsePollIntervals("pump=10, Body=120")
+	if err != nil || got[intellicenter.KindPump] != 10*time.Second || got[intellicenter.KindBody] != 120*time.Second {
+		t.Errorf("parsePollIntervals = %v, %v", got, err)
+	}
+	for _, bad := range []string{"pump", "pump=", "=10", "pump=fast", "valve=10", "pump=2"} {
+		if _, err := parsePollIntervals(bad); err == nil {
+			t.Errorf("parsePollIntervals(%q) = nil error, want failure", bad)
+		}
+	}
+}
+
+// TestCleanupStalePumps verifies a removed or renamed pump's series are deleted
+// on the next refresh instead of keeping their last reading.
+func TestCleanupStalePumps(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	pump := func(objName, name string) ObjectData {
+		return ObjectData{ObjName: objName, Params: map[string]string{"SNAME": name, "RPM": "2400", "WATTS": "800"}}
+	}
+	pm.applyPumpData([]ObjectData{pump("PMP71", "Stale Filter"), pump("PMP72", "Stale Spa")}, 0)
+
+	// PMP71 renamed, PMP72 removed.
+	pm.applyPumpData([]ObjectData{pump("PMP71", "Fresh Filter")}, 0)
+	for _, labels := range [][]string{{"PMP71", "Stale Filter"}, {"PMP72", "Stale Spa"}} {
+		if pm.metrics.pumpRPM.DeleteLabelValues(labels...) || pm.metrics.pumpWatts.DeleteLabelValues(labels...) {
+			t.Errorf("stale pump series %v left behind", labels)
+		}
+	}
+	if got := gaugeVal(t, pm.metrics.pumpRPM.WithLabelValues("PMP71", "Fresh Filter")); got != 2400 {
+		t.Errorf("renamed pump_rpm = %v, want 2400", got)
+	}
+}
+
+// TestCleanupStaleBodiesAndHeaters verifies a removed or renamed body's and
+// heater's series are deleted on the next refresh.
+func TestCleanupStaleBodiesAndHeaters(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	body := func(objName, name string) ObjectData {
+		return ObjectData{ObjName: objName, Params: map[string]string{
+			"SNAME": name, "SUBTYP": "POOL", "STATUS": "ON", "TEMP": "80", "HTMODE": "1", "HTSRC": "H0071", "LOTMP": "84", "HITMP": "90",
+		}}
+	}
+	heater := func(objName, name string) ObjectData {
+		return ObjectData{ObjName: objName, Params: map[string]string{"SNAME": name, "SUBTYP": "GENERIC", "STATUS": "ON"}}
+	}
+	pm.applyBodyTemperatures([]ObjectData{body("B7101", "Stale Pool"), body("B7102", "Gone Pool")})
+	pm.applyThermalStatus([]ObjectData{heater("H0071", "Stale Heater"), heater("H0072", "Gone Heater")})
+
+	// B7101 and H0071 renamed, B7102 and H0072 removed.
+	pm.applyBodyTemperatures([]ObjectData{body("B7101", "Fresh Pool")})
+	pm.applyThermalStatus([]ObjectData{heater("H0071", "Fresh Heater")})
+	for _, labels := range [][]string{{"B7101", "POOL", "Stale Pool"}, {"B7102", "POOL", "Gone Pool"}} {
+		if pm.metrics.poolTemperature.DeleteLabelValues(labels...) || pm.metrics.bodyHeatMode.DeleteLabelValues(labels...) ||
+			pm.metrics.bodyTemperatureError.DeleteLabelValues(labels...) {
+			t.Errorf("stale body series %v left behind", labels)
+		}
+	}
+	if _, ok := pm.bodyHeatingStatus["gone pool"]; ok {
+		t.Error("removed body's heating status kept")
+	}
+	for _, labels := range [][]string{{"H0071", "Stale Heater", "GENERIC"}, {"H0072", "Gone Heater", "GENERIC"}} {
+		if pm.metrics.thermalStatus.DeleteLabelValues(labels...) || pm.metrics.thermalLowSetpoint.DeleteLabelValues(labels...) ||
+			pm.metrics.heaterHeatingSeconds.DeleteLabelValues(labels[:2]...) || pm.metrics.heaterCycles.DeleteLabelValues(labels[:2]...) {
+			t.Errorf("stale heater series %v left behind", labels)
+		}
+	}
+	if got := gaugeVal(t, pm.metrics.poolTemperature.WithLabelValues("B7101", "POOL", "Fresh Pool")); got != 80 {
+		t.Errorf("renamed water temperature = %v, want 80", got)
+	}
+	if got := gaugeVal(t, pm.metrics.thermalStatus.WithLabelValues("H0071", "Fresh Heater", "GENERIC")); got != thermalStatusHeating {
+		t.Errorf("renamed thermal_status = %v, want %d", got, thermalStatusHeating)
+	}
+}
+
+// TestKnownEquipmentEmitsWhenOff verifies a known pump without an RPM and a
+// known circuit without a STATUS still emit 0 on a refresh, instead of leaving
+// a gap until they are first seen running.
+func TestKnownEquipmentEmitsWhenOff(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	pumpParams := map[string]string{"SNAME": "Idle Pump", "STATUS": "0"}
+	pm.applyPumpData([]ObjectData{{ObjName: "PMP09", Params: pumpParams}}, 0)
+	pm.applyCircuitStatus([]ObjectData{{ObjName: "C0099", Params: map[string]string{
+		"SNAME": "Idle Light", "SUBTYP": "LIGHT",
+	}}})
+
+	if got := gaugeVal(t, pm.metrics.pumpRPM.WithLabelValues("PMP09", "Idle Pump")); got != 0 {
+		t.Errorf("pump_rpm = %v, want 0", got)
+	}
+	if !pm.activeCircuitKeys[metricKey("C0099", "Idle Light", "LIGHT", "")] {
+		t.Error("circuit without STATUS emitted no circuit_status series")
+	}
+	if _, ok := pumpParams["RPM"]; ok {
+		t.Error("defaulting RPM mutated the caller's params")
+	}
+}
+
+func TestMasterCircuitStatus(t *testing.T) {
+	circuits := []ObjectData{
+		{ObjName: "C0006", Params: map[string]string{"SNAME": "Master Pool", "SUBTYP": "POOL", "STATUS": "ON"}},
+		{ObjName: "C0001", Params: map[string]string{"SNAME": "Master Spa", "SUBTYP": "SPA", "STATUS": "OFF"}},
+		{ObjName: "C0003", Params: map[string]string{"SNAME": "Master Light", "SUBTYP": "LIGHT", "STATUS": "ON"}},
+	}
+
+	pm := NewPoolMonitor("test", "6680", false)
+	pm.applyCircuitStatus(circuits)
+	if got := gaugeVal(t, pm.metrics.masterCircuitStatus.WithLabelValues("C0006", "Master Pool", "POOL", "")); got != 1 {
+		t.Errorf("pool master = %v, want 1", got)
+	}
+	if got := gaugeVal(t, pm.metrics.masterCircuitStatus.WithLabelValues("C0001", "Master Spa", "SPA", "")); got != 0 {
+		t.Errorf("spa master = %v, want 0", got)
+	}
+	if pm.activeMasterKeys[metricKey("C0003", "Master Light", "LIGHT", "")] {
+		t.Error("LIGHT circuit detected as a master circuit")
+	}
+
+	// The override replaces detection, and the dropped masters are cleaned up.
+	pm.masterCircuits = parseObjnamList(" C0003 ,")
+	pm.applyCircuitStatus(circuits)
+	if !pm.activeMasterKeys[metricKey("C0003", "Master Light", "LIGHT", "")] || len(pm.activeMasterKeys) != 1 {
+		t.Errorf("override masters = %v, want only C0003", pm.activeMasterKeys)
+	}
+	if pm.metrics.masterCircuitStatus.DeleteLabelValues("C0006", "Master Pool", "POOL", "") {
+		t.Error("stale pool master series not cleaned up")
+	}
+}
+
 func TestProcessPumpObjectWithInvalidRPM(t *testing.T) {
 	poolMonitor := NewPoolMonitor("test", "6680", false)
 
@@ -409,7 +1165,7 @@ func TestCreateMetricsHandler(t *testing.T) {
 	registry := prometheus.NewRegistry()
 	poolMonitor := NewPoolMonitor("test", "6680", false)
 
-	handler := createMetricsHandler(registry, poolMonitor)
+	handler := createMetricsHandler(registry, poolMonitor, &appConfig{})
 	if handler == nil {
 		t.Error("createMetricsHandler should return a non-nil handler")
 	}
@@ -538,26 +1294,183 @@ func TestGetEnvOrDefaultWithExistingVar(t *testing.T) {
 	}
 }
 
+// TestHTTPMuxRoutes checks each listener's mux serves only its own routes, so
+// the debug endpoints can't be reached on the metrics port or vice versa.
+func TestHTTPMuxRoutes(t *testing.T) {
+	pm := NewPoolMonitor("", "", false)
+	muxes := map[string]*http.ServeMux{
+		"metrics": newMetricsMux(createPrometheusRegistry(&appConfig{}, pm.metrics), pm, &appConfig{}),
+		"debug":   newDebugMux(),
+	}
+	routes := []struct {
+		mux, path string
+		want      int
+	}{
+		{"metrics", "/metrics", http.StatusOK},
+		{"metrics", "/health", http.StatusOK},
+		{"metrics", "/debug/pprof/", http.StatusNotFound},
+		{"debug", "/debug/pprof/", http.StatusOK},
+		{"debug", "/metrics", http.StatusNotFound},
+		{"debug", "/health", http.StatusNotFound},
+	}
+	for _, r := range routes {
+		rec := httptest.NewRecorder()
+		muxes[r.mux].ServeHTTP(rec, httptest.NewRequest(http.MethodGet, r.path, nil))
+		if rec.Code != r.want {
+			t.Errorf("%s mux GET %s = %d, want %d", r.mux, r.path, rec.Code, r.want)
+		}
+	}
+}
+
+// TestRelocatedMetricsPaths checks --metrics-path/--health-path move the
+// endpoints rather than adding to them.
+func TestRelocatedMetricsPaths(t *testing.T) {
+	defer func() { metricsPath, healthPath = defaultMetricsPath, defaultHealthPath }()
+	metricsPath, healthPath = "/pentameter/metrics", "/pentameter/healthz"
+
+	pm := NewPoolMonitor("", "", false)
+	mux := newMetricsMux(createPrometheusRegistry(&appConfig{}, pm.metrics), pm, &appConfig{})
+	for path, want := range map[string]int{
+		"/pentameter/metrics": http.StatusOK,
+		"/pentameter/healthz": http.StatusOK,
+		"/metrics":            http.StatusNotFound,
+		"/health":             http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
+
+func TestMetricsCompression(t *testing.T) {
+	for _, disabled := range []bool{false, true} {
+		cfg := &appConfig{noCompression: disabled}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		createMetricsHandler(createPrometheusRegistry(cfg, defineControllerMetrics(false)), nil, cfg).ServeHTTP(rec, req)
+		gzipped := rec.Header().Get("Content-Encoding") == "gzip"
+		if gzipped == disabled {
+			t.Errorf("--no-compression=%v: Content-Encoding %q", disabled, rec.Header().Get("Content-Encoding"))
+		}
+	}
+}
+
+func TestValidateHTTPPath(t *testing.T) {
+	for path, ok := range map[string]bool{
+		"/metrics":          true,
+		"/exporters/pool/":  true,
+		"metrics":           false,
+		"":                  false,
+		"/GET metrics":      false,
+		"/metrics/{target}": false,
+	} {
+		if err := validateHTTPPath(path); (err == nil) != ok {
+			t.Errorf("validateHTTPPath(%q) = %v, want ok=%v", path, err, ok)
+		}
+	}
+}
+
+func TestValidateMetricPrefix(t *testing.T) {
+	for prefix, ok := range map[string]bool{
+		"":            true,
+		"pentameter_": true,
+		"pool:":       true,
+		"_x9":         true,
+		"9pool_":      false,
+		"pool-":       false,
+		"pool ":       false,
+	} {
+		if err := validateMetricPrefix(prefix); (err == nil) != ok {
+			t.Errorf("validateMetricPrefix(%q) = %v, want ok=%v", prefix, err, ok)
+		}
+	}
+}
+
+func TestMetricPrefix(t *testing.T) {
+	cfg := &appConfig{metricPrefix: "pentameter_"}
+	pm := NewPoolMonitor("", "", false)
+	registry := createPrometheusRegistry(cfg, pm.metrics)
+	metricsRegisterer(registry, cfg).MustRegister(newDataAgeCollector(pm))
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	names := map[string]bool{}
+	for _, mf := range families {
+		names[mf.GetName()] = true
+		if !strings.HasPrefix(mf.GetName(), "pentameter_") {
+			t.Errorf("metric %s lacks the prefix", mf.GetName())
+		}
+	}
+	if !names["pentameter_intellicenter_build_info"] {
+		t.Errorf("pentameter_intellicenter_build_info missing from %v", names)
+	}
+}
+
+func TestParseControllerIPs(t *testing.T) {
+	for value, want := range map[string][]string{
+		"":                                  nil,
+		"192.168.1.100":                     {"192.168.1.100"},
+		" 192.168.1.100, ":                  {"192.168.1.100"},
+		"192.168.1.100, 192.168.1.101":      {"192.168.1.100", "192.168.1.101"},
+		"192.168.1.100,,192.168.1.101,pool": {"192.168.1.100", "192.168.1.101", "pool"},
+	} {
+		if got, err := parseControllerIPs(value); err != nil || !slices.Equal(got, want) {
+			t.Errorf("parseControllerIPs(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if _, err := parseControllerIPs("192.168.1.100, 192.168.1.100"); err == nil {
+		t.Error("parseControllerIPs accepted the same controller twice")
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	families, err := createProcessRegistry(&appConfig{}).Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	for _, mf := range families {
+		if mf.GetName() != "intellicenter_build_info" {
+			continue
+		}
+		labels := map[string]string{}
+		for _, lp := range mf.GetMetric()[0].GetLabel() {
+			labels[lp.GetName()] = lp.GetValue()
+		}
+		if labels["version"] != version || labels["goversion"] != runtime.Version() {
+			t.Errorf("build info labels = %v, want version=%s goversion=%s", labels, version, runtime.Version())
+		}
+		if v := mf.GetMetric()[0].GetGauge().GetValue(); v != 1 {
+			t.Errorf("build info = %v, want 1", v)
+		}
+		return
+	}
+	t.Fatal("intellicenter_build_info not registered")
+}
+
 func TestMetricsServerBindAndServe(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping server test in short mode")
 	}
 
-	registry := createPrometheusRegistry()
 	monitor := NewPoolMonitor("", "", false)
+	registry := createPrometheusRegistry(&appConfig{}, monitor.metrics)
 
 	// Port "0" lets the OS pick a free port, so the test never collides with a
 	// real metrics server or another test.
-	ln, err := bindMetricsServer(registry, monitor, "0")
+	ln, err := bindMetricsServer(registry, monitor, &appConfig{httpPort: "0"})
 	if err != nil {
 		t.Fatalf("bindMetricsServer should succeed on a free port: %v", err)
 	}
-	if ln == nil {
+	if ln.Listener == nil {
 		t.Fatal("bindMetricsServer returned a nil listener with no error")
 	}
 
 	served := make(chan error, 1)
-	go func() { served <- serveMetrics(ln) }()
+	go func() { served <- serveHTTP(t.Context(), ln) }()
 
 	resp, err := http.Get("http://" + ln.Addr().String() + "/health")
 	if err != nil {
@@ -575,7 +1488,34 @@ func TestMetricsServerBindAndServe(t *testing.T) {
 		t.Errorf("closing listener: %v", err)
 	}
 	if err := <-served; err != nil {
-		t.Errorf("serveMetrics returned %v after a graceful close, want nil", err)
+		t.Errorf("serveHTTP returned %v after a graceful close, want nil", err)
+	}
+}
+
+// TestServeHTTPShutdown checks canceling the context (SIGINT/SIGTERM in
+// metrics mode) stops the server gracefully with a nil return.
+func TestServeHTTPShutdown(t *testing.T) {
+	pm := NewPoolMonitor("", "", false)
+	ln, err := bindMetricsServer(createPrometheusRegistry(&appConfig{}, pm.metrics), pm, &appConfig{httpPort: "0"})
+	if err != nil {
+		t.Fatalf("bindMetricsServer: %v", err)
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	served := make(chan error, 1)
+	go func() { served <- serveHTTP(ctx, ln) }()
+
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("serveHTTP returned %v after shutdown, want nil", err)
+		}
+	case <-time.After(httpShutdownTimeout):
+		t.Fatal("serveHTTP did not return after the context was canceled")
+	}
+	if resp, err := http.Get("http://" + ln.Addr().String() + "/health"); err == nil {
+		_ = resp.Body.Close()
+		t.Error("server still answering after shutdown")
 	}
 }
 
@@ -911,11 +1851,81 @@ func TestGetThermalStatus(_ *testing.T) {
 	poolMonitor.applyThermalStatus(objs)
 }
 
+func TestHeaterActiveFiringSource(t *testing.T) {
+	objs := []ObjectData{
+		{ObjName: "H0001", Params: map[string]string{"SNAME": "Test Heat Pump", "STATUS": "OFF", "SUBTYP": "ULTRA", "BODY": "B1101"}},
+		{ObjName: "H0002", Params: map[string]string{"SNAME": "Test Gas", "STATUS": "OFF", "SUBTYP": "GENERIC", "BODY": "B1101"}},
+		{ObjName: "HXULT", Params: map[string]string{"SNAME": "Test Preferred", "STATUS": "STATUS"}},
+	}
+
+	tests := []struct {
+		name     string
+		htMode   int
+		wantGas  float64
+		wantPump float64
+	}{
+		{"gas firing", htModeHeating, 1, 0},
+		{"heat pump heating", htModeHeatPumpHeating, 0, 1},
+		{"heat pump cooling", htModeHeatPumpCooling, 0, 1},
+		{"idle", htModeOff, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := NewPoolMonitor("test", "6680", false)
+			// The body's HTSRC is the combo object, not either real heater.
+			pm.referencedHeaters["HXULT"] = BodyHeaterInfo{BodyName: "Pool", BodyObj: "B1101", HeaterObj: "HXULT", HTMode: tt.htMode}
+			pm.applyHeaterActive(objs)
+
+			if got := gaugeVal(t, pm.metrics.heaterActive.WithLabelValues("H0002", "Test Gas", "heater")); got != tt.wantGas {
+				t.Errorf("gas heater_active = %v, want %v", got, tt.wantGas)
+			}
+			if got := gaugeVal(t, pm.metrics.heaterActive.WithLabelValues("H0001", "Test Heat Pump", "heatpump")); got != tt.wantPump {
+				t.Errorf("heat pump heater_active = %v, want %v", got, tt.wantPump)
+			}
+			if pm.metrics.heaterActive.DeleteLabelValues("HXULT", "Test Preferred", "heater") {
+				t.Error("combo heater object should not get a heater_active series")
+			}
+		})
+	}
+}
+
+func TestThermalStatusMismatch(t *testing.T) {
+	heater := func(status string) ObjectData {
+		return ObjectData{ObjName: "H0009", Params: map[string]string{"SNAME": "Mismatch Heater", "SUBTYP": "GENERIC", "STATUS": status}}
+	}
+	tests := []struct {
+		name       string
+		status     string
+		referenced bool
+		htMode     int
+		want       float64
+	}{
+		{"available and firing", "ON", true, htModeHeating, 0},
+		{"available and idle", "ON", true, htModeOff, 0},
+		{"off but firing", "OFF", true, htModeHeating, 1},
+		{"off but heat pump cooling", "OFF", true, htModeHeatPumpCooling, 1},
+		{"off and idle", "OFF", true, htModeOff, 0},
+		{"off, not referenced", "OFF", false, htModeHeating, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := NewPoolMonitor("test", "6680", false)
+			if tt.referenced {
+				pm.referencedHeaters["H0009"] = BodyHeaterInfo{BodyName: "Pool", BodyObj: "B1101", HeaterObj: "H0009", HTMode: tt.htMode}
+			}
+			pm.processHeaterObject(heater(tt.status))
+			if got := gaugeVal(t, pm.metrics.thermalStatusMismatch.WithLabelValues("H0009", "Mismatch Heater")); got != tt.want {
+				t.Errorf("thermal_status_mismatch = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestProcessBodyHeatingStatusError(t *testing.T) {
 	poolMonitor := NewPoolMonitor("test", "6680", false)
 
 	// Test with invalid HTMODE value
-	poolMonitor.processBodyHeatingStatus("Pool", "invalid", "BODY1")
+	poolMonitor.processBodyHeatingStatus("Pool", "invalid", "BODY1", "POOL")
 
 	// Should not have added anything to bodyHeatingStatus
 	if _, exists := poolMonitor.bodyHeatingStatus["pool"]; exists {
@@ -923,8 +1933,8 @@ func TestProcessBodyHeatingStatusError(t *testing.T) {
 	}
 
 	// Test with empty values
-	poolMonitor.processBodyHeatingStatus("", "1", "BODY1")
-	poolMonitor.processBodyHeatingStatus("Pool", "", "BODY1")
+	poolMonitor.processBodyHeatingStatus("", "1", "BODY1", "POOL")
+	poolMonitor.processBodyHeatingStatus("Pool", "", "BODY1", "POOL")
 
 	// Should still not have added anything
 	if _, exists := poolMonitor.bodyHeatingStatus["pool"]; exists {
@@ -932,6 +1942,27 @@ func TestProcessBodyHeatingStatusError(t *testing.T) {
 	}
 }
 
+// TestBodyHeatMode checks body_heat_mode carries the raw HTMODE, so heat-pump
+// heating (4) and cooling (9) stay distinct from gas heating (1).
+func TestBodyHeatMode(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	gauge := pm.metrics.bodyHeatMode.WithLabelValues("B9401", "SPA", "Mode Spa")
+
+	for mode, want := range map[string]float64{"1": 1, "4": 4, "9": 9} {
+		pm.processBodyHeatingStatus("Mode Spa", mode, "B9401", "SPA")
+		if got := gaugeVal(t, gauge); got != want {
+			t.Errorf("HTMODE=%s: body_heat_mode = %v, want %v", mode, got, want)
+		}
+	}
+
+	pm.processBodyHeatingStatus("Mode Spa", "0", "B9401", "SPA")
+
+	pm.processBodyHeatingStatus("Mode Spa", "invalid", "B9401", "SPA")
+	if got := gaugeVal(t, gauge); got != 0 {
+		t.Errorf("unparseable HTMODE changed body_heat_mode to %v, want last value 0", got)
+	}
+}
+
 // Listen mode tests.
 func TestInitializeState(t *testing.T) {
 	poolMonitor := NewPoolMonitor("test", "6680", true)
@@ -1006,28 +2037,68 @@ func TestTrackWaterTempNotInListenMode(t *testing.T) {
 	}
 }
 
+// TestQuietDetection checks that --quiet-detection drops the first-seen
+// "detected" lines while still logging (and counting) real changes.
+func TestQuietDetection(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	for _, quiet := range []bool{false, true} {
+		buf.Reset()
+		pm := NewPoolMonitor("test", "6680", true)
+		pm.quietDetection = quiet
+		pm.trackWaterTemp("Pool", 82.5, ObjectData{})
+		pm.trackCircuit("Pool Light", testStatusOff, ObjectData{})
+		pm.initialPollDone = true
+		pm.trackCircuit("Pool Light", testStatusOn, ObjectData{})
+
+		out := buf.String()
+		if got := strings.Contains(out, "detected"); got == quiet {
+			t.Errorf("quiet=%v: detected lines logged = %v, want %v\n%s", quiet, got, !quiet, out)
+		}
+		if !strings.Contains(out, "Pool Light turned ON") {
+			t.Errorf("quiet=%v: change line missing\n%s", quiet, out)
+		}
+		if pm.previousState.PollChangeCount != 1 {
+			t.Errorf("quiet=%v: PollChangeCount = %d, want 1", quiet, pm.previousState.PollChangeCount)
+		}
+	}
+}
+
 func TestTrackAirTempInListenMode(t *testing.T) {
 	poolMonitor := NewPoolMonitor("test", "6680", true)
-	emptyObj := ObjectData{}
+	air := ObjectData{ObjName: "_A135", Params: map[string]string{"SNAME": "Air Sensor"}}
+	patio := ObjectData{ObjName: "_A136", Params: map[string]string{"SNAME": "Patio"}}
 
 	// First call - should detect new temperature
-	poolMonitor.trackAirTemp(75.0, emptyObj)
+	poolMonitor.trackAirTemp(75.0, air)
 
 	if poolMonitor.previousState == nil {
-		t.Error("previousState should be initialized")
+		t.Fatal("previousState should be initialized")
 	}
 
-	if poolMonitor.previousState.AirTemp != 75.0 {
-		t.Errorf("Expected air temp 75.0, got %v", poolMonitor.previousState.AirTemp)
+	if poolMonitor.previousState.AirTemps["_A135"] != 75.0 {
+		t.Errorf("Expected air temp 75.0, got %v", poolMonitor.previousState.AirTemps["_A135"])
 	}
 
 	// Second call with same temp - should not log change
-	poolMonitor.trackAirTemp(75.0, emptyObj)
+	poolMonitor.trackAirTemp(75.0, air)
+
+	// A second sensor is tracked on its own, not as a change to the first.
+	poolMonitor.previousState.PollChangeCount = 0
+	poolMonitor.trackAirTemp(68.0, patio)
+	if poolMonitor.previousState.PollChangeCount != 0 {
+		t.Errorf("second sensor counted as %d change(s), want 0", poolMonitor.previousState.PollChangeCount)
+	}
 
 	// Third call with different temp - should log change
-	poolMonitor.trackAirTemp(76.0, emptyObj)
-	if poolMonitor.previousState.AirTemp != 76.0 {
-		t.Errorf("Expected air temp 76.0, got %v", poolMonitor.previousState.AirTemp)
+	poolMonitor.trackAirTemp(76.0, air)
+	if poolMonitor.previousState.AirTemps["_A135"] != 76.0 {
+		t.Errorf("Expected air temp 76.0, got %v", poolMonitor.previousState.AirTemps["_A135"])
+	}
+	if poolMonitor.previousState.AirTemps["_A136"] != 68.0 {
+		t.Errorf("Expected patio temp 68.0, got %v", poolMonitor.previousState.AirTemps["_A136"])
 	}
 }
 
@@ -1239,6 +2310,22 @@ func TestResolveCircuitName(t *testing.T) {
 	}
 }
 
+func TestResolveCircuitNameFromConfiguration(t *testing.T) {
+	pm := NewPoolMonitor(testIntelliCenterIP, testIntelliCenterPort, false)
+	pm.configObjects = map[string]intellicenter.ConfigObject{
+		"GRP02": {ID: "GRP02", ObjType: "CIRCUIT", Name: "Patio"},
+		"C0003": {ID: "C0003", ObjType: "CIRCUIT", Name: "Config Name"},
+	}
+	pm.circuitNames["C0003"] = "Live Name"
+
+	if got := pm.resolveCircuitName("GRP02"); got != "Patio" {
+		t.Errorf("config fallback: got %q, want Patio", got)
+	}
+	if got := pm.resolveCircuitName("C0003"); got != "Live Name" {
+		t.Errorf("live SNAME should win over config: got %q", got)
+	}
+}
+
 func TestBuildCircGrpChanges(t *testing.T) {
 	poolMonitor := NewPoolMonitor("test", "6680", true)
 
@@ -1537,6 +2624,22 @@ func TestTrackUnknownEquipment(t *testing.T) {
 	}
 }
 
+// TestHandledTypesNotUnknown checks that every OBJTYP with a push handler is
+// skipped by the unknown-equipment tracker, so a new handler can't leave its
+// type double-reported.
+func TestHandledTypesNotUnknown(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", true)
+	pm.initializeState()
+	for objType := range objTypeHandlers {
+		pm.trackUnknownEquipment(ObjectData{ObjName: "OBJ" + objType, Params: map[string]string{
+			"SNAME": "Handled", "STATUS": "ON", "OBJTYP": objType,
+		}})
+		if _, tracked := pm.previousState.UnknownEquip["OBJ"+objType]; tracked {
+			t.Errorf("handled type %s reported as unknown equipment", objType)
+		}
+	}
+}
+
 func TestTrackUnknownEquipmentNotInListenMode(t *testing.T) {
 	poolMonitor := NewPoolMonitor("test", "6680", false)
 
@@ -1577,6 +2680,32 @@ func TestLogUnknownEquipmentChanged(_ *testing.T) {
 	poolMonitor.logUnknownEquipmentChanged("", "VALVE2", "VALVE:CLOSED", "VALVE:OPEN")
 }
 
+func TestLogUnknownEquipmentUsesConfigName(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	pm := NewPoolMonitor("test", "6680", true)
+	pm.configObjects = map[string]intellicenter.ConfigObject{
+		"VAL01": {ID: "VAL01", ObjType: "VALVE", Name: "Intake Valve"},
+	}
+
+	pm.logUnknownEquipmentDetected("", "VAL01", "VALVE", "OFF")
+	pm.logUnknownEquipmentChanged("", "VAL01", "VALVE:OFF", "VALVE:ON")
+	pm.logUnknownEquipmentDetected("", "VAL02", "VALVE", "OFF") // not in config
+
+	out := buf.String()
+	if !strings.Contains(out, "detected - Intake Valve (VAL01)") {
+		t.Errorf("detected line should carry the config name:\n%s", out)
+	}
+	if !strings.Contains(out, "changed - Intake Valve (VAL01)") {
+		t.Errorf("changed line should carry the config name:\n%s", out)
+	}
+	if !strings.Contains(out, "detected - VAL02 type=VALVE") {
+		t.Errorf("unnamed object should fall back to its objnam:\n%s", out)
+	}
+}
+
 func TestListenModeIntegration(t *testing.T) {
 	poolMonitor := NewPoolMonitor("test", "6680", true)
 	poolMonitor.initializeState()
@@ -1669,7 +2798,7 @@ func TestListenModeIntegration(t *testing.T) {
 		t.Error("Pool temperature should be tracked")
 	}
 
-	if poolMonitor.previousState.AirTemp != 75.2 {
+	if poolMonitor.previousState.AirTemps["_A135"] != 75.2 {
 		t.Error("Air temperature should be tracked")
 	}
 
@@ -1864,6 +2993,145 @@ func TestProcessRawPushNotification(t *testing.T) {
 	}
 }
 
+// TestProcessRawPushNotificationShapes checks both decode paths update metrics:
+// a well-formed frame goes through the typed model, and a frame it rejects (a
+// junk objectList entry) still has its valid items salvaged by the map walk.
+func TestProcessRawPushNotificationShapes(t *testing.T) {
+	poolMonitor := NewPoolMonitor("test", "6680", true)
+	poolMonitor.initializeState()
+	body := func(temp string) map[string]interface{} {
+		return map[string]interface{}{"changes": []interface{}{
+			map[string]interface{}{"objnam": "B5101", "params": map[string]interface{}{
+				"SNAME": "Shape Pool", "TEMP": temp, "OBJTYP": "BODY", "SUBTYP": "POOL", "STATUS": "ON",
+			}},
+		}}
+	}
+	temp := poolMonitor.metrics.poolTemperature.WithLabelValues("B5101", "POOL", "Shape Pool")
+
+	poolMonitor.processRawPushNotification(map[string]interface{}{
+		"command": "WriteParamList", "objectList": []interface{}{body("81")},
+	})
+	if got := gaugeVal(t, temp); got != 81 {
+		t.Errorf("typed path: water temp = %v, want 81", got)
+	}
+
+	poolMonitor.processRawPushNotification(map[string]interface{}{
+		"command": "WriteParamList", "objectList": []interface{}{"junk", body("83")},
+	})
+	if got := gaugeVal(t, temp); got != 83 {
+		t.Errorf("fallback path: water temp = %v, want 83", got)
+	}
+}
+
+// TestLastPushTimestamp checks that a push carrying an object list stamps
+// intellicenter_last_push_timestamp_seconds and one without does not.
+func TestLastPushTimestamp(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", true)
+	pm.initializeState()
+	clock := time.Unix(1_700_000_000, 0)
+	pm.now = func() time.Time { return clock }
+	pm.metrics.lastPushTimestamp.Reset()
+
+	pm.processRawPushNotification(map[string]interface{}{"command": "WriteParamList"})
+	if pm.metrics.lastPushTimestamp.DeleteLabelValues() {
+		t.Error("push without an object list stamped the timestamp")
+	}
+
+	pm.processRawPushNotification(map[string]interface{}{
+		"command": "NotifyList", "objectList": []interface{}{
+			map[string]interface{}{"objnam": "C0001", "params": map[string]interface{}{"STATUS": "ON"}},
+		},
+	})
+	if got := gaugeVal(t, pm.metrics.lastPushTimestamp.WithLabelValues()); got != 1_700_000_000 {
+		t.Errorf("last push timestamp = %v, want 1700000000", got)
+	}
+}
+
+// benchPollObjects is a small installation's poll result: two bodies, a pump
+// and a handful of circuits and features.
+func benchPollObjects() (bodies, pumps, circuits []ObjectData) {
+	bodies = []ObjectData{
+		{ObjName: "B1101", Params: map[string]string{"SNAME": "Pool", "SUBTYP": "POOL", "STATUS": "ON", "TEMP": "82", "HTMODE": "1", "HTSRC": "H0001", "LOTMP": "85", "HITMP": "104"}},
+		{ObjName: "B1202", Params: map[string]string{"SNAME": "Spa", "SUBTYP": "SPA", "STATUS": "OFF", "TEMP": "80", "HTMODE": "0", "HTSRC": "00000", "LOTMP": "100", "HITMP": "104"}},
+	}
+	pumps = []ObjectData{
+		{ObjName: "PMP01", Params: map[string]string{"SNAME": "VS", "STATUS": "10", "RPM": "1800", "PWR": "215", "GPM": "55"}},
+	}
+	circuits = []ObjectData{
+		{ObjName: "C0006", Params: map[string]string{"SNAME": "Pool", "SUBTYP": "POOL", "STATUS": "ON", "FREEZE": "ON"}},
+		{ObjName: "C0001", Params: map[string]string{"SNAME": "Spa", "SUBTYP": "SPA", "STATUS": "OFF", "FREEZE": "ON"}},
+		{ObjName: "C0003", Params: map[string]string{"SNAME": "Pool Light", "SUBTYP": "LIGHT", "STATUS": "OFF"}},
+		{ObjName: "FTR01", Params: map[string]string{"SNAME": "Spa Heat", "SUBTYP": "GENERIC", "STATUS": "OFF"}},
+		{ObjName: "FTR02", Params: map[string]string{"SNAME": "Waterfall", "SUBTYP": "GENERIC", "STATUS": "ON"}},
+	}
+	return bodies, pumps, circuits
+}
+
+// BenchmarkApplyPoll measures turning one poll's objects into metrics, the
+// per-refresh work the engine's recompute repeats on every poll and push.
+func BenchmarkApplyPoll(b *testing.B) {
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(os.Stderr) })
+	pm := NewPoolMonitor("test", "6680", false)
+	bodies, pumps, circuits := benchPollObjects()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		pm.applyBodyTemperatures(bodies)
+		pm.applyPumpData(pumps, 0)
+		pm.applyCircuitStatus(circuits)
+	}
+}
+
+// BenchmarkProcessRawPushNotification measures handling one decoded push,
+// including the typed-model conversion of the generic map.
+func BenchmarkProcessRawPushNotification(b *testing.B) {
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(os.Stderr) })
+	pm := NewPoolMonitor("test", "6680", false)
+	msg := map[string]interface{}{"command": "WriteParamList", "objectList": []interface{}{
+		map[string]interface{}{"changes": []interface{}{
+			map[string]interface{}{"objnam": "B1101", "params": map[string]interface{}{
+				"SNAME": "Pool", "TEMP": "82", "OBJTYP": "BODY", "SUBTYP": "POOL", "STATUS": "ON",
+			}},
+		}},
+	}}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		pm.processRawPushNotification(msg)
+	}
+}
+
+// FuzzProcessRawPushNotification feeds arbitrary JSON objects through push
+// handling, typed path and map-walk fallback alike, and fails on any panic.
+// Run beyond the seed corpus with: go test -fuzz=FuzzProcessRawPushNotification
+func FuzzProcessRawPushNotification(f *testing.F) {
+	for _, seed := range []string{
+		`{"command":"WriteParamList","objectList":[{"changes":[{"objnam":"B1101","params":{"SNAME":"Pool","TEMP":"82","OBJTYP":"BODY","SUBTYP":"POOL","STATUS":"ON"}}]}]}`,
+		`{"command":"NotifyList","objectList":[{"objnam":"PMP01","params":{"SNAME":"VS","RPM":"1800","PWR":"215","OBJTYP":"PUMP"}}]}`,
+		`{"command":"WriteParamList","objectList":["junk",{"changes":[{"objnam":"C0001","params":{"OBJTYP":"CIRCUIT","STATUS":"ON"}}]}]}`,
+		`{"command":"WriteParamList","objectList":[{"changes":[{"objnam":7,"params":{"OBJTYP":["HEATER"],"STATUS":null}}]}]}`,
+		`{"command":"WriteParamList","objectList":[{"changes":"nope"}]}`,
+		`{"objectList":[]}`,
+		`{}`,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	log.SetOutput(io.Discard)
+	f.Cleanup(func() { log.SetOutput(os.Stderr) })
+	f.Fuzz(func(_ *testing.T, data []byte) {
+		var msg map[string]interface{}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return // not a JSON object: never reaches push handling
+		}
+		poolMonitor := NewPoolMonitor("test", "6680", true)
+		poolMonitor.initializeState()
+		poolMonitor.processRawPushNotification(msg)
+	})
+}
+
 func TestProcessObjectListItem(t *testing.T) {
 	poolMonitor := NewPoolMonitor("test", "6680", true)
 	poolMonitor.initializeState()
@@ -2248,7 +3516,7 @@ func TestCleanupStaleMetrics(t *testing.T) {
 			Name: "test_cleanup_metric",
 			Help: "Test metric for cleanup testing",
 		},
-		[]string{"circuit", "name", "subtyp"},
+		[]string{"circuit", "name", "subtyp", "group"},
 	)
 
 	// Register the gauge (unregister at end of test)
@@ -2265,29 +3533,29 @@ func TestCleanupStaleMetrics(t *testing.T) {
 		{
 			name: "deletes stale metrics not in current",
 			previous: map[string]bool{
-				"C01|Pool Light|LIGHT":    true,
-				"C02|Spa Light|LIGHT":     true,
-				"C03|Old Circuit|GENERIC": true,
+				"C01|Pool Light|LIGHT|":    true,
+				"C02|Spa Light|LIGHT|":     true,
+				"C03|Old Circuit|GENERIC|": true,
 			},
 			current: map[string]bool{
-				"C01|Pool Light|LIGHT": true,
-				"C02|Spa Light|LIGHT":  true,
+				"C01|Pool Light|LIGHT|": true,
+				"C02|Spa Light|LIGHT|":  true,
 				// C03 is missing - should be deleted (log output confirms)
 			},
 		},
 		{
 			name: "no deletions when all metrics current",
 			previous: map[string]bool{
-				"C01|Pool Light|LIGHT": true,
+				"C01|Pool Light|LIGHT|Lights": true,
 			},
 			current: map[string]bool{
-				"C01|Pool Light|LIGHT": true,
+				"C01|Pool Light|LIGHT|Lights": true,
 			},
 		},
 		{
 			name:     "handles empty previous",
 			previous: map[string]bool{},
-			current:  map[string]bool{"C01|Pool Light|LIGHT": true},
+			current:  map[string]bool{"C01|Pool Light|LIGHT|": true},
 		},
 		{
 			name: "handles malformed key gracefully",
@@ -2312,7 +3580,7 @@ func TestCleanupStaleMetrics(t *testing.T) {
 			for key := range tt.previous {
 				parts := strings.SplitN(key, "|", metricKeyPartsCount)
 				if len(parts) == metricKeyPartsCount {
-					testGauge.WithLabelValues(parts[0], parts[1], parts[2]).Set(1)
+					testGauge.WithLabelValues(parts...).Set(1)
 				}
 			}
 
@@ -2340,7 +3608,7 @@ func TestActiveCircuitKeyTracking(t *testing.T) {
 	poolMonitor.processCircuitObject(obj)
 
 	// Verify the key was tracked
-	expectedKey := "C01|Pool Light|LIGHT"
+	expectedKey := "C01|Pool Light|LIGHT|"
 	if !poolMonitor.activeCircuitKeys[expectedKey] {
 		t.Errorf("expected activeCircuitKeys to contain %q", expectedKey)
 	}
@@ -2366,7 +3634,7 @@ func TestActiveFeatureKeyTracking(t *testing.T) {
 	poolMonitor.processCircuitObject(obj)
 
 	// Verify the key was tracked
-	expectedKey := "FTR01|Spa Jets|GENERIC"
+	expectedKey := "FTR01|Spa Jets|GENERIC|"
 	if !poolMonitor.activeFeatureKeys[expectedKey] {
 		t.Errorf("expected activeFeatureKeys to contain %q", expectedKey)
 	}
@@ -2401,10 +3669,10 @@ func TestStaleMetricCleanupIntegration(t *testing.T) {
 	poolMonitor.applyCircuitStatus(objs)
 
 	// Verify both keys are tracked.
-	if !poolMonitor.activeCircuitKeys["C01|Pool Light|LIGHT"] {
+	if !poolMonitor.activeCircuitKeys["C01|Pool Light|LIGHT|"] {
 		t.Error("expected C01 to be tracked after first call")
 	}
-	if !poolMonitor.activeCircuitKeys["C02|Spa Light|LIGHT"] {
+	if !poolMonitor.activeCircuitKeys["C02|Spa Light|LIGHT|"] {
 		t.Error("expected C02 to be tracked after first call")
 	}
 }
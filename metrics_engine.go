@@ -2,8 +2,15 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"maps"
+	"os/signal"
+	"slices"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/astrostl/pentameter/intellicenter"
 	"github.com/prometheus/client_golang/prometheus"
@@ -19,36 +26,196 @@ import (
 // the entire equipment set out of the engine's raw snapshot — identical to a
 // legacy poll — so cross-object logic (freeze protection, thermal interpretation,
 // feature visibility, stale cleanup) stays exactly as published.
+//
+// With several controllers (a comma-separated --ic-ip) each gets its own
+// PoolMonitor, engine and metric set, registered on the shared registry under a
+// controller label; one /metrics endpoint, pushgateway and mDNS advertisement
+// serve them all.
+//
+// SIGINT/SIGTERM stop it gracefully: the HTTP server finishes in-flight
+// scrapes, the engines close their panel connections, and the mDNS advertiser
+// says goodbye.
 func runMetricsEngine(cfg *appConfig, registry *prometheus.Registry) {
-	pm := NewPoolMonitor(cfg.intelliCenterIP, cfg.intelliCenterPort, false)
-	engine := intellicenter.NewEngine(cfg.intelliCenterIP, cfg.intelliCenterPort, cfg.pollInterval)
-	engine.Logf = log.Printf
-	engine.Resolve = newDiscoveryResolver(cfg)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	var pusher *gatewayPusher
+	if cfg.pushgatewayURL != "" {
+		pusher = newGatewayPusher(cfg.pushgatewayURL, cfg.pushgatewayJob, cfg.pushgatewayInst, registry)
+		log.Printf("Pushing metrics to %s after each successful scan", cfg.pushgatewayURL)
+	}
+	addrs := []string{cfg.intelliCenterIP}
+	if len(cfg.controllers) > 1 {
+		addrs = cfg.controllers
+	}
+	monitors := make([]*PoolMonitor, 0, len(addrs))
+	enginesDone := make([]<-chan struct{}, 0, len(addrs))
+	for _, addr := range addrs {
+		pm, engineDone := startController(ctx, cfg, addr, registry, pusher, len(addrs) > 1)
+		monitors = append(monitors, pm)
+		enginesDone = append(enginesDone, engineDone)
+	}
+	if pusher != nil {
+		go pusher.run(ctx)
+	}
+
+	// Advertise over mDNS so this exporter is discoverable, matching the legacy path.
+	if adv, err := StartMDNSAdvertiser(cfg.httpPort, false); err != nil {
+		log.Printf("Warning: mDNS advertisement disabled: %v", err)
+	} else {
+		defer func() {
+			if cerr := adv.Close(); cerr != nil {
+				log.Printf("Error closing mDNS advertiser: %v", cerr)
+			}
+		}()
+	}
+
+	if cfg.debugAddr != "" {
+		startDebugServer(ctx, cfg.debugAddr)
+	}
+
+	// --debug-endpoint is refused with several controllers, so only a lone
+	// controller's monitor ever carries a state engine.
+	pm := monitors[0]
+	ln, err := bindMetricsServer(registry, pm, cfg)
+	if err != nil {
+		log.Fatalf("HTTP server failed: %v", err)
+	}
+	log.Printf("Starting Prometheus metrics server on :%s", cfg.httpPort)
+	log.Printf("Metrics available at http://localhost:%s%s", cfg.httpPort, cfg.metricsPath)
+	if pm.stateEngine != nil {
+		log.Printf("Debug state available at http://localhost:%s%s", cfg.httpPort, debugStatePath)
+	}
+	if err := serveHTTP(ctx, ln); err != nil {
+		log.Fatalf("HTTP server failed: %v", err)
+	}
+	log.Printf("Shutting down")
+	// The engines get their --shutdown-grace to finish a poll in flight, then
+	// close their connections.
+	stopTimeout := cfg.shutdownGrace + httpShutdownTimeout
+	deadline := time.After(stopTimeout)
+	for _, engineDone := range enginesDone {
+		select {
+		case <-engineDone:
+		case <-deadline:
+			log.Printf("Engine did not stop within %v; exiting anyway", stopTimeout)
+			return
+		}
+	}
+}
+
+// startController runs the engine for the controller at addr, wired to its own
+// PoolMonitor, and returns that monitor with the engine's done channel. The
+// monitor's metrics register under a controller label when labeled; otherwise
+// they register unlabeled, exactly as a single-controller process always has.
+func startController(ctx context.Context, cfg *appConfig, addr string, registry *prometheus.Registry,
+	pusher *gatewayPusher, labeled bool,
+) (*PoolMonitor, <-chan struct{}) {
+	controllerCfg := *cfg
+	controllerCfg.intelliCenterIP = addr
+	pm := newMetricsMonitor(&controllerCfg)
+	r := metricsRegisterer(registry, cfg)
+	if labeled {
+		r = prometheus.WrapRegistererWith(prometheus.Labels{controllerLabel: addr}, r)
+	}
+	if cfg.watchdogTimeout > 0 || cfg.startupTimeout > 0 {
+		pm.watchdog = newFailureWatchdog(cfg.watchdogTimeout, cfg.startupTimeout)
+	}
+	pm.pushgateway = pusher
+	r.MustRegister(newDataAgeCollector(pm))
+	pm.metrics.register(r, cfg)
+
+	engine := newEngine(&controllerCfg, pm.metrics)
+	pm.metrics.instrumentEngine(engine)
+	if cfg.debugEndpoint {
+		pm.stateEngine = engine
+	}
+	return pm, startMetricsEngine(ctx, pm, engine)
+}
 
+// newMetricsMonitor builds the metrics-mode PoolMonitor (listenMode=false,
+// never connected) with the interpretation settings from cfg.
+func newMetricsMonitor(cfg *appConfig) *PoolMonitor {
+	pm := newPoolMonitor(cfg.intelliCenterIP, cfg.intelliCenterPort, false, cfg.tempUnits == tempUnitsCelsius)
+	pm.declaredBodies = cfg.bodies
+	pm.enumMap = cfg.enumMap
+	pm.masterCircuits = cfg.masterCircuits
+	pm.boardTempKey = cfg.boardTempKey
+	pm.circuitTimerKey = cfg.circuitTimerKey
+	pm.chemFlowKey = cfg.chemFlowKey
+	pm.freezeObject = cfg.freezeObject
+	pm.bodyGallons = cfg.bodyGallons
+	pm.equipmentStatus = cfg.equipmentStatus
+	pm.booleanStatus = cfg.statusEncoding == statusEncodingBoolean
+	if cfg.pumpAnomaly > 0 {
+		pm.pumpAnomaly = newPumpAnomalyDetector(cfg.pumpAnomaly)
+	}
+	return pm
+}
+
+// startDebugServer serves the debug mux on its own listener. Debugging is
+// secondary, so a bind failure is logged and metrics carry on without it.
+func startDebugServer(ctx context.Context, addr string) {
+	ln, err := bindDebugServer(addr)
+	if err != nil {
+		log.Printf("Warning: debug server disabled: %v", err)
+		return
+	}
+	log.Printf("Debug endpoints available at http://%s/debug/pprof/", ln.Addr())
+	go func() {
+		if err := serveHTTP(ctx, ln); err != nil {
+			log.Printf("Debug server stopped: %v", err)
+		}
+	}()
+}
+
+// startMetricsEngine wires the engine's scan results and change stream to pm's
+// gauges, then runs the engine in the background until ctx is canceled. The
+// returned channel closes once the engine has stopped and closed its
+// connections.
+func startMetricsEngine(ctx context.Context, pm *PoolMonitor, engine *intellicenter.Engine) <-chan struct{} {
 	// Serialize recomputes: the push subscriber and the OnScan callback both
 	// drive refreshFromEngine, which mutates shared PoolMonitor metric state.
 	var mu sync.Mutex
 	ready := false
+	inventoryLogged := false
 
 	// Logging is change-gated in refreshFromEngine (logChangedf), so push- and
 	// poll-driven recomputes both log only real transitions; no quiet toggle.
-	recompute := func() {
-		mu.Lock()
+	recompute := func(poll bool) {
+		lockTimed(&mu, "recompute")
 		defer mu.Unlock()
 		pm.refreshFromEngine(engine)
+		if poll {
+			pm.samplePumpEfficiency(engine.Snapshot().Pumps)
+			pm.sampleReadings(engine.RawObjects())
+		}
 	}
 
 	engine.OnScan = func(err error) {
-		if err != nil {
-			connectionFailure.Set(1)
+		if pm.watchdog != nil {
+			pm.watchdog.record(err)
+		}
+		if !pm.metrics.recordScanResult(err) {
 			return
 		}
-		connectionFailure.Set(0)
 		mu.Lock()
 		ready = true
+		firstScan := !inventoryLogged
+		inventoryLogged = true
 		mu.Unlock()
-		recompute() // refresh at the engine's poll cadence (logs only changes)
+		recompute(true) // refresh at the engine's poll cadence (logs only changes)
 		pm.updateRefreshTimestamp()
+		if pm.pushgateway != nil {
+			pm.pushgateway.trigger()
+		}
+		if firstScan {
+			snap := engine.Snapshot()
+			log.Print(inventorySummary(snap))
+			for _, warning := range duplicateNames(snap) {
+				log.Print(warning)
+			}
+		}
 	}
 
 	// Push-driven freshness: every change recomputes (quietly) between polls.
@@ -59,44 +226,129 @@ func runMetricsEngine(cfg *appConfig, registry *prometheus.Registry) {
 			r := ready
 			mu.Unlock()
 			if r {
-				recompute()
+				recompute(false)
 			}
 		}
 	}()
 
-	go func() { _ = engine.Run(context.Background()) }()
+	if pm.watchdog != nil {
+		go pm.watchdog.run(ctx)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = engine.Run(ctx)
+	}()
+	return done
+}
 
-	// Advertise over mDNS so this exporter is discoverable, matching the legacy path.
-	if adv, err := StartMDNSAdvertiser(cfg.httpPort, false); err != nil {
-		log.Printf("Warning: mDNS advertisement disabled: %v", err)
-	} else {
-		defer func() {
-			if cerr := adv.Close(); cerr != nil {
-				log.Printf("Error closing mDNS advertiser: %v", cerr)
-			}
-		}()
+// inventorySummary is the one-time "what did pentameter find" line logged after
+// the first successful scan, so a first run confirms its equipment without
+// scraping /metrics.
+func inventorySummary(snap intellicenter.Snapshot) string {
+	parts := []string{
+		inventoryPart("bodies", snap.Bodies, func(b intellicenter.Body) string { return b.Name }),
+		inventoryPart("pumps", snap.Pumps, func(p intellicenter.Pump) string { return p.Name }),
+		inventoryPart("circuits", snap.Circuits, func(c intellicenter.Circuit) string { return c.Name }),
+		inventoryPart("heaters", snap.Heaters, func(h intellicenter.Heater) string { return h.Name }),
+		inventoryPart("sensors", snap.Sensors, func(s intellicenter.Sensor) string { return s.Name }),
 	}
+	return "Discovered equipment: " + strings.Join(parts, "; ")
+}
 
-	ln, err := bindMetricsServer(registry, pm, cfg.httpPort)
-	if err != nil {
-		log.Fatalf("HTTP server failed: %v", err)
+// inventoryPart renders one kind as "N label (name, name, ...)", names sorted.
+func inventoryPart[T any](label string, objs map[string]T, name func(T) string) string {
+	if len(objs) == 0 {
+		return "0 " + label
 	}
-	log.Printf("Starting Prometheus metrics server on :%s", cfg.httpPort)
-	log.Printf("Metrics available at http://localhost:%s/metrics", cfg.httpPort)
-	if err := serveMetrics(ln); err != nil {
-		log.Fatalf("HTTP server failed: %v", err)
+	names := make([]string, 0, len(objs))
+	for _, o := range objs {
+		names = append(names, name(o))
+	}
+	slices.Sort(names)
+	return fmt.Sprintf("%d %s (%s)", len(objs), label, strings.Join(names, ", "))
+}
+
+// duplicateNames returns a warning for each name shared by several objects of
+// one kind. Their metrics stay distinct through the objnam label, but
+// dashboards and alerts that select by name silently merge or overwrite them.
+func duplicateNames(snap intellicenter.Snapshot) []string {
+	var warnings []string
+	warnings = append(warnings, duplicatePart("bodies", snap.Bodies, func(b intellicenter.Body) string { return b.Name })...)
+	warnings = append(warnings, duplicatePart("pumps", snap.Pumps, func(p intellicenter.Pump) string { return p.Name })...)
+	warnings = append(warnings, duplicatePart("circuits", snap.Circuits, func(c intellicenter.Circuit) string { return c.Name })...)
+	warnings = append(warnings, duplicatePart("heaters", snap.Heaters, func(h intellicenter.Heater) string { return h.Name })...)
+	warnings = append(warnings, duplicatePart("sensors", snap.Sensors, func(s intellicenter.Sensor) string { return s.Name })...)
+	return warnings
+}
+
+// duplicatePart renders one warning per name shared within one kind, sorted
+// by name, listing the sharing objnams.
+func duplicatePart[T any](label string, objs map[string]T, name func(T) string) []string {
+	byName := make(map[string][]string)
+	for objnam, o := range objs {
+		if n := name(o); n != "" {
+			byName[n] = append(byName[n], objnam)
+		}
+	}
+	var warnings []string
+	for _, n := range slices.Sorted(maps.Keys(byName)) {
+		objnams := byName[n]
+		if len(objnams) < 2 {
+			continue
+		}
+		slices.Sort(objnams)
+		warnings = append(warnings, fmt.Sprintf("Warning: %d %s share the name %q (%s); their metrics differ only by objnam, "+
+			"so rename them on the panel or select by objnam in dashboards", len(objnams), label, n, strings.Join(objnams, ", ")))
+	}
+	return warnings
+}
+
+// instrumentEngine wires the engine's diagnostic hooks to m's connection-level
+// metrics. Shared by every mode that exports metrics.
+func (m *controllerMetrics) instrumentEngine(engine *intellicenter.Engine) {
+	engine.OnPushSkipped = m.pushesSkipped.Inc
+	engine.OnPushDropped = m.pushesDropped.Inc
+	engine.OnReconnect = m.reconnects.Inc
+	engine.OnReboot = func(time.Duration) { m.panelReboots.Inc() }
+	engine.OnQuery = func(command string, rtt time.Duration) {
+		m.queryResponseSeconds.WithLabelValues(command).Observe(rtt.Seconds())
+	}
+	engine.OnEmptyResponse = func(query string) {
+		m.emptyResponses.WithLabelValues(query).Inc()
+	}
+}
+
+// recordScanResult sets the failure gauges from an engine scan result and
+// reports whether the scan succeeded. A QueryError means the panel was reached
+// but a query failed, so the transport is known good; any other error is a
+// transport failure, and says nothing new about queries.
+func (m *controllerMetrics) recordScanResult(err error) bool {
+	switch {
+	case err == nil:
+		m.connectionFailure.Set(0)
+		m.queryFailure.Set(0)
+		return true
+	case intellicenter.IsQueryError(err):
+		m.connectionFailure.Set(0)
+		m.queryFailure.Set(1)
+	default:
+		m.connectionFailure.Set(1)
 	}
+	return false
 }
 
 // refreshFromEngine recomputes every metric from the engine's current raw snapshot,
 // reproducing a full poll. Object groups are applied in a fixed order
-// (bodies → air → pumps → freeze → circuits → thermal) so dependent state
-// (referenced heaters, freeze-protection active) is set first.
+// (system → bodies → air → pumps → filtration → freeze → circuits → thermal) so dependent
+// state (referenced heaters, pump running, freeze-protection active) is set first.
 func (pm *PoolMonitor) refreshFromEngine(e *intellicenter.Engine) {
 	pm.featureConfig = e.Config()
+	pm.configObjects = e.Objects()
 
-	var bodies, circuits, pumps, heaters, sensors, pmpCircs []ObjectData
-	for _, o := range e.RawObjects() {
+	raw := e.RawObjects()
+	var bodies, circuits, pumps, heaters, sensors, pmpCircs, circGrps, systems, scheds, alerts, chems []ObjectData
+	for _, o := range raw {
 		od := ObjectData{ObjName: o.ObjName, Params: o.Params}
 		switch o.Kind {
 		case intellicenter.KindBody:
@@ -111,14 +363,36 @@ func (pm *PoolMonitor) refreshFromEngine(e *intellicenter.Engine) {
 			sensors = append(sensors, od)
 		case intellicenter.KindPMPCirc:
 			pmpCircs = append(pmpCircs, od)
+		case intellicenter.KindCircGrp:
+			circGrps = append(circGrps, od)
+		case intellicenter.KindSystem:
+			systems = append(systems, od)
+		case intellicenter.KindSched:
+			scheds = append(scheds, od)
+		case intellicenter.KindAlert:
+			alerts = append(alerts, od)
+		case intellicenter.KindChem:
+			chems = append(chems, od)
 		}
 	}
 
+	pm.applySystemInfo(systems) // sets pm.panelMetric before any temperature is exported
 	pm.applyBodyTemperatures(bodies)
 	pm.applyAirTemperature(sensors)
 	pm.applyPumpData(pumps, 0)         // sets pm.pumpRunning (RPM>0 per pump)
 	pm.applyPumpAssociations(pmpCircs) // sets pm.circuitToPumps (circuit→pumps)
+	pm.applyBodyFiltration(bodies)     // needs pumpRunning + circuitToPumps
+	pm.applyTurnovers(bodies)          // needs pumpRunning/pumpFlow + circuitToPumps
 	pm.applyFreezeProtection(circuits) // _FEA2 lives among the circuit objects
+	pm.cacheCircuitNames(circuits)     // group SNAMEs for the group label
+	pm.applyCircuitGroups(circGrps)    // sets pm.circuitGroups (member→group names)
 	pm.applyCircuitStatus(circuits)    // gates circuit/feature ON on pump delivery
+	pm.applyCircuitTimers(circuits)    // --circuit-timer-key only
 	pm.applyThermalStatus(heaters)
+	pm.applyHeaterActive(heaters) // needs referencedHeaters from the bodies
+	pm.applySchedules(scheds)     // --schedules only; needs circuit names
+	pm.applyAlerts(alerts)        // --alerts only; none tracked reports 0
+	pm.applyChemistry(chems)      // IntelliChem panels only
+	pm.applyEnumMetrics(raw)      // --enum-map tables, across every kind
+	pm.pruneEquipmentStatus(raw)
 }
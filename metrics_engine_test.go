@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -14,7 +17,7 @@ import (
 // TestRefreshFromEngine drives the engine against a mock IntelliCenter, then
 // recomputes all metrics from its snapshot and asserts the gauge values match
 // the legacy interpretation (temps, pump RPM, circuit on/off, freeze coloring,
-// thermal status + setpoint).
+// thermal status + setpoint, circuit-group labels).
 func TestRefreshFromEngine(t *testing.T) {
 	responses := map[string]IntelliCenterResponse{
 		"GetParamList:OBJTYP=CIRCUIT": {ObjectList: []ObjectData{
@@ -22,6 +25,11 @@ func TestRefreshFromEngine(t *testing.T) {
 			{ObjName: "C0002", Params: map[string]string{"SNAME": "Cleaner", "STATUS": "ON", "OBJTYP": "CIRCUIT", "SUBTYP": "GENERIC", "FREEZE": "ON"}},
 			{ObjName: "FTR01", Params: map[string]string{"SNAME": "Waterfall", "STATUS": "ON", "OBJTYP": "CIRCUIT", "SUBTYP": "GENERIC", "FREEZE": "OFF"}},
 			{ObjName: "_FEA2", Params: map[string]string{"SNAME": "Freeze", "STATUS": "ON", "OBJTYP": "CIRCUIT", "SUBTYP": "GENERIC"}},
+			{ObjName: "GRP01", Params: map[string]string{"SNAME": "Lights", "STATUS": "ON", "OBJTYP": "CIRCUIT", "SUBTYP": "CIRCGRP"}},
+		}},
+		"GetParamList:OBJTYP=CIRCGRP": {ObjectList: []ObjectData{
+			{ObjName: "c0101", Params: map[string]string{"PARENT": "GRP01", "CIRCUIT": "C0001"}},
+			{ObjName: "c0102", Params: map[string]string{"PARENT": "GRP01", "CIRCUIT": "FTR01"}},
 		}},
 		"GetParamList:OBJTYP=BODY": {ObjectList: []ObjectData{
 			{ObjName: "B1101", Params: map[string]string{
@@ -60,14 +68,14 @@ func TestRefreshFromEngine(t *testing.T) {
 		got  float64
 		want float64
 	}{
-		{"circuit Pool Light on", gaugeVal(t, circuitStatus.WithLabelValues("C0001", "Pool Light", "LIGHT")), 1},
-		{"circuit Cleaner freeze-protected", gaugeVal(t, circuitStatus.WithLabelValues("C0002", "Cleaner", "GENERIC")), 2},
-		{"feature Waterfall on", gaugeVal(t, featureStatus.WithLabelValues("FTR01", "Waterfall", "GENERIC")), 1},
-		{"water temp", gaugeVal(t, poolTemperature.WithLabelValues("POOL", "Pool")), 82},
-		{"air temp", gaugeVal(t, airTemperature.WithLabelValues("AIR", "Air")), 75},
-		{"pump rpm", gaugeVal(t, pumpRPM.WithLabelValues("PMP01", "Pump")), 2000},
-		{"thermal heating", gaugeVal(t, thermalStatus.WithLabelValues("H0001", "Gas", "GAS")), float64(thermalStatusHeating)},
-		{"thermal low setpoint", gaugeVal(t, thermalLowSetpoint.WithLabelValues("H0001", "Gas", "GAS")), 85},
+		{"circuit Pool Light on", gaugeVal(t, pm.metrics.circuitStatus.WithLabelValues("C0001", "Pool Light", "LIGHT", "Lights")), 1},
+		{"circuit Cleaner freeze-protected", gaugeVal(t, pm.metrics.circuitStatus.WithLabelValues("C0002", "Cleaner", "GENERIC", "")), 2},
+		{"feature Waterfall on", gaugeVal(t, pm.metrics.featureStatus.WithLabelValues("FTR01", "Waterfall", "GENERIC", "Lights")), 1},
+		{"water temp", gaugeVal(t, pm.metrics.poolTemperature.WithLabelValues("B1101", "POOL", "Pool")), 82},
+		{"air temp", gaugeVal(t, pm.metrics.airTemperature.WithLabelValues("_A135", "AIR", "Air")), 75},
+		{"pump rpm", gaugeVal(t, pm.metrics.pumpRPM.WithLabelValues("PMP01", "Pump")), 2000},
+		{"thermal heating", gaugeVal(t, pm.metrics.thermalStatus.WithLabelValues("H0001", "Gas", "GAS")), float64(thermalStatusHeating)},
+		{"thermal low setpoint", gaugeVal(t, pm.metrics.thermalLowSetpoint.WithLabelValues("H0001", "Gas", "GAS")), 85},
 	}
 	for _, c := range checks {
 		if c.got != c.want {
@@ -81,6 +89,188 @@ func TestRefreshFromEngine(t *testing.T) {
 	}
 }
 
+// TestSkippedPushUpdatesGauge has the panel push a body temperature change on
+// the request connection while a poll awaits its pump response. The skipped
+// push must still land in engine state, so the water-temperature gauge reflects
+// it without waiting for the push connection or the next poll.
+func TestSkippedPushUpdatesGauge(t *testing.T) {
+	responses := map[string]IntelliCenterResponse{
+		"GetParamList:OBJTYP=BODY": {ObjectList: []ObjectData{
+			{ObjName: "B2101", Params: map[string]string{"SNAME": "Skip Pool", "STATUS": "ON", "TEMP": "82", "SUBTYP": "POOL"}},
+		}},
+	}
+	pushes := map[string]map[string]any{
+		// Bodies are scanned before pumps, so B2101 is known when this arrives.
+		"GetParamList:OBJTYP=PUMP": {
+			"command": "NotifyList",
+			"objectList": []any{
+				map[string]any{"objnam": "B2101", "params": map[string]any{"TEMP": "90"}},
+			},
+		},
+	}
+	server := createMockWebSocketServerWithPushes(t, responses, pushes)
+	defer server.Close()
+
+	host, port, _ := strings.Cut(strings.TrimPrefix(server.URL, "http://"), ":")
+	engine := intellicenter.NewEngine(host, port, time.Hour) // long poll: baseline only
+	var skipped atomic.Int32
+	engine.OnPushSkipped = func() { skipped.Add(1) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = engine.Run(ctx) }()
+	waitForCond(t, func() bool { return engine.Snapshot().Bodies["B2101"].Temp == 90 })
+
+	pm := NewPoolMonitor(host, port, false)
+	pm.refreshFromEngine(engine)
+	if got := gaugeVal(t, pm.metrics.poolTemperature.WithLabelValues("B2101", "POOL", "Skip Pool")); got != 90 {
+		t.Errorf("water temp gauge: got %v, want 90 (from the skipped push)", got)
+	}
+	if skipped.Load() == 0 {
+		t.Error("OnPushSkipped should fire for the skipped push")
+	}
+}
+
+// TestMetricsEngineTracksPanelOverPolls is an end-to-end run of the metrics
+// pipeline: startMetricsEngine drives a real engine through several poll ticks
+// against a scripted panel whose values change between polls, then loses and
+// regains the panel. Gauges must follow each change, and the refresh timestamp
+// and connection_failure must reflect every transition.
+func TestMetricsEngineTracksPanelOverPolls(t *testing.T) {
+	body := func(temp string) IntelliCenterResponse {
+		return IntelliCenterResponse{ObjectList: []ObjectData{
+			{ObjName: "B3101", Params: map[string]string{"SNAME": "E2E Pool", "STATUS": "ON", "TEMP": temp, "SUBTYP": "POOL"}},
+		}}
+	}
+	pump := func(rpm string) IntelliCenterResponse {
+		return IntelliCenterResponse{ObjectList: []ObjectData{
+			{ObjName: "PMP31", Params: map[string]string{"SNAME": "E2E Pump", "STATUS": "ON", "RPM": rpm, "WATTS": "500", "GPM": "40"}},
+		}}
+	}
+	mock := newScriptedMock(map[string]IntelliCenterResponse{
+		"GetParamList:OBJTYP=BODY": body("80"),
+		"GetParamList:OBJTYP=PUMP": pump("1500"),
+	}, nil)
+	server := mock.serve(t)
+	defer server.Close()
+
+	host, port, _ := strings.Cut(strings.TrimPrefix(server.URL, "http://"), ":")
+	pm := NewPoolMonitor(host, port, false)
+	pm.metrics.connectionFailure.Set(1)
+	engine := intellicenter.NewEngine(host, port, 50*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	startMetricsEngine(ctx, pm, engine)
+
+	temp := pm.metrics.poolTemperature.WithLabelValues("B3101", "POOL", "E2E Pool")
+	rpm := pm.metrics.pumpRPM.WithLabelValues("PMP31", "E2E Pump")
+
+	// The refresh timestamp is set last in a scan, so waiting on it also waits
+	// for the gauges and connection_failure of that scan.
+	waitForCond(t, func() bool { return gaugeVal(t, pm.metrics.lastRefreshTimestamp) != 0 })
+	if gaugeVal(t, temp) != 80 || gaugeVal(t, rpm) != 1500 || gaugeVal(t, pm.metrics.connectionFailure) != 0 {
+		t.Errorf("after baseline: temp=%v rpm=%v connection_failure=%v, want 80 1500 0",
+			gaugeVal(t, temp), gaugeVal(t, rpm), gaugeVal(t, pm.metrics.connectionFailure))
+	}
+
+	// Values change between polls; the next ticks must pick them up.
+	mock.set("GetParamList:OBJTYP=BODY", body("84"))
+	waitForCond(t, func() bool { return gaugeVal(t, temp) == 84 })
+	mock.set("GetParamList:OBJTYP=PUMP", pump("2400"))
+	waitForCond(t, func() bool { return gaugeVal(t, rpm) == 2400 })
+
+	// The panel goes away: polls fail and connection_failure is raised, while
+	// the last good values stay published.
+	mock.setDown(true)
+	waitForCond(t, func() bool { return gaugeVal(t, pm.metrics.connectionFailure) == 1 })
+	if got := gaugeVal(t, temp); got != 84 {
+		t.Errorf("water temp while disconnected = %v, want last good 84", got)
+	}
+
+	// It comes back with new values: the engine reconnects (after its backoff)
+	// and the gauges and health metrics recover.
+	pm.metrics.lastRefreshTimestamp.Set(0)
+	mock.set("GetParamList:OBJTYP=BODY", body("86"))
+	mock.setDown(false)
+	waitForCondWithin(t, 10*time.Second, func() bool { return gaugeVal(t, pm.metrics.lastRefreshTimestamp) != 0 })
+	if gaugeVal(t, temp) != 86 || gaugeVal(t, pm.metrics.connectionFailure) != 0 {
+		t.Errorf("after reconnect: temp=%v connection_failure=%v, want 86 0",
+			gaugeVal(t, temp), gaugeVal(t, pm.metrics.connectionFailure))
+	}
+}
+
+func TestRecordScanResult(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantOK         bool
+		wantConnection float64
+		wantQuery      float64
+	}{
+		{"query failure", fmt.Errorf("poll: %w", &intellicenter.QueryError{Command: "GetParamList", Reason: "response=400"}), false, 0, 1},
+		{"transport failure leaves query state", errors.New("dial: connection refused"), false, 1, 1},
+		{"success clears both", nil, true, 0, 0},
+		{"transport failure", errors.New("read: i/o timeout"), false, 1, 0},
+	}
+	m := defineControllerMetrics(false)
+	for _, tt := range tests {
+		if got := m.recordScanResult(tt.err); got != tt.wantOK {
+			t.Errorf("%s: ok = %v, want %v", tt.name, got, tt.wantOK)
+		}
+		if got := gaugeVal(t, m.connectionFailure); got != tt.wantConnection {
+			t.Errorf("%s: connection_failure = %v, want %v", tt.name, got, tt.wantConnection)
+		}
+		if got := gaugeVal(t, m.queryFailure); got != tt.wantQuery {
+			t.Errorf("%s: query_failure = %v, want %v", tt.name, got, tt.wantQuery)
+		}
+	}
+}
+
+func TestInventorySummary(t *testing.T) {
+	snap := intellicenter.Snapshot{
+		Bodies:   map[string]intellicenter.Body{"B1202": {Name: "Spa"}, "B1101": {Name: "Pool"}},
+		Pumps:    map[string]intellicenter.Pump{"PMP01": {Name: "VS"}},
+		Circuits: map[string]intellicenter.Circuit{"C0003": {Name: "Pool Light"}, "FTR01": {Name: "Jets"}},
+	}
+	want := "Discovered equipment: 2 bodies (Pool, Spa); 1 pumps (VS); 2 circuits (Jets, Pool Light); 0 heaters; 0 sensors"
+	if got := inventorySummary(snap); got != want {
+		t.Errorf("inventorySummary =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDuplicateNames(t *testing.T) {
+	snap := intellicenter.Snapshot{
+		Bodies: map[string]intellicenter.Body{"B1101": {Name: "Pool"}, "B1202": {Name: "Spa"}},
+		Circuits: map[string]intellicenter.Circuit{
+			"C0004": {Name: "Light"}, "C0003": {Name: "Light"}, "FTR01": {Name: "Jets"}, "FTR02": {Name: "Jets"}, "C0005": {Name: "Cleaner"},
+		},
+	}
+	got := duplicateNames(snap)
+	if len(got) != 2 || !strings.Contains(got[0], `2 circuits share the name "Jets" (FTR01, FTR02)`) ||
+		!strings.Contains(got[1], `2 circuits share the name "Light" (C0003, C0004)`) {
+		t.Errorf("duplicateNames = %q", got)
+	}
+	if got := duplicateNames(intellicenter.Snapshot{Bodies: snap.Bodies}); len(got) != 0 {
+		t.Errorf("unique names warned: %q", got)
+	}
+}
+
+// TestDuplicateNamedCircuits checks two circuits sharing an SNAME export two
+// series, told apart by objnam, instead of one overwriting the other.
+func TestDuplicateNamedCircuits(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	pm.applyCircuitStatus([]ObjectData{
+		{ObjName: "C0081", Params: map[string]string{"SNAME": "Twin Light", "SUBTYP": "LIGHT", "STATUS": "ON"}},
+		{ObjName: "C0082", Params: map[string]string{"SNAME": "Twin Light", "SUBTYP": "LIGHT", "STATUS": "ON"}},
+	})
+	if got := gaugeVal(t, pm.metrics.circuitStatus.WithLabelValues("C0081", "Twin Light", "LIGHT", "")); got != 1 {
+		t.Errorf("C0081 circuit_status = %v, want 1", got)
+	}
+	if got := gaugeVal(t, pm.metrics.circuitStatus.WithLabelValues("C0082", "Twin Light", "LIGHT", "")); got != 1 {
+		t.Errorf("C0082 circuit_status = %v, want 1", got)
+	}
+}
+
 // gaugeVal reads a gauge's current value via the metric model (no extra deps).
 func gaugeVal(t *testing.T, g prometheus.Gauge) float64 {
 	t.Helper()
@@ -91,9 +281,24 @@ func gaugeVal(t *testing.T, g prometheus.Gauge) float64 {
 	return m.GetGauge().GetValue()
 }
 
+// counterVal reads a counter's current value via the metric model.
+func counterVal(t *testing.T, c prometheus.Counter) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		t.Fatalf("write metric: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
 func waitForCond(t *testing.T, cond func() bool) {
 	t.Helper()
-	deadline := time.After(3 * time.Second)
+	waitForCondWithin(t, 3*time.Second, cond)
+}
+
+func waitForCondWithin(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.After(timeout)
 	for {
 		if cond() {
 			return
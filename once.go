@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/astrostl/pentameter/intellicenter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Exit statuses of --once.
+const (
+	exitOnceOK     = 0
+	exitOnceFailed = 1
+)
+
+// runOnce connects, waits for the engine's first scan, writes every gathered
+// metric to out in the Prometheus text format and returns the exit status.
+// Nothing is served or advertised, so it suits cron jobs, node_exporter's
+// textfile collector and quick checks. The first scan decides the outcome: a
+// panel that can't be reached fails instead of being retried.
+func runOnce(cfg *appConfig, out io.Writer) int {
+	pm := newMetricsMonitor(cfg)
+	registry := createPrometheusRegistry(cfg, pm.metrics)
+	engine, err := scanOnce(cfg, pm.metrics)
+	if err != nil {
+		log.Printf("Scrape failed: %v", err)
+		return exitOnceFailed
+	}
+
+	pm.refreshFromEngine(engine)
+	if err := writeMetrics(out, registry); err != nil {
+		log.Printf("Writing metrics failed: %v", err)
+		return exitOnceFailed
+	}
+	return exitOnceOK
+}
+
+// scanOnce runs an engine until its first scan (baseline and static config)
+// completes, stops it, and returns it with that scan's result. The stopped
+// engine still answers Snapshot, RawObjects and Config.
+func scanOnce(cfg *appConfig, m *controllerMetrics) (*intellicenter.Engine, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	engine := newEngine(cfg, m)
+	m.instrumentEngine(engine)
+
+	scanned := make(chan error, 1)
+	engine.OnScan = func(err error) {
+		m.recordScanResult(err)
+		select {
+		case scanned <- err:
+		default: // only the first scan counts
+		}
+	}
+	engineDone := make(chan struct{})
+	go func() {
+		defer close(engineDone)
+		_ = engine.Run(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-scanned:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	cancel()
+	// As in metrics mode, the engine gets its --shutdown-grace to finish a poll
+	// in flight before closing its connections.
+	stopTimeout := cfg.shutdownGrace + httpShutdownTimeout
+	select {
+	case <-engineDone:
+	case <-time.After(stopTimeout):
+		log.Printf("Engine did not stop within %v; exiting anyway", stopTimeout)
+	}
+	return engine, err
+}
+
+// writeMetrics gathers registry and encodes it to out in the Prometheus text
+// exposition format, as served on /metrics.
+func writeMetrics(out io.Writer, registry *prometheus.Registry) error {
+	families, err := registry.Gather()
+	if err != nil {
+		return fmt.Errorf("gather: %w", err)
+	}
+	enc := expfmt.NewEncoder(out, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			return fmt.Errorf("encode %s: %w", mf.GetName(), err)
+		}
+	}
+	return nil
+}
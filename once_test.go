@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRunOnce scrapes a mock IntelliCenter once and checks the metrics land on
+// the writer in the Prometheus text format.
+func TestRunOnce(t *testing.T) {
+	responses := map[string]IntelliCenterResponse{
+		"GetParamList:OBJTYP=BODY": {ObjectList: []ObjectData{
+			{ObjName: "B1191", Params: map[string]string{"SNAME": "Once Pool", "STATUS": "ON", "TEMP": "81", "SUBTYP": "POOL", "HTMODE": "0"}},
+		}},
+	}
+	server := createMockWebSocketServer(t, responses)
+	t.Cleanup(server.Close)
+	host, port, _ := strings.Cut(strings.TrimPrefix(server.URL, "http://"), ":")
+
+	var out bytes.Buffer
+	cfg := &appConfig{intelliCenterIP: host, intelliCenterPort: port, pollInterval: time.Hour}
+	if code := runOnce(cfg, &out); code != exitOnceOK {
+		t.Fatalf("runOnce exit status = %d, want %d", code, exitOnceOK)
+	}
+	want := `water_temperature_fahrenheit{body="POOL",name="Once Pool",objnam="B1191"} 81`
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, out.String())
+	}
+	if !strings.Contains(out.String(), "# TYPE water_temperature_fahrenheit gauge") {
+		t.Error("output is not in the Prometheus text format")
+	}
+}
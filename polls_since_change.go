@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/astrostl/pentameter/intellicenter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// pollsSinceChangeMetrics holds polls_since_change.
+type pollsSinceChangeMetrics struct {
+	pollsSinceChange *prometheus.GaugeVec
+}
+
+// definePollsSinceChangeMetrics builds pollsSinceChangeMetrics.
+func definePollsSinceChangeMetrics() pollsSinceChangeMetrics {
+	return pollsSinceChangeMetrics{
+		pollsSinceChange: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "intellicenter_polls_since_change",
+				Help: "Consecutive successful polls over which an object's sensor reading (body TEMP, sensor PROBE, " +
+					"chemistry PHVAL/ORPVAL/SALT) has not changed; 0 on the poll it changed. A large value can mean a " +
+					"dead sensor repeating its last reading.",
+			},
+			[]string{fieldObjnam},
+		),
+	}
+}
+
+// readingKeys are the sensor readings watched for staleness, by kind. Only
+// analog readings: equipment state (a pump off, a circuit on) legitimately
+// holds for hours.
+var readingKeys = map[intellicenter.Kind][]string{
+	intellicenter.KindBody:   {keyTEMP},
+	intellicenter.KindSensor: {keyPROBE},
+	intellicenter.KindChem:   {keyPHVAL, keyORPVAL, keySALT},
+}
+
+// readingSample is an object's last readings and how many polls they have held.
+type readingSample struct {
+	readings string
+	polls    int
+}
+
+// sampleReadings advances intellicenter_polls_since_change once per poll.
+// It runs at the poll cadence only, since pushes between polls would otherwise
+// count as polls. Objects that report no reading at all have no series.
+func (pm *PoolMonitor) sampleReadings(raw []intellicenter.RawObject) {
+	seen := make(map[string]bool, len(pm.readingSamples))
+	for _, o := range raw {
+		keys, ok := readingKeys[o.Kind]
+		if !ok {
+			continue
+		}
+		values := make([]string, len(keys))
+		for i, key := range keys {
+			values[i] = o.Params[key]
+		}
+		readings := strings.Join(values, "|")
+		if strings.Trim(readings, "|") == "" {
+			continue
+		}
+		seen[o.ObjName] = true
+		sample := readingSample{readings: readings}
+		if prev, ok := pm.readingSamples[o.ObjName]; ok && prev.readings == readings {
+			sample.polls = prev.polls + 1
+		}
+		pm.readingSamples[o.ObjName] = sample
+		pm.metrics.pollsSinceChange.WithLabelValues(o.ObjName).Set(float64(sample.polls))
+	}
+	for objName := range pm.readingSamples {
+		if !seen[objName] {
+			delete(pm.readingSamples, objName)
+			pm.metrics.pollsSinceChange.DeleteLabelValues(objName)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/astrostl/pentameter/intellicenter"
+)
+
+func TestSampleReadings(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	poll := func(temp, probe string) {
+		pm.sampleReadings([]intellicenter.RawObject{
+			{ObjName: "B9501", Kind: intellicenter.KindBody, Params: map[string]string{"TEMP": temp}},
+			{ObjName: "_A951", Kind: intellicenter.KindSensor, Params: map[string]string{"PROBE": probe}},
+			{ObjName: "C9501", Kind: intellicenter.KindCircuit, Params: map[string]string{"STATUS": "ON"}},
+		})
+	}
+
+	poll("82", "75")
+	poll("82", "76")
+	poll("82", "77")
+	if got := gaugeVal(t, pm.metrics.pollsSinceChange.WithLabelValues("B9501")); got != 2 {
+		t.Errorf("unchanged body: got %v, want 2", got)
+	}
+	if got := gaugeVal(t, pm.metrics.pollsSinceChange.WithLabelValues("_A951")); got != 0 {
+		t.Errorf("changing sensor: got %v, want 0", got)
+	}
+	if pm.metrics.pollsSinceChange.DeleteLabelValues("C9501") {
+		t.Error("circuit state should not be tracked")
+	}
+
+	// A change resets the count.
+	poll("83", "77")
+	if got := gaugeVal(t, pm.metrics.pollsSinceChange.WithLabelValues("B9501")); got != 0 {
+		t.Errorf("after change: got %v, want 0", got)
+	}
+
+	// An object that stops reporting a reading loses its series.
+	poll("83", "")
+	if pm.metrics.pollsSinceChange.DeleteLabelValues("_A951") {
+		t.Error("series kept for a sensor without a reading")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+)
+
+// Timing profile names (--profile).
+const (
+	profileDefault      = "default"
+	profileConservative = "conservative"
+	profileFast         = "fast"
+)
+
+// timingProfile is a --profile preset of the timing knobs, so users can tune
+// for a slow or a fast panel with one flag. Zero fields keep the built-in
+// default.
+type timingProfile struct {
+	pollInterval   time.Duration // metrics/homebridge poll; listen mode keeps its own
+	initialTimeout time.Duration // per-response timeout during a baseline
+	queryTimeout   time.Duration // steady-state per-response timeout
+	queryPacing    time.Duration // delay between a scan's sub-queries
+	pushQueue      int           // pushes buffered before dropping
+}
+
+var timingProfiles = map[string]timingProfile{
+	profileDefault: {},
+	// Older or busy panels: poll less often, wait longer, space out queries,
+	// and ride out longer push bursts.
+	profileConservative: {
+		pollInterval:   120 * time.Second,
+		initialTimeout: 120 * time.Second,
+		queryTimeout:   60 * time.Second,
+		queryPacing:    250 * time.Millisecond,
+		pushQueue:      1024,
+	},
+	// Responsive panels on a good network: poll more often and fail faster.
+	profileFast: {
+		pollInterval:   30 * time.Second,
+		initialTimeout: 30 * time.Second,
+		queryTimeout:   15 * time.Second,
+	},
+}
+
+// applyProfile fills cfg's timing settings from p, leaving any knob the user
+// set explicitly (flag or env var, as reported by explicit) untouched.
+func applyProfile(cfg *appConfig, p timingProfile, explicit func(flagName, envVar string) bool) {
+	if p.pollInterval > 0 && !cfg.listenMode && !explicit("interval", "PENTAMETER_INTERVAL") {
+		cfg.pollInterval = p.pollInterval
+	}
+	if p.initialTimeout > 0 && !explicit("initial-timeout", "PENTAMETER_INITIAL_TIMEOUT") {
+		cfg.initialTimeout = p.initialTimeout
+	}
+	if p.queryPacing > 0 && !explicit("query-pacing", "PENTAMETER_QUERY_PACING") {
+		cfg.queryPacing = p.queryPacing
+	}
+	cfg.queryTimeout = p.queryTimeout
+	cfg.pushQueue = p.pushQueue
+}
+
+// explicitlySet reports whether a flag was given on the command line or through
+// its environment variable. Call it after flag.Parse.
+func explicitlySet(flagName, envVar string) bool {
+	if os.Getenv(envVar) != "" {
+		return true
+	}
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == flagName {
+			set = true
+		}
+	})
+	return set
+}
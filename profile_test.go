@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyProfile(t *testing.T) {
+	none := func(string, string) bool { return false }
+
+	cfg := &appConfig{pollInterval: 60 * time.Second, initialTimeout: 60 * time.Second}
+	applyProfile(cfg, timingProfiles[profileConservative], none)
+	if cfg.pollInterval != 120*time.Second || cfg.initialTimeout != 120*time.Second ||
+		cfg.queryTimeout != 60*time.Second || cfg.queryPacing != 250*time.Millisecond || cfg.pushQueue != 1024 {
+		t.Errorf("conservative: got %+v", cfg)
+	}
+
+	// Explicit flags win over the preset.
+	explicit := func(name, _ string) bool { return name == "interval" || name == "query-pacing" }
+	cfg = &appConfig{pollInterval: 45 * time.Second, initialTimeout: 60 * time.Second}
+	applyProfile(cfg, timingProfiles[profileConservative], explicit)
+	if cfg.pollInterval != 45*time.Second || cfg.queryPacing != 0 {
+		t.Errorf("explicit interval/pacing overridden: got %+v", cfg)
+	}
+	if cfg.initialTimeout != 120*time.Second {
+		t.Errorf("initial timeout = %v, want the preset's 120s", cfg.initialTimeout)
+	}
+
+	// Listen mode keeps its own poll interval.
+	cfg = &appConfig{listenMode: true, pollInterval: 10 * time.Second}
+	applyProfile(cfg, timingProfiles[profileFast], none)
+	if cfg.pollInterval != 10*time.Second {
+		t.Errorf("listen mode interval = %v, want 10s", cfg.pollInterval)
+	}
+
+	// The default profile changes nothing.
+	cfg = &appConfig{pollInterval: 60 * time.Second, initialTimeout: 60 * time.Second}
+	applyProfile(cfg, timingProfiles[profileDefault], none)
+	if cfg.pollInterval != 60*time.Second || cfg.initialTimeout != 60*time.Second ||
+		cfg.queryTimeout != 0 || cfg.pushQueue != 0 {
+		t.Errorf("default: got %+v", cfg)
+	}
+}
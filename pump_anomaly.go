@@ -0,0 +1,120 @@
+package main
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/astrostl/pentameter/intellicenter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Pump efficiency anomaly heuristic (--pump-anomaly). GPM-per-watt at a given
+// speed is roughly constant for a clean system; a clogging filter or worn
+// impeller moves it. Baselines are learned per pump and speed band, in memory,
+// so a restart relearns them.
+const (
+	// pumpAnomalyRPMBand groups speeds into bands that share a baseline, so
+	// small RPM wobble at one programmed speed doesn't split the history.
+	pumpAnomalyRPMBand = 100
+
+	// pumpAnomalyMinSamples is how many polls a band must see before it can flag.
+	pumpAnomalyMinSamples = 30
+
+	// pumpAnomalyAlpha is the rolling baseline's weight for each new sample once
+	// past the warm-up, where samples are averaged equally.
+	pumpAnomalyAlpha = 0.05
+)
+
+// pumpAnomalyMetrics holds pump_efficiency_anomaly.
+type pumpAnomalyMetrics struct {
+	pumpEfficiencyAnomaly *prometheus.GaugeVec
+}
+
+// definePumpAnomalyMetrics builds pumpAnomalyMetrics.
+func definePumpAnomalyMetrics() pumpAnomalyMetrics {
+	return pumpAnomalyMetrics{
+		pumpEfficiencyAnomaly: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "pump_efficiency_anomaly",
+				Help: "Heuristic (--pump-anomaly only): 1 if the pump's GPM-per-watt at its current speed has dropped below " +
+					"its learned baseline by more than the threshold (dirty filter, impeller issue), 0 otherwise. " +
+					"Flow-capable pumps only.",
+			},
+			[]string{"pump", fieldName},
+		),
+	}
+}
+
+// pumpBaseline is the learned GPM-per-watt ratio for one pump speed band.
+type pumpBaseline struct {
+	ratio   float64
+	samples int
+}
+
+// pumpAnomalyDetector holds the rolling baselines and the deviation threshold.
+type pumpAnomalyDetector struct {
+	threshold float64                  // fractional deviation that flags (0.25 = 25%)
+	baselines map[string]*pumpBaseline // "objnam|band" -> baseline
+}
+
+func newPumpAnomalyDetector(thresholdPercent int) *pumpAnomalyDetector {
+	return &pumpAnomalyDetector{
+		threshold: float64(thresholdPercent) / 100,
+		baselines: make(map[string]*pumpBaseline),
+	}
+}
+
+// observe records one running sample and reports whether it has dropped below
+// the band's baseline by more than the threshold. Only such degradations are
+// withheld from the baseline, so a clogging filter stays flagged instead of
+// becoming the new normal. A sample above the baseline is learned; one past the
+// threshold (a cleaned filter, a new impeller) replaces the baseline outright,
+// so the next degradation is measured from the improved state.
+func (d *pumpAnomalyDetector) observe(objName string, rpm, gpm, watts float64) bool {
+	key := objName + "|" + strconv.Itoa(int(math.Round(rpm/pumpAnomalyRPMBand)))
+	ratio := gpm / watts
+	b := d.baselines[key]
+	if b == nil {
+		b = &pumpBaseline{}
+		d.baselines[key] = b
+	}
+	if b.samples >= pumpAnomalyMinSamples {
+		switch deviation := (ratio - b.ratio) / b.ratio; {
+		case deviation < -d.threshold:
+			return true
+		case deviation > d.threshold:
+			b.ratio = ratio
+			b.samples++
+			return false
+		}
+	}
+	alpha := max(1/float64(b.samples+1), pumpAnomalyAlpha)
+	b.ratio += alpha * (ratio - b.ratio)
+	b.samples++
+	return false
+}
+
+// samplePumpEfficiency feeds each flow-capable pump's poll values to the
+// detector and sets pump_efficiency_anomaly. Called once per poll, not per push
+// recompute: pump values are poll-only, and repeats would skew the baseline.
+// Pumps without flow capability (MAXF 0) report an estimated GPM and are skipped.
+func (pm *PoolMonitor) samplePumpEfficiency(pumps map[string]intellicenter.Pump) {
+	if pm.pumpAnomaly == nil {
+		return
+	}
+	for _, p := range pumps {
+		if p.MaxFlow <= 0 {
+			continue
+		}
+		anomalous := false
+		if p.RPM > 0 && p.GPM > 0 && p.Watts > 0 {
+			anomalous = pm.pumpAnomaly.observe(p.ID, p.RPM, p.GPM, p.Watts)
+		}
+		value := 0.0
+		if anomalous {
+			value = 1
+		}
+		pm.metrics.pumpEfficiencyAnomaly.WithLabelValues(p.ID, p.Name).Set(value)
+		pm.logChangedf("pumpanomaly:"+p.ID, "Pump efficiency anomaly: %s (%s) = %v", p.Name, p.ID, anomalous)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/astrostl/pentameter/intellicenter"
+)
+
+func TestPumpAnomalyDetector(t *testing.T) {
+	d := newPumpAnomalyDetector(25)
+
+	// Warm-up: a band never flags before it has enough samples.
+	for i := 0; i < pumpAnomalyMinSamples; i++ {
+		if d.observe("PMP01", 2500, 60, 1200) {
+			t.Fatalf("sample %d flagged during warm-up", i)
+		}
+	}
+	if d.observe("PMP01", 2510, 58, 1200) {
+		t.Error("small deviation flagged")
+	}
+	if !d.observe("PMP01", 2500, 40, 1200) {
+		t.Error("33% drop in GPM-per-watt not flagged")
+	}
+	// The anomalous sample was not learned, so it keeps flagging.
+	if !d.observe("PMP01", 2500, 40, 1200) {
+		t.Error("repeat anomaly not flagged; the baseline absorbed it")
+	}
+	// A lasting improvement (cleaned filter) is never flagged and becomes the
+	// baseline, so the next clog is measured from it.
+	if d.observe("PMP01", 2500, 90, 1200) {
+		t.Error("improvement flagged")
+	}
+	if d.observe("PMP01", 2500, 90, 1200) {
+		t.Error("improvement still flagged on the next poll")
+	}
+	if !d.observe("PMP01", 2500, 60, 1200) {
+		t.Error("33% drop from the improved baseline not flagged")
+	}
+	// Another speed band has its own (still warming) baseline.
+	if d.observe("PMP01", 1500, 20, 300) {
+		t.Error("unlearned speed band flagged")
+	}
+}
+
+func TestSamplePumpEfficiency(t *testing.T) {
+	pm := NewPoolMonitor("", "", false)
+	pumps := map[string]intellicenter.Pump{
+		"PMP01": {ID: "PMP01", Name: "VSF", RPM: 2500, GPM: 60, Watts: 1200, MaxFlow: 130},
+		"PMP02": {ID: "PMP02", Name: "VS", RPM: 2500, GPM: 60, Watts: 1200},
+	}
+
+	pm.samplePumpEfficiency(pumps) // disabled: no-op
+	if pm.pumpAnomaly != nil {
+		t.Fatal("detector created while disabled")
+	}
+
+	pm.pumpAnomaly = newPumpAnomalyDetector(25)
+	for i := 0; i < pumpAnomalyMinSamples; i++ {
+		pm.samplePumpEfficiency(pumps)
+	}
+	if got := gaugeVal(t, pm.metrics.pumpEfficiencyAnomaly.WithLabelValues("PMP01", "VSF")); got != 0 {
+		t.Errorf("baseline poll: anomaly = %v, want 0", got)
+	}
+
+	clogged := pumps["PMP01"]
+	clogged.GPM = 35
+	pumps["PMP01"] = clogged
+	pm.samplePumpEfficiency(pumps)
+	if got := gaugeVal(t, pm.metrics.pumpEfficiencyAnomaly.WithLabelValues("PMP01", "VSF")); got != 1 {
+		t.Errorf("clogged poll: anomaly = %v, want 1", got)
+	}
+	if _, ok := pm.pumpAnomaly.baselines["PMP02|25"]; ok {
+		t.Error("pump without flow capability (MAXF 0) was sampled")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// defaultPushgatewayJob is the job grouping label pushed metrics carry.
+const defaultPushgatewayJob = "pentameter"
+
+// gatewayPusher pushes the registry to a Prometheus Pushgateway after every
+// successful scan (--pushgateway-url), for deployments Prometheus can't scrape
+// (behind NAT, say). /metrics is still served. Pushes run on their own
+// goroutine so a slow gateway never holds up a poll; scans that land while a
+// push is in flight coalesce into one more push.
+type gatewayPusher struct {
+	url    string
+	pusher *push.Pusher
+	kick   chan struct{}
+
+	failing bool // the last push failed; only run's goroutine touches it
+}
+
+// newGatewayPusher pushes g to the gateway at gatewayURL, grouped by job and,
+// when set, instance.
+func newGatewayPusher(gatewayURL, job, instance string, g prometheus.Gatherer) *gatewayPusher {
+	p := push.New(gatewayURL, job).Gatherer(g)
+	if instance != "" {
+		p = p.Grouping("instance", instance)
+	}
+	return &gatewayPusher{url: gatewayURL, pusher: p, kick: make(chan struct{}, 1)}
+}
+
+// trigger requests a push without waiting for it.
+func (g *gatewayPusher) trigger() {
+	select {
+	case g.kick <- struct{}{}:
+	default: // a push is already pending
+	}
+}
+
+// run pushes once per trigger until ctx is canceled. A failure logs once per
+// streak; the next successful scan simply pushes again.
+func (g *gatewayPusher) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-g.kick:
+		}
+		err := g.pusher.PushContext(ctx)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return
+		case err != nil && !g.failing:
+			log.Printf("Warning: push to %s failed: %v", g.url, err)
+			g.failing = true
+		case err == nil && g.failing:
+			log.Printf("Push to %s recovered", g.url)
+			g.failing = false
+		}
+	}
+}
+
+// validatePushgatewayURL checks --pushgateway-url names an http(s) endpoint.
+func validatePushgatewayURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http(s) URL", value)
+	}
+	return nil
+}
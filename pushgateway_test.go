@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestGatewayPusher verifies a triggered push PUTs the registry to the
+// job/instance group on the gateway.
+func TestGatewayPusher(t *testing.T) {
+	var mu sync.Mutex
+	var method, path, body string
+	pushed := make(chan struct{}, 1)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		method, path, body = r.Method, r.URL.Path, string(data)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		select {
+		case pushed <- struct{}{}:
+		default:
+		}
+	}))
+	defer gateway.Close()
+
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "pushgateway_test_value"})
+	gauge.Set(42)
+	registry.MustRegister(gauge)
+
+	g := newGatewayPusher(gateway.URL, defaultPushgatewayJob, "poolhouse", registry)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go g.run(ctx)
+	g.trigger()
+	<-pushed
+
+	mu.Lock()
+	defer mu.Unlock()
+	if method != http.MethodPut || path != "/metrics/job/pentameter/instance/poolhouse" {
+		t.Errorf("push = %s %s, want PUT /metrics/job/pentameter/instance/poolhouse", method, path)
+	}
+	if !strings.Contains(body, "pushgateway_test_value") {
+		t.Errorf("pushed body missing the registry's metric (%d bytes)", len(body))
+	}
+}
+
+func TestValidatePushgatewayURL(t *testing.T) {
+	if err := validatePushgatewayURL("http://gateway:9091"); err != nil {
+		t.Errorf("validatePushgatewayURL(http://gateway:9091) = %v, want nil", err)
+	}
+	for _, bad := range []string{"gateway:9091", "ftp://gateway", "http://"} {
+		if err := validatePushgatewayURL(bad); err == nil {
+			t.Errorf("validatePushgatewayURL(%q) = nil error, want failure", bad)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SCHED params used for next-run countdowns and scheduled setpoints (--schedules).
+const (
+	keyDAY      = "DAY"    // enabled weekdays, e.g. "MTWRFAU"
+	keySTART    = "START"  // ABSTIM (clock time) or SRIS/SSET (sunrise/sunset)
+	keyTIME     = "TIME"   // clock start, "HH,MM,SS"
+	keySTOP     = "STOP"   // like START, for the end
+	keyTIMOUT   = "TIMOUT" // clock end, "HH,MM,SS"
+	startAbsTim = "ABSTIM" // the only start type with a fixed clock time
+)
+
+// schedDays maps IntelliCenter's DAY letters to weekdays (R = Thursday,
+// A = Saturday, U = Sunday).
+var schedDays = map[rune]time.Weekday{
+	'U': time.Sunday, 'M': time.Monday, 'T': time.Tuesday, 'W': time.Wednesday,
+	'R': time.Thursday, 'F': time.Friday, 'A': time.Saturday,
+}
+
+// scheduleMetrics holds circuit_next_run_seconds.
+type scheduleMetrics struct {
+	circuitNextRunSeconds *prometheus.GaugeVec
+}
+
+// defineScheduleMetrics builds scheduleMetrics.
+func defineScheduleMetrics() scheduleMetrics {
+	return scheduleMetrics{
+		circuitNextRunSeconds: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "circuit_next_run_seconds",
+				Help: "Seconds until the circuit's next scheduled start, from its enabled clock-time schedules (--schedules only). " +
+					"Computed on the exporter's local clock; sunrise/sunset starts are not included.",
+			},
+			[]string{logFieldCircuit, fieldName},
+		),
+	}
+}
+
+// parseSchedDays returns the weekdays named by a SCHED DAY value.
+func parseSchedDays(days string) map[time.Weekday]bool {
+	enabled := make(map[time.Weekday]bool)
+	for _, d := range strings.ToUpper(days) {
+		if wd, ok := schedDays[d]; ok {
+			enabled[wd] = true
+		}
+	}
+	return enabled
+}
+
+// nextScheduledRun returns the first start strictly after now on one of days,
+// at hh:mm:ss in now's location. ok is false when days names no weekday.
+func nextScheduledRun(now time.Time, days string, hh, mm, ss int) (time.Time, bool) {
+	enabled := parseSchedDays(days)
+	if len(enabled) == 0 {
+		return time.Time{}, false
+	}
+	// Eight days covers today's start having already passed on a once-a-week
+	// schedule. time.Date normalizes day overflow and keeps DST-correct clock times.
+	for d := range 8 {
+		start := time.Date(now.Year(), now.Month(), now.Day()+d, hh, mm, ss, 0, now.Location())
+		if start.After(now) && enabled[start.Weekday()] {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// activeScheduledRun returns the start of the run in progress at now, for a
+// schedule starting at start and ending at stop (both "HH,MM,SS" clock times)
+// on days. A stop at or before the start runs past midnight, so a run begun
+// yesterday can still be in progress. ok is false when no run covers now.
+func activeScheduledRun(now time.Time, days, start, stop string) (time.Time, bool) {
+	enabled := parseSchedDays(days)
+	sh, sm, ss, okStart := parseSchedTime(start)
+	eh, em, es, okStop := parseSchedTime(stop)
+	if len(enabled) == 0 || !okStart || !okStop {
+		return time.Time{}, false
+	}
+	for d := 0; d >= -1; d-- {
+		begin := time.Date(now.Year(), now.Month(), now.Day()+d, sh, sm, ss, 0, now.Location())
+		if !enabled[begin.Weekday()] {
+			continue
+		}
+		end := time.Date(now.Year(), now.Month(), now.Day()+d, eh, em, es, 0, now.Location())
+		if !end.After(begin) {
+			end = end.AddDate(0, 0, 1)
+		}
+		if !now.Before(begin) && now.Before(end) {
+			return begin, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseSchedTime parses a SCHED TIME ("HH,MM,SS"; seconds optional).
+func parseSchedTime(s string) (hh, mm, ss int, ok bool) {
+	parts := strings.Split(s, ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, 0, 0, false
+	}
+	vals := make([]int, 3)
+	for i, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || v < 0 {
+			return 0, 0, 0, false
+		}
+		vals[i] = v
+	}
+	if vals[0] > 23 || vals[1] > 59 || vals[2] > 59 {
+		return 0, 0, 0, false
+	}
+	return vals[0], vals[1], vals[2], true
+}
+
+// applySchedules sets circuit_next_run_seconds for every circuit with an
+// enabled (STATUS ON) clock-time schedule, taking the soonest start across its
+// schedules. Circuits left without one lose their series. Must run after
+// applyCircuitStatus, which caches the circuit names.
+func (pm *PoolMonitor) applySchedules(objs []ObjectData) {
+	now := pm.now()
+	next := make(map[string]time.Time)
+	for _, obj := range objs {
+		p := obj.Params
+		if p[keySTATUS] != statusOn || p[keySTART] != startAbsTim {
+			continue
+		}
+		hh, mm, ss, ok := parseSchedTime(p[keyTIME])
+		if !ok {
+			continue
+		}
+		start, ok := nextScheduledRun(now, p[keyDAY], hh, mm, ss)
+		if !ok {
+			continue
+		}
+		circuit := p[keyCIRCUIT]
+		if prev, seen := next[circuit]; !seen || start.Before(prev) {
+			next[circuit] = start
+		}
+	}
+
+	previous := pm.activeNextRunKeys
+	pm.activeNextRunKeys = make(map[string]bool, len(next))
+	for circuit, start := range next {
+		name := pm.circuitNames[circuit]
+		pm.metrics.circuitNextRunSeconds.WithLabelValues(circuit, name).Set(start.Sub(now).Seconds())
+		pm.activeNextRunKeys[circuit+"|"+name] = true
+	}
+	for key := range previous {
+		if !pm.activeNextRunKeys[key] {
+			circuit, name, _ := strings.Cut(key, "|")
+			pm.metrics.circuitNextRunSeconds.DeleteLabelValues(circuit, name)
+		}
+	}
+	pm.applyScheduledSetpoints(now, objs)
+}
+
+// applyScheduledSetpoints sets circuit_scheduled_setpoint_fahrenheit for every
+// circuit with an enabled clock-time schedule running now that carries a heat
+// setpoint (body schedules, e.g. a lower target overnight). When runs overlap,
+// the one that started last wins, as its setpoint is the one most recently
+// applied. Circuits with no such run lose their series.
+func (pm *PoolMonitor) applyScheduledSetpoints(now time.Time, objs []ObjectData) {
+	type run struct {
+		start    time.Time
+		setpoint float64
+	}
+	running := make(map[string]run)
+	for _, obj := range objs {
+		p := obj.Params
+		if p[keySTATUS] != statusOn || p[keySTART] != startAbsTim || p[keySTOP] != startAbsTim {
+			continue
+		}
+		setpoint, err := strconv.ParseFloat(p[keyLOTMP], 64)
+		if err != nil || setpoint <= 0 {
+			continue
+		}
+		start, ok := activeScheduledRun(now, p[keyDAY], p[keyTIME], p[keyTIMOUT])
+		if !ok {
+			continue
+		}
+		circuit := p[keyCIRCUIT]
+		if prev, seen := running[circuit]; !seen || start.After(prev.start) {
+			running[circuit] = run{start: start, setpoint: setpoint}
+		}
+	}
+
+	previous := pm.activeSetpointKeys
+	pm.activeSetpointKeys = make(map[string]bool, len(running))
+	for circuit, r := range running {
+		name := pm.circuitNames[circuit]
+		pm.metrics.circuitScheduledSetpoint.WithLabelValues(circuit, name).Set(pm.displayTemp(r.setpoint))
+		pm.activeSetpointKeys[circuit+"|"+name] = true
+	}
+	for key := range previous {
+		if !pm.activeSetpointKeys[key] {
+			circuit, name, _ := strings.Cut(key, "|")
+			pm.metrics.circuitScheduledSetpoint.DeleteLabelValues(circuit, name)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextScheduledRun(t *testing.T) {
+	// Wednesday 2026-06-10 18:00 local.
+	now := time.Date(2026, 6, 10, 18, 0, 0, 0, time.Local)
+	cases := []struct {
+		name   string
+		days   string
+		hh, mm int
+		want   time.Duration
+		ok     bool
+	}{
+		{"later today", "MTWRFAU", 19, 30, 90 * time.Minute, true},
+		{"passed today: tomorrow", "MTWRFAU", 8, 0, 14 * time.Hour, true},
+		{"weekdays from Wednesday evening", "MTWRF", 8, 0, 14 * time.Hour, true},
+		{"weekend only", "AU", 8, 0, 2*24*time.Hour + 14*time.Hour, true},
+		{"once a week, already passed", "W", 8, 0, 7*24*time.Hour - 10*time.Hour, true},
+		{"exactly now is not next", "W", 18, 0, 7 * 24 * time.Hour, true},
+		{"no days", "", 8, 0, 0, false},
+	}
+	for _, tc := range cases {
+		start, ok := nextScheduledRun(now, tc.days, tc.hh, tc.mm, 0)
+		if ok != tc.ok || (ok && start.Sub(now) != tc.want) {
+			t.Errorf("%s: next = %v (%v), want %v (%v)", tc.name, start.Sub(now), ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestParseSchedTime(t *testing.T) {
+	if hh, mm, ss, ok := parseSchedTime("19,30,15"); !ok || hh != 19 || mm != 30 || ss != 15 {
+		t.Errorf("parseSchedTime = %d:%d:%d %v", hh, mm, ss, ok)
+	}
+	if _, _, _, ok := parseSchedTime("07,05"); !ok {
+		t.Error("seconds should be optional")
+	}
+	for _, bad := range []string{"", "19", "24,00,00", "12,60,00", "a,b,c", "1,2,3,4"} {
+		if _, _, _, ok := parseSchedTime(bad); ok {
+			t.Errorf("parseSchedTime(%q) accepted", bad)
+		}
+	}
+}
+
+func TestApplySchedules(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	now := time.Date(2026, 6, 10, 18, 0, 0, 0, time.Local) // Wednesday
+	pm.now = func() time.Time { return now }
+	pm.circuitNames = map[string]string{"C0091": "Sched Pool", "C0092": "Sched Light"}
+
+	sched := func(objName, circuit, status, start, tm string) ObjectData {
+		return ObjectData{ObjName: objName, Params: map[string]string{
+			"CIRCUIT": circuit, "STATUS": status, "DAY": "MTWRFAU", "START": start, "TIME": tm,
+		}}
+	}
+	pm.applySchedules([]ObjectData{
+		sched("SCH01", "C0091", "ON", "ABSTIM", "08,00,00"),
+		sched("SCH02", "C0091", "ON", "ABSTIM", "20,00,00"),  // sooner: wins
+		sched("SCH03", "C0092", "OFF", "ABSTIM", "19,00,00"), // disabled
+		sched("SCH04", "C0092", "ON", "SSET", "00,00,00"),    // sunset: no clock time
+	})
+	if got := gaugeVal(t, pm.metrics.circuitNextRunSeconds.WithLabelValues("C0091", "Sched Pool")); got != 7200 {
+		t.Errorf("C0091 next run = %v, want 7200 (soonest schedule)", got)
+	}
+	if pm.metrics.circuitNextRunSeconds.DeleteLabelValues("C0092", "Sched Light") {
+		t.Error("circuit with only disabled/sunset schedules exported a series")
+	}
+
+	// A removed schedule drops its circuit's series.
+	pm.applySchedules(nil)
+	if pm.metrics.circuitNextRunSeconds.DeleteLabelValues("C0091", "Sched Pool") {
+		t.Error("stale circuit_next_run_seconds series not removed")
+	}
+}
+
+func TestActiveScheduledRun(t *testing.T) {
+	// Wednesday 2026-06-10 02:00 local.
+	now := time.Date(2026, 6, 10, 2, 0, 0, 0, time.Local)
+	cases := []struct {
+		name        string
+		days        string
+		start, stop string
+		begun       time.Duration // how long before now the run started
+		ok          bool
+	}{
+		{"overnight from yesterday", "T", "22,00,00", "06,00,00", 4 * time.Hour, true},
+		{"overnight, yesterday not enabled", "W", "22,00,00", "06,00,00", 0, false},
+		{"same-day run in progress", "W", "01,00,00", "03,00,00", time.Hour, true},
+		{"same-day run over", "W", "00,00,00", "01,00,00", 0, false},
+		{"starts exactly now", "W", "02,00,00", "04,00,00", 0, true},
+		{"ends exactly now", "W", "01,00,00", "02,00,00", 0, false},
+		{"bad time", "W", "01,00", "nope", 0, false},
+	}
+	for _, tc := range cases {
+		start, ok := activeScheduledRun(now, tc.days, tc.start, tc.stop)
+		if ok != tc.ok || (ok && now.Sub(start) != tc.begun) {
+			t.Errorf("%s: begun %v ago (%v), want %v (%v)", tc.name, now.Sub(start), ok, tc.begun, tc.ok)
+		}
+	}
+}
+
+func TestApplyScheduledSetpoints(t *testing.T) {
+	pm := NewPoolMonitor("test", "6680", false)
+	now := time.Date(2026, 6, 10, 2, 0, 0, 0, time.Local) // Wednesday 02:00
+	pm.now = func() time.Time { return now }
+	pm.circuitNames = map[string]string{"C0096": "Setpoint Pool", "C0097": "Setpoint Spa"}
+
+	sched := func(objName, circuit, status, start, stop, lotmp string) ObjectData {
+		return ObjectData{ObjName: objName, Params: map[string]string{
+			"CIRCUIT": circuit, "STATUS": status, "DAY": "MTWRFAU",
+			"START": "ABSTIM", "TIME": start, "STOP": "ABSTIM", "TIMOUT": stop, "LOTMP": lotmp,
+		}}
+	}
+	pm.applySchedules([]ObjectData{
+		sched("SCH11", "C0096", "ON", "08,00,00", "22,00,00", "84"), // daytime: not running
+		sched("SCH12", "C0096", "ON", "22,00,00", "06,00,00", "78"), // overnight: running
+		sched("SCH13", "C0096", "ON", "01,00,00", "03,00,00", "76"), // started last: wins
+		sched("SCH14", "C0097", "OFF", "00,00,00", "23,00,00", "100"),
+	})
+	if got := gaugeVal(t, pm.metrics.circuitScheduledSetpoint.WithLabelValues("C0096", "Setpoint Pool")); got != 76 {
+		t.Errorf("C0096 scheduled setpoint = %v, want 76 (latest-started run)", got)
+	}
+	if pm.metrics.circuitScheduledSetpoint.DeleteLabelValues("C0097", "Setpoint Spa") {
+		t.Error("disabled schedule exported a setpoint")
+	}
+
+	// Once no run is in progress the series is dropped.
+	now = time.Date(2026, 6, 10, 7, 0, 0, 0, time.Local)
+	pm.applySchedules([]ObjectData{sched("SCH12", "C0096", "ON", "22,00,00", "06,00,00", "78")})
+	if pm.metrics.circuitScheduledSetpoint.DeleteLabelValues("C0096", "Setpoint Pool") {
+		t.Error("setpoint series kept after the run ended")
+	}
+}
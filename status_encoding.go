@@ -0,0 +1,56 @@
+package main
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Status encodings for circuit_status, feature_status and master_circuit_status
+// (--status-encoding).
+const (
+	// statusEncodingTristate folds freeze protection into the status value:
+	// 0=off, 1=on, 2=freeze protection active. One series per circuit.
+	statusEncodingTristate = "tristate"
+
+	// statusEncodingBoolean keeps status strictly 0/1 (freeze-protected reads 1,
+	// since the circuit is running) and reports freeze protection in separate
+	// *_freeze_protected gauges.
+	statusEncodingBoolean = "boolean"
+)
+
+// freezeMetrics are the boolean-encoding freeze-protection gauges.
+type freezeMetrics struct {
+	circuitFreezeProtected *prometheus.GaugeVec
+	featureFreezeProtected *prometheus.GaugeVec
+}
+
+// defineFreezeMetrics builds the boolean-encoding freeze-protection gauges.
+func defineFreezeMetrics() freezeMetrics {
+	return freezeMetrics{
+		circuitFreezeProtected: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "circuit_freeze_protected",
+				Help: "1 if the circuit is running for freeze protection, 0 otherwise (--status-encoding=boolean only)",
+			},
+			[]string{logFieldCircuit, fieldName, fieldSubtyp, fieldGroup},
+		),
+
+		featureFreezeProtected: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "feature_freeze_protected",
+				Help: "1 if the feature is running for freeze protection, 0 otherwise (--status-encoding=boolean only)",
+			},
+			[]string{"feature", fieldName, fieldSubtyp, fieldGroup},
+		),
+	}
+}
+
+// encodeStatus splits a tristate status value into the exported status and
+// freeze-protected values. Under the tristate encoding the value passes through
+// and freeze is unused.
+func (pm *PoolMonitor) encodeStatus(value float64) (status, freeze float64) {
+	if !pm.booleanStatus {
+		return value, 0
+	}
+	if value == circuitStatusFreezeProtected {
+		return circuitStatusOn, 1
+	}
+	return value, 0
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestStatusEncoding(t *testing.T) {
+	objs := []ObjectData{
+		{ObjName: "C0091", Params: map[string]string{"SNAME": "Encoded Pool", "SUBTYP": "POOL", "STATUS": "ON", "FREEZE": "ON"}},
+		{ObjName: "FTR91", Params: map[string]string{"SNAME": "Encoded Jets", "SUBTYP": "GENERIC", "STATUS": "ON", "FREEZE": "ON"}},
+	}
+	pm := NewPoolMonitor("test", "6680", false)
+	circuit := func() prometheus.Gauge {
+		return pm.metrics.circuitStatus.WithLabelValues("C0091", "Encoded Pool", "POOL", "")
+	}
+	feature := func() prometheus.Gauge {
+		return pm.metrics.featureStatus.WithLabelValues("FTR91", "Encoded Jets", "GENERIC", "")
+	}
+	pm.freezeProtectionActive = true
+	pm.applyCircuitStatus(objs)
+	if got := gaugeVal(t, circuit()); got != circuitStatusFreezeProtected {
+		t.Errorf("tristate circuit_status = %v, want 2", got)
+	}
+	if got := gaugeVal(t, feature()); got != circuitStatusFreezeProtected {
+		t.Errorf("tristate feature_status = %v, want 2", got)
+	}
+	if pm.metrics.circuitFreezeProtected.DeleteLabelValues("C0091", "Encoded Pool", "POOL", "") {
+		t.Error("tristate encoding exported circuit_freeze_protected")
+	}
+
+	pm.booleanStatus = true
+	pm.applyCircuitStatus(objs)
+	if got := gaugeVal(t, circuit()); got != circuitStatusOn {
+		t.Errorf("boolean circuit_status = %v, want 1", got)
+	}
+	if got := gaugeVal(t, pm.metrics.masterCircuitStatus.WithLabelValues("C0091", "Encoded Pool", "POOL", "")); got != circuitStatusOn {
+		t.Errorf("boolean master_circuit_status = %v, want 1", got)
+	}
+	if got := gaugeVal(t, pm.metrics.circuitFreezeProtected.WithLabelValues("C0091", "Encoded Pool", "POOL", "")); got != 1 {
+		t.Errorf("circuit_freeze_protected = %v, want 1", got)
+	}
+	if got := gaugeVal(t, feature()); got != circuitStatusOn {
+		t.Errorf("boolean feature_status = %v, want 1", got)
+	}
+	if got := gaugeVal(t, pm.metrics.featureFreezeProtected.WithLabelValues("FTR91", "Encoded Jets", "GENERIC", "")); got != 1 {
+		t.Errorf("feature_freeze_protected = %v, want 1", got)
+	}
+
+	pm.freezeProtectionActive = false
+	pm.applyCircuitStatus(objs)
+	if got := gaugeVal(t, pm.metrics.circuitFreezeProtected.WithLabelValues("C0091", "Encoded Pool", "POOL", "")); got != 0 {
+		t.Errorf("freeze over: circuit_freeze_protected = %v, want 0", got)
+	}
+}
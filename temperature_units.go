@@ -0,0 +1,93 @@
+package main
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Temperature output units (--units). The panel reports Fahrenheit (system
+// MODE=ENGLISH); Celsius output converts before export and renames the
+// temperature metrics from *_fahrenheit to *_celsius.
+const (
+	tempUnitsFahrenheit = "f"
+	tempUnitsCelsius    = "c"
+)
+
+// temperatureMetrics are the temperature metrics. Their names and help depend
+// on --units, so they are built by defineTemperatureMetrics.
+type temperatureMetrics struct {
+	poolTemperature          *prometheus.GaugeVec
+	airTemperature           *prometheus.GaugeVec
+	solarTemperature         *prometheus.GaugeVec
+	thermalLowSetpoint       *prometheus.GaugeVec
+	thermalHighSetpoint      *prometheus.GaugeVec
+	bodyTemperatureError     *prometheus.GaugeVec
+	boardTemperature         *prometheus.GaugeVec
+	circuitScheduledSetpoint *prometheus.GaugeVec
+}
+
+// temperatureUnit returns the metric-name suffix and the unit word for help
+// text, in Celsius when celsius is set.
+func temperatureUnit(celsius bool) (suffix, word string) {
+	if celsius {
+		return "_celsius", "Celsius"
+	}
+	return "_fahrenheit", "Fahrenheit"
+}
+
+// displayTemp converts a panel temperature to the output units. Every absolute
+// temperature goes through it before Set, so body, air, setpoint and board
+// readings always agree. The panel reads Fahrenheit unless it reports
+// MODE=METRIC; a metric panel's readings are already Celsius, so --units=c
+// exports them as-is rather than converting twice.
+func (pm *PoolMonitor) displayTemp(reading float64) float64 {
+	if pm.celsius && !pm.panelMetric {
+		return (reading - 32) * 5 / 9
+	}
+	return reading
+}
+
+// displayTempDelta converts a temperature difference: a scale, with no offset.
+func (pm *PoolMonitor) displayTempDelta(reading float64) float64 {
+	if pm.celsius && !pm.panelMetric {
+		return reading * 5 / 9
+	}
+	return reading
+}
+
+// defineTemperatureMetrics builds the temperature metrics, named and described
+// in Celsius when celsius is set.
+func defineTemperatureMetrics(celsius bool) temperatureMetrics {
+	suffix, unit := temperatureUnit(celsius)
+	newVec := func(name, help string, labels []string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name + suffix, Help: help}, labels)
+	}
+
+	var m temperatureMetrics
+	m.poolTemperature = newVec("water_temperature",
+		"Current water temperature in "+unit,
+		[]string{fieldObjnam, logFieldBody, fieldName})
+	m.airTemperature = newVec("air_temperature",
+		"Current outdoor air temperature in "+unit,
+		[]string{fieldObjnam, "sensor", fieldName})
+	m.solarTemperature = newVec("solar_temperature",
+		"Current solar collector temperature in "+unit,
+		[]string{fieldObjnam, "sensor", fieldName})
+	m.thermalLowSetpoint = newVec("thermal_low_setpoint",
+		"Heating target temperature in "+unit+" (turn on heating when temp drops below this)",
+		[]string{logFieldHeater, fieldName, fieldSubtyp})
+	m.thermalHighSetpoint = newVec("thermal_high_setpoint",
+		"Cooling target temperature in "+unit+" (turn on cooling when temp rises above this)",
+		[]string{logFieldHeater, fieldName, fieldSubtyp})
+	m.bodyTemperatureError = newVec("body_temperature_error",
+		"Water temperature minus the heating setpoint (LOTMP) in "+unit+", for bodies with an assigned "+
+			"heater. Negative means below target (calling for heat); zero or positive means satisfied.",
+		[]string{fieldObjnam, logFieldBody, fieldName})
+	// A vector with no labels, so nothing is exported until a reading arrives
+	// (a plain gauge would publish a misleading 0 on panels without one).
+	m.boardTemperature = newVec("intellicenter_board_temperature",
+		"Controller board temperature in "+unit+", from the system object param named by --board-temp-key",
+		nil)
+	m.circuitScheduledSetpoint = newVec("circuit_scheduled_setpoint",
+		"Heat setpoint (LOTMP) of the body schedule running now for the circuit, in "+unit+" (--schedules only). "+
+			"Absent when no clock-time schedule with a setpoint is running; compare with the thermal low setpoint.",
+		[]string{logFieldCircuit, fieldName})
+	return m
+}
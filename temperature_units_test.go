@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestCelsiusOutput(t *testing.T) {
+	pm := newPoolMonitor("test", "6680", false, true)
+	pm.processBodyObject(ObjectData{ObjName: "B9301", Params: map[string]string{
+		"SNAME": "Celsius Pool", "SUBTYP": "POOL", "STATUS": "ON", "TEMP": "95", "HTSRC": "H9301", "LOTMP": "104", "HITMP": "104",
+	}}, pm.referencedHeaters)
+	pm.applyAirTemperature([]ObjectData{{ObjName: "_A935", Params: map[string]string{
+		"SNAME": "Celsius Air", "PROBE": "32", "SUBTYP": "AIR",
+	}}})
+
+	if got := gaugeVal(t, pm.metrics.poolTemperature.WithLabelValues("B9301", "POOL", "Celsius Pool")); got != 35 {
+		t.Errorf("water temperature = %v°C, want 35", got)
+	}
+	if got := gaugeVal(t, pm.metrics.airTemperature.WithLabelValues("_A935", "AIR", "Celsius Air")); got != 0 {
+		t.Errorf("air temperature = %v°C, want 0", got)
+	}
+	// A difference scales without the 32° offset: -9°F is -5°C.
+	if got := gaugeVal(t, pm.metrics.bodyTemperatureError.WithLabelValues("B9301", "POOL", "Celsius Pool")); got != -5 {
+		t.Errorf("temperature error = %v°C, want -5", got)
+	}
+
+	// A metric panel already reports Celsius: exported as-is, not converted twice.
+	pm.applySystemInfo([]ObjectData{{ObjName: "_5451", Params: map[string]string{"MODE": "METRIC"}}})
+	pm.processBodyObject(ObjectData{ObjName: "B9301", Params: map[string]string{
+		"SNAME": "Celsius Pool", "SUBTYP": "POOL", "STATUS": "ON", "TEMP": "35", "HTSRC": "H9301", "LOTMP": "40", "HITMP": "40",
+	}}, pm.referencedHeaters)
+	if got := gaugeVal(t, pm.metrics.poolTemperature.WithLabelValues("B9301", "POOL", "Celsius Pool")); got != 35 {
+		t.Errorf("metric panel water temperature = %v°C, want 35", got)
+	}
+	if got := gaugeVal(t, pm.metrics.bodyTemperatureError.WithLabelValues("B9301", "POOL", "Celsius Pool")); got != -5 {
+		t.Errorf("metric panel temperature error = %v°C, want -5", got)
+	}
+	if got := gaugeVal(t, pm.metrics.unitMismatch); got != 0 {
+		t.Errorf("metric panel with --units=c: unit_mismatch = %v, want 0", got)
+	}
+
+	families, err := createPrometheusRegistry(&appConfig{}, pm.metrics).Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for _, mf := range families {
+		names[mf.GetName()] = true
+	}
+	if !names["water_temperature_celsius"] || names["water_temperature_fahrenheit"] {
+		t.Errorf("registered names %v: want water_temperature_celsius only", names)
+	}
+
+	if !newMetricsMonitor(&appConfig{tempUnits: tempUnitsCelsius}).celsius {
+		t.Error("--units=c did not reach the metrics monitor")
+	}
+}
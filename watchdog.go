@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// exitWatchdog is the exit status after --watchdog-timeout or
+	// --startup-timeout, distinct from usage errors so supervisors and logs can
+	// tell the two apart.
+	exitWatchdog = 3
+
+	// watchdogChecks is how many times per timeout the watchdog checks, so it
+	// fires at most a tenth of the timeout late.
+	watchdogChecks = 10
+)
+
+// failureWatchdog exits the process once engine scans have failed continuously
+// for longer than the timeout (--watchdog-timeout), so a supervisor (systemd,
+// Docker restart policy) restarts it fresh instead of it spinning on a wedged
+// connection. Startup counts as failing until the first successful scan, and
+// startupTimeout (--startup-timeout), when set, bounds that first wait instead.
+// A zero limit never expires: the exporter keeps retrying and serving its
+// failure metrics.
+type failureWatchdog struct {
+	timeout        time.Duration    // continuous failure limit; 0 = none
+	startupTimeout time.Duration    // limit on the wait for the first success; 0 = use timeout
+	now            func() time.Time // injectable clock (tests)
+	exit           func(code int)   // injectable exit (tests); defaults to os.Exit
+
+	mu           sync.Mutex
+	failingSince time.Time // zero while healthy
+	succeeded    bool      // a scan has succeeded since startup
+}
+
+func newFailureWatchdog(timeout, startupTimeout time.Duration) *failureWatchdog {
+	w := &failureWatchdog{
+		timeout:        timeout,
+		startupTimeout: startupTimeout,
+		now:            time.Now,
+		exit:           os.Exit,
+	}
+	w.failingSince = w.now()
+	return w
+}
+
+// record notes one scan result: success clears the failure streak, and the
+// first failure after a success starts it.
+func (w *failureWatchdog) record(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch {
+	case err == nil:
+		w.failingSince = time.Time{}
+		w.succeeded = true
+	case w.failingSince.IsZero():
+		w.failingSince = w.now()
+	}
+}
+
+// limit is the failure limit in force: startupTimeout until the first success
+// (when set), timeout otherwise. Caller holds mu.
+func (w *failureWatchdog) limit() time.Duration {
+	if !w.succeeded && w.startupTimeout > 0 {
+		return w.startupTimeout
+	}
+	return w.timeout
+}
+
+// expired reports how long scans have been failing, whether that is still the
+// wait for the first success, and whether it exceeds the limit in force.
+func (w *failureWatchdog) expired() (failing time.Duration, startup, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	limit := w.limit()
+	if w.failingSince.IsZero() || limit <= 0 {
+		return 0, false, false
+	}
+	failing = w.now().Sub(w.failingSince)
+	return failing, !w.succeeded, failing > limit
+}
+
+// check exits if the failure streak has outlasted its limit. It is polled
+// rather than driven by scan results, because a connect stuck in retries
+// reports none.
+func (w *failureWatchdog) check() {
+	failing, startup, ok := w.expired()
+	if !ok {
+		return
+	}
+	if startup {
+		log.Printf("Startup: no successful scan within %v, exiting for a supervisor restart", failing.Round(time.Second))
+	} else {
+		log.Printf("Watchdog: no successful scan for %v (limit %v), exiting for a supervisor restart",
+			failing.Round(time.Second), w.timeout)
+	}
+	w.exit(exitWatchdog)
+}
+
+// run checks until ctx is canceled.
+func (w *failureWatchdog) run(ctx context.Context) {
+	shortest := w.timeout
+	if w.startupTimeout > 0 && (shortest <= 0 || w.startupTimeout < shortest) {
+		shortest = w.startupTimeout
+	}
+	ticker := time.NewTicker(max(shortest/watchdogChecks, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFailureWatchdog(t *testing.T) {
+	clock := time.Unix(1_700_000_000, 0)
+	exitCode := -1
+	w := newFailureWatchdog(5*time.Minute, 0)
+	w.now = func() time.Time { return clock }
+	w.exit = func(code int) { exitCode = code }
+	w.failingSince = clock // startup counts as failing
+
+	errScan := errors.New("connect refused")
+	steps := []struct {
+		advance time.Duration
+		err     error
+		exit    bool
+	}{
+		{4 * time.Minute, errScan, false}, // still within the limit since startup
+		{time.Minute, nil, false},         // success clears the streak
+		{time.Minute, errScan, false},     // streak starts here
+		{4 * time.Minute, errScan, false}, // 4m failing
+		{2 * time.Minute, errScan, true},  // 6m failing: exit
+	}
+	for i, st := range steps {
+		clock = clock.Add(st.advance)
+		w.record(st.err)
+		exitCode = -1
+		w.check()
+		if got := exitCode == exitWatchdog; got != st.exit {
+			t.Errorf("step %d: exited = %v, want %v", i, got, st.exit)
+		}
+	}
+
+	// No scan results at all (connect stuck retrying) still trips it.
+	w.record(nil)
+	clock = clock.Add(time.Hour)
+	exitCode = -1
+	w.check()
+	if exitCode != -1 {
+		t.Error("healthy watchdog exited")
+	}
+	w = newFailureWatchdog(time.Minute, 0)
+	w.now = func() time.Time { return clock.Add(2 * time.Minute) }
+	w.failingSince = clock
+	w.exit = func(code int) { exitCode = code }
+	w.check()
+	if exitCode != exitWatchdog {
+		t.Errorf("no scans since startup: exit code = %d, want %d", exitCode, exitWatchdog)
+	}
+}
+
+func TestStartupTimeout(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	newWatchdog := func(timeout, startup time.Duration, clock *time.Time, exitCode *int) *failureWatchdog {
+		w := newFailureWatchdog(timeout, startup)
+		w.now = func() time.Time { return *clock }
+		w.exit = func(code int) { *exitCode = code }
+		w.failingSince = start
+		return w
+	}
+
+	// Budget exceeded: no success within the startup timeout exits.
+	clock, exitCode := start, -1
+	w := newWatchdog(0, 2*time.Minute, &clock, &exitCode)
+	clock = start.Add(time.Minute)
+	w.record(errors.New("connect refused"))
+	w.check()
+	if exitCode != -1 {
+		t.Fatal("exited within the startup budget")
+	}
+	clock = start.Add(3 * time.Minute)
+	w.check()
+	if exitCode != exitWatchdog {
+		t.Errorf("startup budget exceeded: exit code = %d, want %d", exitCode, exitWatchdog)
+	}
+
+	// Success within budget: the startup limit no longer applies, and with no
+	// --watchdog-timeout later outages never exit.
+	clock, exitCode = start, -1
+	w = newWatchdog(0, 2*time.Minute, &clock, &exitCode)
+	clock = start.Add(time.Minute)
+	w.record(nil)
+	w.record(errors.New("read: i/o timeout"))
+	clock = start.Add(time.Hour)
+	w.check()
+	if exitCode != -1 {
+		t.Error("exited after a successful startup with no watchdog timeout")
+	}
+
+	// After a successful startup, the watchdog timeout takes over.
+	clock, exitCode = start, -1
+	w = newWatchdog(10*time.Minute, time.Minute, &clock, &exitCode)
+	w.record(nil)
+	clock = start.Add(time.Minute)
+	w.record(errors.New("read: i/o timeout"))
+	clock = start.Add(5 * time.Minute)
+	w.check()
+	if exitCode != -1 {
+		t.Error("startup timeout applied after the first success")
+	}
+	clock = start.Add(12 * time.Minute)
+	w.check()
+	if exitCode != exitWatchdog {
+		t.Errorf("watchdog timeout exceeded: exit code = %d, want %d", exitCode, exitWatchdog)
+	}
+}